@@ -44,11 +44,33 @@ const (
 	// WindowServiceReportWindowProcedure is the fully-qualified name of the WindowService's
 	// ReportWindow RPC.
 	WindowServiceReportWindowProcedure = "/naniwosuruno.v1.WindowService/ReportWindow"
+	// WindowServiceReportWindowsProcedure is the fully-qualified name of the WindowService's
+	// ReportWindows RPC.
+	WindowServiceReportWindowsProcedure = "/naniwosuruno.v1.WindowService/ReportWindows"
+	// WindowServicePresenceProcedure is the fully-qualified name of the WindowService's Presence RPC.
+	WindowServicePresenceProcedure = "/naniwosuruno.v1.WindowService/Presence"
 	// WindowServiceHeartbeatProcedure is the fully-qualified name of the WindowService's Heartbeat RPC.
 	WindowServiceHeartbeatProcedure = "/naniwosuruno.v1.WindowService/Heartbeat"
+	// WindowServiceGoodbyeProcedure is the fully-qualified name of the WindowService's Goodbye RPC.
+	WindowServiceGoodbyeProcedure = "/naniwosuruno.v1.WindowService/Goodbye"
+	// WindowServiceGetClientConfigProcedure is the fully-qualified name of the WindowService's
+	// GetClientConfig RPC.
+	WindowServiceGetClientConfigProcedure = "/naniwosuruno.v1.WindowService/GetClientConfig"
+	// WindowServiceControlClientProcedure is the fully-qualified name of the WindowService's
+	// ControlClient RPC.
+	WindowServiceControlClientProcedure = "/naniwosuruno.v1.WindowService/ControlClient"
+	// WindowServiceSetCustomStatusProcedure is the fully-qualified name of the WindowService's
+	// SetCustomStatus RPC.
+	WindowServiceSetCustomStatusProcedure = "/naniwosuruno.v1.WindowService/SetCustomStatus"
 	// WindowServiceSubscribeEventsProcedure is the fully-qualified name of the WindowService's
 	// SubscribeEvents RPC.
 	WindowServiceSubscribeEventsProcedure = "/naniwosuruno.v1.WindowService/SubscribeEvents"
+	// WindowServiceGetSnapshotProcedure is the fully-qualified name of the WindowService's GetSnapshot
+	// RPC.
+	WindowServiceGetSnapshotProcedure = "/naniwosuruno.v1.WindowService/GetSnapshot"
+	// WindowServiceRelayEventsProcedure is the fully-qualified name of the WindowService's RelayEvents
+	// RPC.
+	WindowServiceRelayEventsProcedure = "/naniwosuruno.v1.WindowService/RelayEvents"
 )
 
 // AuthServiceClient is a client for the naniwosuruno.v1.AuthService service.
@@ -155,10 +177,27 @@ func (UnimplementedAuthServiceHandler) VerifyChallenge(context.Context, *connect
 type WindowServiceClient interface {
 	// 客户端上报当前窗口状态
 	ReportWindow(context.Context, *connect.Request[v1.ReportWindowRequest]) (*connect.Response[v1.ReportWindowResponse], error)
+	// 客户端补报离线期间缓存的窗口变化，仅写入事件历史，不作为实时事件推送
+	ReportWindows(context.Context, *connect.Request[v1.ReportWindowsRequest]) (*connect.Response[v1.ReportWindowsResponse], error)
+	// 长连接在线状态：客户端保持双向流打开，发送焦点变化与保活；流关闭或保活超时时服务端立即将其标记为离线。
+	// 需要 HTTP/2，无法建立时客户端退回 ReportWindow 与 Heartbeat 轮询
+	Presence(context.Context) *connect.BidiStreamForClient[v1.PresenceUpdate, v1.PresenceAck]
 	// 心跳包
 	Heartbeat(context.Context, *connect.Request[v1.HeartbeatRequest]) (*connect.Response[v1.HeartbeatResponse], error)
+	// 客户端正常退出前调用，服务端立即将其标记为离线
+	Goodbye(context.Context, *connect.Request[v1.GoodbyeRequest]) (*connect.Response[v1.GoodbyeResponse], error)
+	// 客户端获取服务端下发的推荐设置，心跳与 Presence 确认中的 config_revision 变化时重新获取
+	GetClientConfig(context.Context, *connect.Request[v1.GetClientConfigRequest]) (*connect.Response[v1.GetClientConfigResponse], error)
+	// 暂停、恢复客户端的上报或强制显示为忙碌，使用服务端配置中的静态 Token 认证；客户端也可以用会话令牌控制自身
+	ControlClient(context.Context, *connect.Request[v1.ControlClientRequest]) (*connect.Response[v1.ControlClientResponse], error)
+	// 设置或清除客户端自己的手动状态，如 "Lunch"，到期后自动清除
+	SetCustomStatus(context.Context, *connect.Request[v1.SetCustomStatusRequest]) (*connect.Response[v1.SetCustomStatusResponse], error)
 	// 前端订阅实时窗口事件流
 	SubscribeEvents(context.Context, *connect.Request[v1.SubscribeEventsRequest]) (*connect.ServerStreamForClient[v1.WindowEvent], error)
+	// 获取所有客户端的当前状态快照，包含合成的 "me" 条目
+	GetSnapshot(context.Context, *connect.Request[v1.GetSnapshotRequest]) (*connect.Response[v1.GetSnapshotResponse], error)
+	// 中继：NAT 后的服务端以客户端身份认证后，将其处理过的事件按顺序批量转发到上游
+	RelayEvents(context.Context, *connect.Request[v1.RelayEventsRequest]) (*connect.Response[v1.RelayEventsResponse], error)
 }
 
 // NewWindowServiceClient constructs a client for the naniwosuruno.v1.WindowService service. By
@@ -178,26 +217,82 @@ func NewWindowServiceClient(httpClient connect.HTTPClient, baseURL string, opts
 			connect.WithSchema(windowServiceMethods.ByName("ReportWindow")),
 			connect.WithClientOptions(opts...),
 		),
+		reportWindows: connect.NewClient[v1.ReportWindowsRequest, v1.ReportWindowsResponse](
+			httpClient,
+			baseURL+WindowServiceReportWindowsProcedure,
+			connect.WithSchema(windowServiceMethods.ByName("ReportWindows")),
+			connect.WithClientOptions(opts...),
+		),
+		presence: connect.NewClient[v1.PresenceUpdate, v1.PresenceAck](
+			httpClient,
+			baseURL+WindowServicePresenceProcedure,
+			connect.WithSchema(windowServiceMethods.ByName("Presence")),
+			connect.WithClientOptions(opts...),
+		),
 		heartbeat: connect.NewClient[v1.HeartbeatRequest, v1.HeartbeatResponse](
 			httpClient,
 			baseURL+WindowServiceHeartbeatProcedure,
 			connect.WithSchema(windowServiceMethods.ByName("Heartbeat")),
 			connect.WithClientOptions(opts...),
 		),
+		goodbye: connect.NewClient[v1.GoodbyeRequest, v1.GoodbyeResponse](
+			httpClient,
+			baseURL+WindowServiceGoodbyeProcedure,
+			connect.WithSchema(windowServiceMethods.ByName("Goodbye")),
+			connect.WithClientOptions(opts...),
+		),
+		getClientConfig: connect.NewClient[v1.GetClientConfigRequest, v1.GetClientConfigResponse](
+			httpClient,
+			baseURL+WindowServiceGetClientConfigProcedure,
+			connect.WithSchema(windowServiceMethods.ByName("GetClientConfig")),
+			connect.WithClientOptions(opts...),
+		),
+		controlClient: connect.NewClient[v1.ControlClientRequest, v1.ControlClientResponse](
+			httpClient,
+			baseURL+WindowServiceControlClientProcedure,
+			connect.WithSchema(windowServiceMethods.ByName("ControlClient")),
+			connect.WithClientOptions(opts...),
+		),
+		setCustomStatus: connect.NewClient[v1.SetCustomStatusRequest, v1.SetCustomStatusResponse](
+			httpClient,
+			baseURL+WindowServiceSetCustomStatusProcedure,
+			connect.WithSchema(windowServiceMethods.ByName("SetCustomStatus")),
+			connect.WithClientOptions(opts...),
+		),
 		subscribeEvents: connect.NewClient[v1.SubscribeEventsRequest, v1.WindowEvent](
 			httpClient,
 			baseURL+WindowServiceSubscribeEventsProcedure,
 			connect.WithSchema(windowServiceMethods.ByName("SubscribeEvents")),
 			connect.WithClientOptions(opts...),
 		),
+		getSnapshot: connect.NewClient[v1.GetSnapshotRequest, v1.GetSnapshotResponse](
+			httpClient,
+			baseURL+WindowServiceGetSnapshotProcedure,
+			connect.WithSchema(windowServiceMethods.ByName("GetSnapshot")),
+			connect.WithClientOptions(opts...),
+		),
+		relayEvents: connect.NewClient[v1.RelayEventsRequest, v1.RelayEventsResponse](
+			httpClient,
+			baseURL+WindowServiceRelayEventsProcedure,
+			connect.WithSchema(windowServiceMethods.ByName("RelayEvents")),
+			connect.WithClientOptions(opts...),
+		),
 	}
 }
 
 // windowServiceClient implements WindowServiceClient.
 type windowServiceClient struct {
 	reportWindow    *connect.Client[v1.ReportWindowRequest, v1.ReportWindowResponse]
+	reportWindows   *connect.Client[v1.ReportWindowsRequest, v1.ReportWindowsResponse]
+	presence        *connect.Client[v1.PresenceUpdate, v1.PresenceAck]
 	heartbeat       *connect.Client[v1.HeartbeatRequest, v1.HeartbeatResponse]
+	goodbye         *connect.Client[v1.GoodbyeRequest, v1.GoodbyeResponse]
+	getClientConfig *connect.Client[v1.GetClientConfigRequest, v1.GetClientConfigResponse]
+	controlClient   *connect.Client[v1.ControlClientRequest, v1.ControlClientResponse]
+	setCustomStatus *connect.Client[v1.SetCustomStatusRequest, v1.SetCustomStatusResponse]
 	subscribeEvents *connect.Client[v1.SubscribeEventsRequest, v1.WindowEvent]
+	getSnapshot     *connect.Client[v1.GetSnapshotRequest, v1.GetSnapshotResponse]
+	relayEvents     *connect.Client[v1.RelayEventsRequest, v1.RelayEventsResponse]
 }
 
 // ReportWindow calls naniwosuruno.v1.WindowService.ReportWindow.
@@ -205,24 +300,81 @@ func (c *windowServiceClient) ReportWindow(ctx context.Context, req *connect.Req
 	return c.reportWindow.CallUnary(ctx, req)
 }
 
+// ReportWindows calls naniwosuruno.v1.WindowService.ReportWindows.
+func (c *windowServiceClient) ReportWindows(ctx context.Context, req *connect.Request[v1.ReportWindowsRequest]) (*connect.Response[v1.ReportWindowsResponse], error) {
+	return c.reportWindows.CallUnary(ctx, req)
+}
+
+// Presence calls naniwosuruno.v1.WindowService.Presence.
+func (c *windowServiceClient) Presence(ctx context.Context) *connect.BidiStreamForClient[v1.PresenceUpdate, v1.PresenceAck] {
+	return c.presence.CallBidiStream(ctx)
+}
+
 // Heartbeat calls naniwosuruno.v1.WindowService.Heartbeat.
 func (c *windowServiceClient) Heartbeat(ctx context.Context, req *connect.Request[v1.HeartbeatRequest]) (*connect.Response[v1.HeartbeatResponse], error) {
 	return c.heartbeat.CallUnary(ctx, req)
 }
 
+// Goodbye calls naniwosuruno.v1.WindowService.Goodbye.
+func (c *windowServiceClient) Goodbye(ctx context.Context, req *connect.Request[v1.GoodbyeRequest]) (*connect.Response[v1.GoodbyeResponse], error) {
+	return c.goodbye.CallUnary(ctx, req)
+}
+
+// GetClientConfig calls naniwosuruno.v1.WindowService.GetClientConfig.
+func (c *windowServiceClient) GetClientConfig(ctx context.Context, req *connect.Request[v1.GetClientConfigRequest]) (*connect.Response[v1.GetClientConfigResponse], error) {
+	return c.getClientConfig.CallUnary(ctx, req)
+}
+
+// ControlClient calls naniwosuruno.v1.WindowService.ControlClient.
+func (c *windowServiceClient) ControlClient(ctx context.Context, req *connect.Request[v1.ControlClientRequest]) (*connect.Response[v1.ControlClientResponse], error) {
+	return c.controlClient.CallUnary(ctx, req)
+}
+
+// SetCustomStatus calls naniwosuruno.v1.WindowService.SetCustomStatus.
+func (c *windowServiceClient) SetCustomStatus(ctx context.Context, req *connect.Request[v1.SetCustomStatusRequest]) (*connect.Response[v1.SetCustomStatusResponse], error) {
+	return c.setCustomStatus.CallUnary(ctx, req)
+}
+
 // SubscribeEvents calls naniwosuruno.v1.WindowService.SubscribeEvents.
 func (c *windowServiceClient) SubscribeEvents(ctx context.Context, req *connect.Request[v1.SubscribeEventsRequest]) (*connect.ServerStreamForClient[v1.WindowEvent], error) {
 	return c.subscribeEvents.CallServerStream(ctx, req)
 }
 
+// GetSnapshot calls naniwosuruno.v1.WindowService.GetSnapshot.
+func (c *windowServiceClient) GetSnapshot(ctx context.Context, req *connect.Request[v1.GetSnapshotRequest]) (*connect.Response[v1.GetSnapshotResponse], error) {
+	return c.getSnapshot.CallUnary(ctx, req)
+}
+
+// RelayEvents calls naniwosuruno.v1.WindowService.RelayEvents.
+func (c *windowServiceClient) RelayEvents(ctx context.Context, req *connect.Request[v1.RelayEventsRequest]) (*connect.Response[v1.RelayEventsResponse], error) {
+	return c.relayEvents.CallUnary(ctx, req)
+}
+
 // WindowServiceHandler is an implementation of the naniwosuruno.v1.WindowService service.
 type WindowServiceHandler interface {
 	// 客户端上报当前窗口状态
 	ReportWindow(context.Context, *connect.Request[v1.ReportWindowRequest]) (*connect.Response[v1.ReportWindowResponse], error)
+	// 客户端补报离线期间缓存的窗口变化，仅写入事件历史，不作为实时事件推送
+	ReportWindows(context.Context, *connect.Request[v1.ReportWindowsRequest]) (*connect.Response[v1.ReportWindowsResponse], error)
+	// 长连接在线状态：客户端保持双向流打开，发送焦点变化与保活；流关闭或保活超时时服务端立即将其标记为离线。
+	// 需要 HTTP/2，无法建立时客户端退回 ReportWindow 与 Heartbeat 轮询
+	Presence(context.Context, *connect.BidiStream[v1.PresenceUpdate, v1.PresenceAck]) error
 	// 心跳包
 	Heartbeat(context.Context, *connect.Request[v1.HeartbeatRequest]) (*connect.Response[v1.HeartbeatResponse], error)
+	// 客户端正常退出前调用，服务端立即将其标记为离线
+	Goodbye(context.Context, *connect.Request[v1.GoodbyeRequest]) (*connect.Response[v1.GoodbyeResponse], error)
+	// 客户端获取服务端下发的推荐设置，心跳与 Presence 确认中的 config_revision 变化时重新获取
+	GetClientConfig(context.Context, *connect.Request[v1.GetClientConfigRequest]) (*connect.Response[v1.GetClientConfigResponse], error)
+	// 暂停、恢复客户端的上报或强制显示为忙碌，使用服务端配置中的静态 Token 认证；客户端也可以用会话令牌控制自身
+	ControlClient(context.Context, *connect.Request[v1.ControlClientRequest]) (*connect.Response[v1.ControlClientResponse], error)
+	// 设置或清除客户端自己的手动状态，如 "Lunch"，到期后自动清除
+	SetCustomStatus(context.Context, *connect.Request[v1.SetCustomStatusRequest]) (*connect.Response[v1.SetCustomStatusResponse], error)
 	// 前端订阅实时窗口事件流
 	SubscribeEvents(context.Context, *connect.Request[v1.SubscribeEventsRequest], *connect.ServerStream[v1.WindowEvent]) error
+	// 获取所有客户端的当前状态快照，包含合成的 "me" 条目
+	GetSnapshot(context.Context, *connect.Request[v1.GetSnapshotRequest]) (*connect.Response[v1.GetSnapshotResponse], error)
+	// 中继：NAT 后的服务端以客户端身份认证后，将其处理过的事件按顺序批量转发到上游
+	RelayEvents(context.Context, *connect.Request[v1.RelayEventsRequest]) (*connect.Response[v1.RelayEventsResponse], error)
 }
 
 // NewWindowServiceHandler builds an HTTP handler from the service implementation. It returns the
@@ -238,26 +390,90 @@ func NewWindowServiceHandler(svc WindowServiceHandler, opts ...connect.HandlerOp
 		connect.WithSchema(windowServiceMethods.ByName("ReportWindow")),
 		connect.WithHandlerOptions(opts...),
 	)
+	windowServiceReportWindowsHandler := connect.NewUnaryHandler(
+		WindowServiceReportWindowsProcedure,
+		svc.ReportWindows,
+		connect.WithSchema(windowServiceMethods.ByName("ReportWindows")),
+		connect.WithHandlerOptions(opts...),
+	)
+	windowServicePresenceHandler := connect.NewBidiStreamHandler(
+		WindowServicePresenceProcedure,
+		svc.Presence,
+		connect.WithSchema(windowServiceMethods.ByName("Presence")),
+		connect.WithHandlerOptions(opts...),
+	)
 	windowServiceHeartbeatHandler := connect.NewUnaryHandler(
 		WindowServiceHeartbeatProcedure,
 		svc.Heartbeat,
 		connect.WithSchema(windowServiceMethods.ByName("Heartbeat")),
 		connect.WithHandlerOptions(opts...),
 	)
+	windowServiceGoodbyeHandler := connect.NewUnaryHandler(
+		WindowServiceGoodbyeProcedure,
+		svc.Goodbye,
+		connect.WithSchema(windowServiceMethods.ByName("Goodbye")),
+		connect.WithHandlerOptions(opts...),
+	)
+	windowServiceGetClientConfigHandler := connect.NewUnaryHandler(
+		WindowServiceGetClientConfigProcedure,
+		svc.GetClientConfig,
+		connect.WithSchema(windowServiceMethods.ByName("GetClientConfig")),
+		connect.WithHandlerOptions(opts...),
+	)
+	windowServiceControlClientHandler := connect.NewUnaryHandler(
+		WindowServiceControlClientProcedure,
+		svc.ControlClient,
+		connect.WithSchema(windowServiceMethods.ByName("ControlClient")),
+		connect.WithHandlerOptions(opts...),
+	)
+	windowServiceSetCustomStatusHandler := connect.NewUnaryHandler(
+		WindowServiceSetCustomStatusProcedure,
+		svc.SetCustomStatus,
+		connect.WithSchema(windowServiceMethods.ByName("SetCustomStatus")),
+		connect.WithHandlerOptions(opts...),
+	)
 	windowServiceSubscribeEventsHandler := connect.NewServerStreamHandler(
 		WindowServiceSubscribeEventsProcedure,
 		svc.SubscribeEvents,
 		connect.WithSchema(windowServiceMethods.ByName("SubscribeEvents")),
 		connect.WithHandlerOptions(opts...),
 	)
+	windowServiceGetSnapshotHandler := connect.NewUnaryHandler(
+		WindowServiceGetSnapshotProcedure,
+		svc.GetSnapshot,
+		connect.WithSchema(windowServiceMethods.ByName("GetSnapshot")),
+		connect.WithHandlerOptions(opts...),
+	)
+	windowServiceRelayEventsHandler := connect.NewUnaryHandler(
+		WindowServiceRelayEventsProcedure,
+		svc.RelayEvents,
+		connect.WithSchema(windowServiceMethods.ByName("RelayEvents")),
+		connect.WithHandlerOptions(opts...),
+	)
 	return "/naniwosuruno.v1.WindowService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case WindowServiceReportWindowProcedure:
 			windowServiceReportWindowHandler.ServeHTTP(w, r)
+		case WindowServiceReportWindowsProcedure:
+			windowServiceReportWindowsHandler.ServeHTTP(w, r)
+		case WindowServicePresenceProcedure:
+			windowServicePresenceHandler.ServeHTTP(w, r)
 		case WindowServiceHeartbeatProcedure:
 			windowServiceHeartbeatHandler.ServeHTTP(w, r)
+		case WindowServiceGoodbyeProcedure:
+			windowServiceGoodbyeHandler.ServeHTTP(w, r)
+		case WindowServiceGetClientConfigProcedure:
+			windowServiceGetClientConfigHandler.ServeHTTP(w, r)
+		case WindowServiceControlClientProcedure:
+			windowServiceControlClientHandler.ServeHTTP(w, r)
+		case WindowServiceSetCustomStatusProcedure:
+			windowServiceSetCustomStatusHandler.ServeHTTP(w, r)
 		case WindowServiceSubscribeEventsProcedure:
 			windowServiceSubscribeEventsHandler.ServeHTTP(w, r)
+		case WindowServiceGetSnapshotProcedure:
+			windowServiceGetSnapshotHandler.ServeHTTP(w, r)
+		case WindowServiceRelayEventsProcedure:
+			windowServiceRelayEventsHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -271,10 +487,42 @@ func (UnimplementedWindowServiceHandler) ReportWindow(context.Context, *connect.
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("naniwosuruno.v1.WindowService.ReportWindow is not implemented"))
 }
 
+func (UnimplementedWindowServiceHandler) ReportWindows(context.Context, *connect.Request[v1.ReportWindowsRequest]) (*connect.Response[v1.ReportWindowsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("naniwosuruno.v1.WindowService.ReportWindows is not implemented"))
+}
+
+func (UnimplementedWindowServiceHandler) Presence(context.Context, *connect.BidiStream[v1.PresenceUpdate, v1.PresenceAck]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("naniwosuruno.v1.WindowService.Presence is not implemented"))
+}
+
 func (UnimplementedWindowServiceHandler) Heartbeat(context.Context, *connect.Request[v1.HeartbeatRequest]) (*connect.Response[v1.HeartbeatResponse], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("naniwosuruno.v1.WindowService.Heartbeat is not implemented"))
 }
 
+func (UnimplementedWindowServiceHandler) Goodbye(context.Context, *connect.Request[v1.GoodbyeRequest]) (*connect.Response[v1.GoodbyeResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("naniwosuruno.v1.WindowService.Goodbye is not implemented"))
+}
+
+func (UnimplementedWindowServiceHandler) GetClientConfig(context.Context, *connect.Request[v1.GetClientConfigRequest]) (*connect.Response[v1.GetClientConfigResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("naniwosuruno.v1.WindowService.GetClientConfig is not implemented"))
+}
+
+func (UnimplementedWindowServiceHandler) ControlClient(context.Context, *connect.Request[v1.ControlClientRequest]) (*connect.Response[v1.ControlClientResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("naniwosuruno.v1.WindowService.ControlClient is not implemented"))
+}
+
+func (UnimplementedWindowServiceHandler) SetCustomStatus(context.Context, *connect.Request[v1.SetCustomStatusRequest]) (*connect.Response[v1.SetCustomStatusResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("naniwosuruno.v1.WindowService.SetCustomStatus is not implemented"))
+}
+
 func (UnimplementedWindowServiceHandler) SubscribeEvents(context.Context, *connect.Request[v1.SubscribeEventsRequest], *connect.ServerStream[v1.WindowEvent]) error {
 	return connect.NewError(connect.CodeUnimplemented, errors.New("naniwosuruno.v1.WindowService.SubscribeEvents is not implemented"))
 }
+
+func (UnimplementedWindowServiceHandler) GetSnapshot(context.Context, *connect.Request[v1.GetSnapshotRequest]) (*connect.Response[v1.GetSnapshotResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("naniwosuruno.v1.WindowService.GetSnapshot is not implemented"))
+}
+
+func (UnimplementedWindowServiceHandler) RelayEvents(context.Context, *connect.Request[v1.RelayEventsRequest]) (*connect.Response[v1.RelayEventsResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("naniwosuruno.v1.WindowService.RelayEvents is not implemented"))
+}
@@ -217,6 +217,7 @@ type ReportWindowRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Title         string                 `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
 	Os            string                 `protobuf:"bytes,2,opt,name=os,proto3" json:"os,omitempty"`
+	ObservedAt    int64                  `protobuf:"varint,3,opt,name=observed_at,json=observedAt,proto3" json:"observed_at,omitempty"` // 客户端观测到该窗口的时间 (Unix 毫秒)，为 0 时使用服务端接收时间
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -265,6 +266,205 @@ func (x *ReportWindowRequest) GetOs() string {
 	return ""
 }
 
+func (x *ReportWindowRequest) GetObservedAt() int64 {
+	if x != nil {
+		return x.ObservedAt
+	}
+	return 0
+}
+
+type PresenceUpdate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Window        *ReportWindowRequest   `protobuf:"bytes,1,opt,name=window,proto3" json:"window,omitempty"` // 焦点变化，为空时表示保活
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PresenceUpdate) Reset() {
+	*x = PresenceUpdate{}
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PresenceUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PresenceUpdate) ProtoMessage() {}
+
+func (x *PresenceUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PresenceUpdate.ProtoReflect.Descriptor instead.
+func (*PresenceUpdate) Descriptor() ([]byte, []int) {
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PresenceUpdate) GetWindow() *ReportWindowRequest {
+	if x != nil {
+		return x.Window
+	}
+	return nil
+}
+
+type PresenceAck struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	KeepaliveTimeout int64                  `protobuf:"varint,1,opt,name=keepalive_timeout,json=keepaliveTimeout,proto3" json:"keepalive_timeout,omitempty"` // 服务端等待保活的最长时间 (秒)，客户端应在此之前发送下一次保活
+	ConfigRevision   string                 `protobuf:"bytes,2,opt,name=config_revision,json=configRevision,proto3" json:"config_revision,omitempty"`        // 同 HeartbeatResponse.config_revision
+	Control          *ClientControl         `protobuf:"bytes,3,opt,name=control,proto3" json:"control,omitempty"`                                            // 同 HeartbeatResponse.control，变化时服务端会主动发送一次确认
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *PresenceAck) Reset() {
+	*x = PresenceAck{}
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PresenceAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PresenceAck) ProtoMessage() {}
+
+func (x *PresenceAck) ProtoReflect() protoreflect.Message {
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PresenceAck.ProtoReflect.Descriptor instead.
+func (*PresenceAck) Descriptor() ([]byte, []int) {
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *PresenceAck) GetKeepaliveTimeout() int64 {
+	if x != nil {
+		return x.KeepaliveTimeout
+	}
+	return 0
+}
+
+func (x *PresenceAck) GetConfigRevision() string {
+	if x != nil {
+		return x.ConfigRevision
+	}
+	return ""
+}
+
+func (x *PresenceAck) GetControl() *ClientControl {
+	if x != nil {
+		return x.Control
+	}
+	return nil
+}
+
+type ReportWindowsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Windows       []*ReportWindowRequest `protobuf:"bytes,1,rep,name=windows,proto3" json:"windows,omitempty"` // 按观测时间升序
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportWindowsRequest) Reset() {
+	*x = ReportWindowsRequest{}
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportWindowsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportWindowsRequest) ProtoMessage() {}
+
+func (x *ReportWindowsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportWindowsRequest.ProtoReflect.Descriptor instead.
+func (*ReportWindowsRequest) Descriptor() ([]byte, []int) {
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ReportWindowsRequest) GetWindows() []*ReportWindowRequest {
+	if x != nil {
+		return x.Windows
+	}
+	return nil
+}
+
+type ReportWindowsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Accepted      uint32                 `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"` // 写入历史的条数，重发的重复条目不计入
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportWindowsResponse) Reset() {
+	*x = ReportWindowsResponse{}
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportWindowsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportWindowsResponse) ProtoMessage() {}
+
+func (x *ReportWindowsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportWindowsResponse.ProtoReflect.Descriptor instead.
+func (*ReportWindowsResponse) Descriptor() ([]byte, []int) {
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ReportWindowsResponse) GetAccepted() uint32 {
+	if x != nil {
+		return x.Accepted
+	}
+	return 0
+}
+
 type ReportWindowResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -273,7 +473,7 @@ type ReportWindowResponse struct {
 
 func (x *ReportWindowResponse) Reset() {
 	*x = ReportWindowResponse{}
-	mi := &file_naniwosuruno_v1_service_proto_msgTypes[5]
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -285,7 +485,7 @@ func (x *ReportWindowResponse) String() string {
 func (*ReportWindowResponse) ProtoMessage() {}
 
 func (x *ReportWindowResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_naniwosuruno_v1_service_proto_msgTypes[5]
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -298,19 +498,20 @@ func (x *ReportWindowResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReportWindowResponse.ProtoReflect.Descriptor instead.
 func (*ReportWindowResponse) Descriptor() ([]byte, []int) {
-	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{5}
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{9}
 }
 
 type HeartbeatRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Count         uint32                 `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	Count         uint32                 `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"` // 在同一纪元内单调递增，用于丢弃重放的心跳
+	Epoch         string                 `protobuf:"bytes,2,opt,name=epoch,proto3" json:"epoch,omitempty"`  // 客户端进程启动时随机生成，纪元变化表示客户端已重启，计数重新开始
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *HeartbeatRequest) Reset() {
 	*x = HeartbeatRequest{}
-	mi := &file_naniwosuruno_v1_service_proto_msgTypes[6]
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -322,7 +523,7 @@ func (x *HeartbeatRequest) String() string {
 func (*HeartbeatRequest) ProtoMessage() {}
 
 func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_naniwosuruno_v1_service_proto_msgTypes[6]
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -335,7 +536,7 @@ func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HeartbeatRequest.ProtoReflect.Descriptor instead.
 func (*HeartbeatRequest) Descriptor() ([]byte, []int) {
-	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{6}
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *HeartbeatRequest) GetCount() uint32 {
@@ -345,16 +546,25 @@ func (x *HeartbeatRequest) GetCount() uint32 {
 	return 0
 }
 
+func (x *HeartbeatRequest) GetEpoch() string {
+	if x != nil {
+		return x.Epoch
+	}
+	return ""
+}
+
 type HeartbeatResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Count         uint32                 `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Count          uint32                 `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	ConfigRevision string                 `protobuf:"bytes,2,opt,name=config_revision,json=configRevision,proto3" json:"config_revision,omitempty"` // 当前客户端设置的版本，与已应用的版本不同时应调用 GetClientConfig
+	Control        *ClientControl         `protobuf:"bytes,3,opt,name=control,proto3" json:"control,omitempty"`                                     // 管理员对本客户端的控制，未设置表示正常上报
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *HeartbeatResponse) Reset() {
 	*x = HeartbeatResponse{}
-	mi := &file_naniwosuruno_v1_service_proto_msgTypes[7]
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -366,7 +576,7 @@ func (x *HeartbeatResponse) String() string {
 func (*HeartbeatResponse) ProtoMessage() {}
 
 func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_naniwosuruno_v1_service_proto_msgTypes[7]
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -379,7 +589,7 @@ func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
 func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
-	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{7}
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *HeartbeatResponse) GetCount() uint32 {
@@ -389,28 +599,42 @@ func (x *HeartbeatResponse) GetCount() uint32 {
 	return 0
 }
 
-type SubscribeEventsRequest struct {
+func (x *HeartbeatResponse) GetConfigRevision() string {
+	if x != nil {
+		return x.ConfigRevision
+	}
+	return ""
+}
+
+func (x *HeartbeatResponse) GetControl() *ClientControl {
+	if x != nil {
+		return x.Control
+	}
+	return nil
+}
+
+type GoodbyeRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	StreamId      string                 `protobuf:"bytes,1,opt,name=stream_id,json=streamId,proto3" json:"stream_id,omitempty"` // e.g. "focus"
+	Epoch         string                 `protobuf:"bytes,1,opt,name=epoch,proto3" json:"epoch,omitempty"` // 退出进程的心跳纪元，与服务端记录的纪元不一致时（新进程已经上线）忽略
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SubscribeEventsRequest) Reset() {
-	*x = SubscribeEventsRequest{}
-	mi := &file_naniwosuruno_v1_service_proto_msgTypes[8]
+func (x *GoodbyeRequest) Reset() {
+	*x = GoodbyeRequest{}
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SubscribeEventsRequest) String() string {
+func (x *GoodbyeRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SubscribeEventsRequest) ProtoMessage() {}
+func (*GoodbyeRequest) ProtoMessage() {}
 
-func (x *SubscribeEventsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_naniwosuruno_v1_service_proto_msgTypes[8]
+func (x *GoodbyeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -421,43 +645,39 @@ func (x *SubscribeEventsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SubscribeEventsRequest.ProtoReflect.Descriptor instead.
-func (*SubscribeEventsRequest) Descriptor() ([]byte, []int) {
-	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use GoodbyeRequest.ProtoReflect.Descriptor instead.
+func (*GoodbyeRequest) Descriptor() ([]byte, []int) {
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{12}
 }
 
-func (x *SubscribeEventsRequest) GetStreamId() string {
+func (x *GoodbyeRequest) GetEpoch() string {
 	if x != nil {
-		return x.StreamId
+		return x.Epoch
 	}
 	return ""
 }
 
-type WindowEvent struct {
+type GoodbyeResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Title         string                 `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
-	Os            string                 `protobuf:"bytes,2,opt,name=os,proto3" json:"os,omitempty"`
-	Client        string                 `protobuf:"bytes,3,opt,name=client,proto3" json:"client,omitempty"`
-	Status        string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"` // "online", "offline", "update"
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *WindowEvent) Reset() {
-	*x = WindowEvent{}
-	mi := &file_naniwosuruno_v1_service_proto_msgTypes[9]
+func (x *GoodbyeResponse) Reset() {
+	*x = GoodbyeResponse{}
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *WindowEvent) String() string {
+func (x *GoodbyeResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*WindowEvent) ProtoMessage() {}
+func (*GoodbyeResponse) ProtoMessage() {}
 
-func (x *WindowEvent) ProtoReflect() protoreflect.Message {
-	mi := &file_naniwosuruno_v1_service_proto_msgTypes[9]
+func (x *GoodbyeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -468,45 +688,826 @@ func (x *WindowEvent) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use WindowEvent.ProtoReflect.Descriptor instead.
-func (*WindowEvent) Descriptor() ([]byte, []int) {
-	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{9}
+// Deprecated: Use GoodbyeResponse.ProtoReflect.Descriptor instead.
+func (*GoodbyeResponse) Descriptor() ([]byte, []int) {
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{13}
 }
 
-func (x *WindowEvent) GetTitle() string {
+type ClientControl struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Mode          string                 `protobuf:"bytes,1,opt,name=mode,proto3" json:"mode,omitempty"`    // "paused" 暂停上报窗口标题，"busy" 显示为忙碌；为空表示正常上报
+	Until         int64                  `protobuf:"varint,2,opt,name=until,proto3" json:"until,omitempty"` // 到期时间 (Unix 毫秒)，0 表示直到手动恢复
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ClientControl) Reset() {
+	*x = ClientControl{}
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClientControl) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientControl) ProtoMessage() {}
+
+func (x *ClientControl) ProtoReflect() protoreflect.Message {
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[14]
 	if x != nil {
-		return x.Title
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *WindowEvent) GetOs() string {
+// Deprecated: Use ClientControl.ProtoReflect.Descriptor instead.
+func (*ClientControl) Descriptor() ([]byte, []int) {
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ClientControl) GetMode() string {
 	if x != nil {
-		return x.Os
+		return x.Mode
 	}
 	return ""
 }
 
-func (x *WindowEvent) GetClient() string {
+func (x *ClientControl) GetUntil() int64 {
 	if x != nil {
-		return x.Client
+		return x.Until
 	}
-	return ""
+	return 0
 }
 
-func (x *WindowEvent) GetStatus() string {
+type ControlClientRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Client        string                 `protobuf:"bytes,1,opt,name=client,proto3" json:"client,omitempty"`   // 客户端 ID 或名称，为空表示所有已连接过的客户端
+	Control       *ClientControl         `protobuf:"bytes,2,opt,name=control,proto3" json:"control,omitempty"` // mode 为空表示恢复
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ControlClientRequest) Reset() {
+	*x = ControlClientRequest{}
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ControlClientRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ControlClientRequest) ProtoMessage() {}
+
+func (x *ControlClientRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[15]
 	if x != nil {
-		return x.Status
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-var File_naniwosuruno_v1_service_proto protoreflect.FileDescriptor
+// Deprecated: Use ControlClientRequest.ProtoReflect.Descriptor instead.
+func (*ControlClientRequest) Descriptor() ([]byte, []int) {
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{15}
+}
 
-const file_naniwosuruno_v1_service_proto_rawDesc = "" +
-	"\n" +
-	"\x1dnaniwosuruno/v1/service.proto\x12\x0fnaniwosuruno.v1\"5\n" +
-	"\x16CreateChallengeRequest\x12\x1b\n" +
+func (x *ControlClientRequest) GetClient() string {
+	if x != nil {
+		return x.Client
+	}
+	return ""
+}
+
+func (x *ControlClientRequest) GetControl() *ClientControl {
+	if x != nil {
+		return x.Control
+	}
+	return nil
+}
+
+type ControlClientResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Clients       []string               `protobuf:"bytes,1,rep,name=clients,proto3" json:"clients,omitempty"` // 受影响的客户端名称
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ControlClientResponse) Reset() {
+	*x = ControlClientResponse{}
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ControlClientResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ControlClientResponse) ProtoMessage() {}
+
+func (x *ControlClientResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ControlClientResponse.ProtoReflect.Descriptor instead.
+func (*ControlClientResponse) Descriptor() ([]byte, []int) {
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ControlClientResponse) GetClients() []string {
+	if x != nil {
+		return x.Clients
+	}
+	return nil
+}
+
+type SetCustomStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        *CustomStatus          `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"` // 未设置或 text 为空表示清除
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetCustomStatusRequest) Reset() {
+	*x = SetCustomStatusRequest{}
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetCustomStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetCustomStatusRequest) ProtoMessage() {}
+
+func (x *SetCustomStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetCustomStatusRequest.ProtoReflect.Descriptor instead.
+func (*SetCustomStatusRequest) Descriptor() ([]byte, []int) {
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *SetCustomStatusRequest) GetStatus() *CustomStatus {
+	if x != nil {
+		return x.Status
+	}
+	return nil
+}
+
+type SetCustomStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetCustomStatusResponse) Reset() {
+	*x = SetCustomStatusResponse{}
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetCustomStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetCustomStatusResponse) ProtoMessage() {}
+
+func (x *SetCustomStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetCustomStatusResponse.ProtoReflect.Descriptor instead.
+func (*SetCustomStatusResponse) Descriptor() ([]byte, []int) {
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{18}
+}
+
+type GetClientConfigRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetClientConfigRequest) Reset() {
+	*x = GetClientConfigRequest{}
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetClientConfigRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetClientConfigRequest) ProtoMessage() {}
+
+func (x *GetClientConfigRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetClientConfigRequest.ProtoReflect.Descriptor instead.
+func (*GetClientConfigRequest) Descriptor() ([]byte, []int) {
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{19}
+}
+
+type GetClientConfigResponse struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	PollInterval      int64                  `protobuf:"varint,1,opt,name=poll_interval,json=pollInterval,proto3" json:"poll_interval,omitempty"`                // 检查窗口标题的间隔 (秒)
+	HeartbeatInterval int64                  `protobuf:"varint,2,opt,name=heartbeat_interval,json=heartbeatInterval,proto3" json:"heartbeat_interval,omitempty"` // 轮询模式下的心跳间隔 (秒)，服务端在 3 个间隔内收不到心跳时判定离线
+	IdleAfter         int64                  `protobuf:"varint,3,opt,name=idle_after,json=idleAfter,proto3" json:"idle_after,omitempty"`                         // 已不再下发：空闲状态由服务端推导，客户端无需该阈值
+	Hide              []string               `protobuf:"bytes,4,rep,name=hide,proto3" json:"hide,omitempty"`                                                     // 追加的隐私规则，命中时标题整体替换为占位符
+	Redact            []string               `protobuf:"bytes,5,rep,name=redact,proto3" json:"redact,omitempty"`                                                 // 追加的隐私规则，命中的部分替换为 "***"
+	Placeholder       string                 `protobuf:"bytes,6,opt,name=placeholder,proto3" json:"placeholder,omitempty"`                                       // 客户端未配置占位符时使用
+	Fields            []string               `protobuf:"bytes,7,rep,name=fields,proto3" json:"fields,omitempty"`                                                 // 允许上报的字段 ("title"、"os")，为空表示全部
+	Revision          string                 `protobuf:"bytes,8,opt,name=revision,proto3" json:"revision,omitempty"`                                             // 以上设置的版本
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *GetClientConfigResponse) Reset() {
+	*x = GetClientConfigResponse{}
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetClientConfigResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetClientConfigResponse) ProtoMessage() {}
+
+func (x *GetClientConfigResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetClientConfigResponse.ProtoReflect.Descriptor instead.
+func (*GetClientConfigResponse) Descriptor() ([]byte, []int) {
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *GetClientConfigResponse) GetPollInterval() int64 {
+	if x != nil {
+		return x.PollInterval
+	}
+	return 0
+}
+
+func (x *GetClientConfigResponse) GetHeartbeatInterval() int64 {
+	if x != nil {
+		return x.HeartbeatInterval
+	}
+	return 0
+}
+
+func (x *GetClientConfigResponse) GetIdleAfter() int64 {
+	if x != nil {
+		return x.IdleAfter
+	}
+	return 0
+}
+
+func (x *GetClientConfigResponse) GetHide() []string {
+	if x != nil {
+		return x.Hide
+	}
+	return nil
+}
+
+func (x *GetClientConfigResponse) GetRedact() []string {
+	if x != nil {
+		return x.Redact
+	}
+	return nil
+}
+
+func (x *GetClientConfigResponse) GetPlaceholder() string {
+	if x != nil {
+		return x.Placeholder
+	}
+	return ""
+}
+
+func (x *GetClientConfigResponse) GetFields() []string {
+	if x != nil {
+		return x.Fields
+	}
+	return nil
+}
+
+func (x *GetClientConfigResponse) GetRevision() string {
+	if x != nil {
+		return x.Revision
+	}
+	return ""
+}
+
+type SubscribeEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	StreamId      string                 `protobuf:"bytes,1,opt,name=stream_id,json=streamId,proto3" json:"stream_id,omitempty"` // e.g. "focus"
+	SinceId       uint64                 `protobuf:"varint,2,opt,name=since_id,json=sinceId,proto3" json:"since_id,omitempty"`   // 断线重连时传入最后收到的事件 ID，服务端从历史中续传；为 0 或断档过大时先发送快照
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeEventsRequest) Reset() {
+	*x = SubscribeEventsRequest{}
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeEventsRequest) ProtoMessage() {}
+
+func (x *SubscribeEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeEventsRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeEventsRequest) Descriptor() ([]byte, []int) {
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *SubscribeEventsRequest) GetStreamId() string {
+	if x != nil {
+		return x.StreamId
+	}
+	return ""
+}
+
+func (x *SubscribeEventsRequest) GetSinceId() uint64 {
+	if x != nil {
+		return x.SinceId
+	}
+	return 0
+}
+
+// WindowEvent 是 SSE 与 RPC 事件流共用的事件信封，以 protojson 序列化
+type WindowEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Title         string                 `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	Os            string                 `protobuf:"bytes,2,opt,name=os,proto3" json:"os,omitempty"`
+	Client        string                 `protobuf:"bytes,3,opt,name=client,proto3" json:"client,omitempty"`
+	Status        string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`                                     // "online", "offline", "idle", "paused", "busy"
+	Source        string                 `protobuf:"bytes,5,opt,name=source,proto3" json:"source,omitempty"`                                     // 仅用于合成的 "me" 条目：被选中的客户端名称
+	SchemaVersion uint32                 `protobuf:"varint,6,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"` // 事件结构版本，结构发生不兼容变更时递增
+	Id            uint64                 `protobuf:"varint,7,opt,name=id,proto3" json:"id,omitempty"`                                            // 事件 ID，单调递增
+	Timestamp     int64                  `protobuf:"varint,8,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                              // 事件产生时间 (Unix 毫秒)
+	ClientId      string                 `protobuf:"bytes,9,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`                 // 客户端 ID，合成条目为 "me"
+	Type          string                 `protobuf:"bytes,10,opt,name=type,proto3" json:"type,omitempty"`                                        // 事件类型，同时作为 SSE 的 event 名称: "presence", "focus", "snapshot"
+	Backfilled    bool                   `protobuf:"varint,11,opt,name=backfilled,proto3" json:"backfilled,omitempty"`                           // 客户端离线期间缓存、重连后补报的事件，只存在于历史中
+	CustomStatus  *CustomStatus          `protobuf:"bytes,12,opt,name=custom_status,json=customStatus,proto3" json:"custom_status,omitempty"`    // 用户手动设置的状态，与检测到的窗口一同展示，未设置时为空
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WindowEvent) Reset() {
+	*x = WindowEvent{}
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WindowEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WindowEvent) ProtoMessage() {}
+
+func (x *WindowEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WindowEvent.ProtoReflect.Descriptor instead.
+func (*WindowEvent) Descriptor() ([]byte, []int) {
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *WindowEvent) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *WindowEvent) GetOs() string {
+	if x != nil {
+		return x.Os
+	}
+	return ""
+}
+
+func (x *WindowEvent) GetClient() string {
+	if x != nil {
+		return x.Client
+	}
+	return ""
+}
+
+func (x *WindowEvent) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *WindowEvent) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *WindowEvent) GetSchemaVersion() uint32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+func (x *WindowEvent) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *WindowEvent) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *WindowEvent) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *WindowEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *WindowEvent) GetBackfilled() bool {
+	if x != nil {
+		return x.Backfilled
+	}
+	return false
+}
+
+func (x *WindowEvent) GetCustomStatus() *CustomStatus {
+	if x != nil {
+		return x.CustomStatus
+	}
+	return nil
+}
+
+type CustomStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`    // 如 "Lunch"
+	Emoji         string                 `protobuf:"bytes,2,opt,name=emoji,proto3" json:"emoji,omitempty"`  // Unicode 表情，如 "🍜"
+	Until         int64                  `protobuf:"varint,3,opt,name=until,proto3" json:"until,omitempty"` // 到期时间 (Unix 毫秒)，0 表示直到清除
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CustomStatus) Reset() {
+	*x = CustomStatus{}
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CustomStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CustomStatus) ProtoMessage() {}
+
+func (x *CustomStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CustomStatus.ProtoReflect.Descriptor instead.
+func (*CustomStatus) Descriptor() ([]byte, []int) {
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *CustomStatus) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *CustomStatus) GetEmoji() string {
+	if x != nil {
+		return x.Emoji
+	}
+	return ""
+}
+
+func (x *CustomStatus) GetUntil() int64 {
+	if x != nil {
+		return x.Until
+	}
+	return 0
+}
+
+type GetSnapshotRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSnapshotRequest) Reset() {
+	*x = GetSnapshotRequest{}
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSnapshotRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSnapshotRequest) ProtoMessage() {}
+
+func (x *GetSnapshotRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSnapshotRequest.ProtoReflect.Descriptor instead.
+func (*GetSnapshotRequest) Descriptor() ([]byte, []int) {
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{24}
+}
+
+type GetSnapshotResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Clients       []*WindowEvent         `protobuf:"bytes,1,rep,name=clients,proto3" json:"clients,omitempty"`
+	Me            *WindowEvent           `protobuf:"bytes,2,opt,name=me,proto3" json:"me,omitempty"`                                         // 在所有在线客户端中选出的权威活动
+	LastEventId   uint64                 `protobuf:"varint,3,opt,name=last_event_id,json=lastEventId,proto3" json:"last_event_id,omitempty"` // 快照对应的最后一个事件 ID，可用于之后的续传
+	Peers         []*WindowEvent         `protobuf:"bytes,4,rep,name=peers,proto3" json:"peers,omitempty"`                                   // 联邦对端服务器的连接状态，type 为 "peer"，status 为 online 或 offline
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSnapshotResponse) Reset() {
+	*x = GetSnapshotResponse{}
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSnapshotResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSnapshotResponse) ProtoMessage() {}
+
+func (x *GetSnapshotResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSnapshotResponse.ProtoReflect.Descriptor instead.
+func (*GetSnapshotResponse) Descriptor() ([]byte, []int) {
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *GetSnapshotResponse) GetClients() []*WindowEvent {
+	if x != nil {
+		return x.Clients
+	}
+	return nil
+}
+
+func (x *GetSnapshotResponse) GetMe() *WindowEvent {
+	if x != nil {
+		return x.Me
+	}
+	return nil
+}
+
+func (x *GetSnapshotResponse) GetLastEventId() uint64 {
+	if x != nil {
+		return x.LastEventId
+	}
+	return 0
+}
+
+func (x *GetSnapshotResponse) GetPeers() []*WindowEvent {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+type RelayEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Events        []*WindowEvent         `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`  // 按 ID 升序，空列表用作保活
+	Resync        bool                   `protobuf:"varint,2,opt,name=resync,proto3" json:"resync,omitempty"` // 为 true 时 events 是下游的全量快照，上游据此替换该中继的所有客户端
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RelayEventsRequest) Reset() {
+	*x = RelayEventsRequest{}
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RelayEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RelayEventsRequest) ProtoMessage() {}
+
+func (x *RelayEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RelayEventsRequest.ProtoReflect.Descriptor instead.
+func (*RelayEventsRequest) Descriptor() ([]byte, []int) {
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *RelayEventsRequest) GetEvents() []*WindowEvent {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+func (x *RelayEventsRequest) GetResync() bool {
+	if x != nil {
+		return x.Resync
+	}
+	return false
+}
+
+type RelayEventsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	LastId        uint64                 `protobuf:"varint,1,opt,name=last_id,json=lastId,proto3" json:"last_id,omitempty"` // 上游已接收的最后一个下游事件 ID，下游据此丢弃已送达的缓存
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RelayEventsResponse) Reset() {
+	*x = RelayEventsResponse{}
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RelayEventsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RelayEventsResponse) ProtoMessage() {}
+
+func (x *RelayEventsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_naniwosuruno_v1_service_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RelayEventsResponse.ProtoReflect.Descriptor instead.
+func (*RelayEventsResponse) Descriptor() ([]byte, []int) {
+	return file_naniwosuruno_v1_service_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *RelayEventsResponse) GetLastId() uint64 {
+	if x != nil {
+		return x.LastId
+	}
+	return 0
+}
+
+var File_naniwosuruno_v1_service_proto protoreflect.FileDescriptor
+
+const file_naniwosuruno_v1_service_proto_rawDesc = "" +
+	"\n" +
+	"\x1dnaniwosuruno/v1/service.proto\x12\x0fnaniwosuruno.v1\"5\n" +
+	"\x16CreateChallengeRequest\x12\x1b\n" +
 	"\tclient_id\x18\x01 \x01(\tR\bclientId\"7\n" +
 	"\x17CreateChallengeResponse\x12\x1c\n" +
 	"\tchallenge\x18\x01 \x01(\tR\tchallenge\"S\n" +
@@ -516,29 +1517,104 @@ const file_naniwosuruno_v1_service_proto_rawDesc = "" +
 	"\x17VerifyChallengeResponse\x12\x14\n" +
 	"\x05token\x18\x01 \x01(\tR\x05token\x12\x1d\n" +
 	"\n" +
-	"expires_in\x18\x02 \x01(\x03R\texpiresIn\";\n" +
+	"expires_in\x18\x02 \x01(\x03R\texpiresIn\"\\\n" +
 	"\x13ReportWindowRequest\x12\x14\n" +
 	"\x05title\x18\x01 \x01(\tR\x05title\x12\x0e\n" +
-	"\x02os\x18\x02 \x01(\tR\x02os\"\x16\n" +
-	"\x14ReportWindowResponse\"(\n" +
+	"\x02os\x18\x02 \x01(\tR\x02os\x12\x1f\n" +
+	"\vobserved_at\x18\x03 \x01(\x03R\n" +
+	"observedAt\"N\n" +
+	"\x0ePresenceUpdate\x12<\n" +
+	"\x06window\x18\x01 \x01(\v2$.naniwosuruno.v1.ReportWindowRequestR\x06window\"\x9d\x01\n" +
+	"\vPresenceAck\x12+\n" +
+	"\x11keepalive_timeout\x18\x01 \x01(\x03R\x10keepaliveTimeout\x12'\n" +
+	"\x0fconfig_revision\x18\x02 \x01(\tR\x0econfigRevision\x128\n" +
+	"\acontrol\x18\x03 \x01(\v2\x1e.naniwosuruno.v1.ClientControlR\acontrol\"V\n" +
+	"\x14ReportWindowsRequest\x12>\n" +
+	"\awindows\x18\x01 \x03(\v2$.naniwosuruno.v1.ReportWindowRequestR\awindows\"3\n" +
+	"\x15ReportWindowsResponse\x12\x1a\n" +
+	"\baccepted\x18\x01 \x01(\rR\baccepted\"\x16\n" +
+	"\x14ReportWindowResponse\">\n" +
 	"\x10HeartbeatRequest\x12\x14\n" +
-	"\x05count\x18\x01 \x01(\rR\x05count\")\n" +
+	"\x05count\x18\x01 \x01(\rR\x05count\x12\x14\n" +
+	"\x05epoch\x18\x02 \x01(\tR\x05epoch\"\x8c\x01\n" +
 	"\x11HeartbeatResponse\x12\x14\n" +
-	"\x05count\x18\x01 \x01(\rR\x05count\"5\n" +
+	"\x05count\x18\x01 \x01(\rR\x05count\x12'\n" +
+	"\x0fconfig_revision\x18\x02 \x01(\tR\x0econfigRevision\x128\n" +
+	"\acontrol\x18\x03 \x01(\v2\x1e.naniwosuruno.v1.ClientControlR\acontrol\"&\n" +
+	"\x0eGoodbyeRequest\x12\x14\n" +
+	"\x05epoch\x18\x01 \x01(\tR\x05epoch\"\x11\n" +
+	"\x0fGoodbyeResponse\"9\n" +
+	"\rClientControl\x12\x12\n" +
+	"\x04mode\x18\x01 \x01(\tR\x04mode\x12\x14\n" +
+	"\x05until\x18\x02 \x01(\x03R\x05until\"h\n" +
+	"\x14ControlClientRequest\x12\x16\n" +
+	"\x06client\x18\x01 \x01(\tR\x06client\x128\n" +
+	"\acontrol\x18\x02 \x01(\v2\x1e.naniwosuruno.v1.ClientControlR\acontrol\"1\n" +
+	"\x15ControlClientResponse\x12\x18\n" +
+	"\aclients\x18\x01 \x03(\tR\aclients\"O\n" +
+	"\x16SetCustomStatusRequest\x125\n" +
+	"\x06status\x18\x01 \x01(\v2\x1d.naniwosuruno.v1.CustomStatusR\x06status\"\x19\n" +
+	"\x17SetCustomStatusResponse\"\x18\n" +
+	"\x16GetClientConfigRequest\"\x8e\x02\n" +
+	"\x17GetClientConfigResponse\x12#\n" +
+	"\rpoll_interval\x18\x01 \x01(\x03R\fpollInterval\x12-\n" +
+	"\x12heartbeat_interval\x18\x02 \x01(\x03R\x11heartbeatInterval\x12\x1d\n" +
+	"\n" +
+	"idle_after\x18\x03 \x01(\x03R\tidleAfter\x12\x12\n" +
+	"\x04hide\x18\x04 \x03(\tR\x04hide\x12\x16\n" +
+	"\x06redact\x18\x05 \x03(\tR\x06redact\x12 \n" +
+	"\vplaceholder\x18\x06 \x01(\tR\vplaceholder\x12\x16\n" +
+	"\x06fields\x18\a \x03(\tR\x06fields\x12\x1a\n" +
+	"\brevision\x18\b \x01(\tR\brevision\"P\n" +
 	"\x16SubscribeEventsRequest\x12\x1b\n" +
-	"\tstream_id\x18\x01 \x01(\tR\bstreamId\"c\n" +
+	"\tstream_id\x18\x01 \x01(\tR\bstreamId\x12\x19\n" +
+	"\bsince_id\x18\x02 \x01(\x04R\asinceId\"\xe5\x02\n" +
 	"\vWindowEvent\x12\x14\n" +
 	"\x05title\x18\x01 \x01(\tR\x05title\x12\x0e\n" +
 	"\x02os\x18\x02 \x01(\tR\x02os\x12\x16\n" +
 	"\x06client\x18\x03 \x01(\tR\x06client\x12\x16\n" +
-	"\x06status\x18\x04 \x01(\tR\x06status2\xd9\x01\n" +
+	"\x06status\x18\x04 \x01(\tR\x06status\x12\x16\n" +
+	"\x06source\x18\x05 \x01(\tR\x06source\x12%\n" +
+	"\x0eschema_version\x18\x06 \x01(\rR\rschemaVersion\x12\x0e\n" +
+	"\x02id\x18\a \x01(\x04R\x02id\x12\x1c\n" +
+	"\ttimestamp\x18\b \x01(\x03R\ttimestamp\x12\x1b\n" +
+	"\tclient_id\x18\t \x01(\tR\bclientId\x12\x12\n" +
+	"\x04type\x18\n" +
+	" \x01(\tR\x04type\x12\x1e\n" +
+	"\n" +
+	"backfilled\x18\v \x01(\bR\n" +
+	"backfilled\x12B\n" +
+	"\rcustom_status\x18\f \x01(\v2\x1d.naniwosuruno.v1.CustomStatusR\fcustomStatus\"N\n" +
+	"\fCustomStatus\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\x12\x14\n" +
+	"\x05emoji\x18\x02 \x01(\tR\x05emoji\x12\x14\n" +
+	"\x05until\x18\x03 \x01(\x03R\x05until\"\x14\n" +
+	"\x12GetSnapshotRequest\"\xd3\x01\n" +
+	"\x13GetSnapshotResponse\x126\n" +
+	"\aclients\x18\x01 \x03(\v2\x1c.naniwosuruno.v1.WindowEventR\aclients\x12,\n" +
+	"\x02me\x18\x02 \x01(\v2\x1c.naniwosuruno.v1.WindowEventR\x02me\x12\"\n" +
+	"\rlast_event_id\x18\x03 \x01(\x04R\vlastEventId\x122\n" +
+	"\x05peers\x18\x04 \x03(\v2\x1c.naniwosuruno.v1.WindowEventR\x05peers\"b\n" +
+	"\x12RelayEventsRequest\x124\n" +
+	"\x06events\x18\x01 \x03(\v2\x1c.naniwosuruno.v1.WindowEventR\x06events\x12\x16\n" +
+	"\x06resync\x18\x02 \x01(\bR\x06resync\".\n" +
+	"\x13RelayEventsResponse\x12\x17\n" +
+	"\alast_id\x18\x01 \x01(\x04R\x06lastId2\xd9\x01\n" +
 	"\vAuthService\x12d\n" +
 	"\x0fCreateChallenge\x12'.naniwosuruno.v1.CreateChallengeRequest\x1a(.naniwosuruno.v1.CreateChallengeResponse\x12d\n" +
-	"\x0fVerifyChallenge\x12'.naniwosuruno.v1.VerifyChallengeRequest\x1a(.naniwosuruno.v1.VerifyChallengeResponse2\x9c\x02\n" +
+	"\x0fVerifyChallenge\x12'.naniwosuruno.v1.VerifyChallengeRequest\x1a(.naniwosuruno.v1.VerifyChallengeResponse2\xf9\a\n" +
 	"\rWindowService\x12[\n" +
-	"\fReportWindow\x12$.naniwosuruno.v1.ReportWindowRequest\x1a%.naniwosuruno.v1.ReportWindowResponse\x12R\n" +
-	"\tHeartbeat\x12!.naniwosuruno.v1.HeartbeatRequest\x1a\".naniwosuruno.v1.HeartbeatResponse\x12Z\n" +
-	"\x0fSubscribeEvents\x12'.naniwosuruno.v1.SubscribeEventsRequest\x1a\x1c.naniwosuruno.v1.WindowEvent0\x01BEZCgithub.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1;naniwosurunov1b\x06proto3"
+	"\fReportWindow\x12$.naniwosuruno.v1.ReportWindowRequest\x1a%.naniwosuruno.v1.ReportWindowResponse\x12^\n" +
+	"\rReportWindows\x12%.naniwosuruno.v1.ReportWindowsRequest\x1a&.naniwosuruno.v1.ReportWindowsResponse\x12M\n" +
+	"\bPresence\x12\x1f.naniwosuruno.v1.PresenceUpdate\x1a\x1c.naniwosuruno.v1.PresenceAck(\x010\x01\x12R\n" +
+	"\tHeartbeat\x12!.naniwosuruno.v1.HeartbeatRequest\x1a\".naniwosuruno.v1.HeartbeatResponse\x12L\n" +
+	"\aGoodbye\x12\x1f.naniwosuruno.v1.GoodbyeRequest\x1a .naniwosuruno.v1.GoodbyeResponse\x12d\n" +
+	"\x0fGetClientConfig\x12'.naniwosuruno.v1.GetClientConfigRequest\x1a(.naniwosuruno.v1.GetClientConfigResponse\x12^\n" +
+	"\rControlClient\x12%.naniwosuruno.v1.ControlClientRequest\x1a&.naniwosuruno.v1.ControlClientResponse\x12d\n" +
+	"\x0fSetCustomStatus\x12'.naniwosuruno.v1.SetCustomStatusRequest\x1a(.naniwosuruno.v1.SetCustomStatusResponse\x12Z\n" +
+	"\x0fSubscribeEvents\x12'.naniwosuruno.v1.SubscribeEventsRequest\x1a\x1c.naniwosuruno.v1.WindowEvent0\x01\x12X\n" +
+	"\vGetSnapshot\x12#.naniwosuruno.v1.GetSnapshotRequest\x1a$.naniwosuruno.v1.GetSnapshotResponse\x12X\n" +
+	"\vRelayEvents\x12#.naniwosuruno.v1.RelayEventsRequest\x1a$.naniwosuruno.v1.RelayEventsResponseBEZCgithub.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1;naniwosurunov1b\x06proto3"
 
 var (
 	file_naniwosuruno_v1_service_proto_rawDescOnce sync.Once
@@ -552,35 +1628,80 @@ func file_naniwosuruno_v1_service_proto_rawDescGZIP() []byte {
 	return file_naniwosuruno_v1_service_proto_rawDescData
 }
 
-var file_naniwosuruno_v1_service_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_naniwosuruno_v1_service_proto_msgTypes = make([]protoimpl.MessageInfo, 28)
 var file_naniwosuruno_v1_service_proto_goTypes = []any{
 	(*CreateChallengeRequest)(nil),  // 0: naniwosuruno.v1.CreateChallengeRequest
 	(*CreateChallengeResponse)(nil), // 1: naniwosuruno.v1.CreateChallengeResponse
 	(*VerifyChallengeRequest)(nil),  // 2: naniwosuruno.v1.VerifyChallengeRequest
 	(*VerifyChallengeResponse)(nil), // 3: naniwosuruno.v1.VerifyChallengeResponse
 	(*ReportWindowRequest)(nil),     // 4: naniwosuruno.v1.ReportWindowRequest
-	(*ReportWindowResponse)(nil),    // 5: naniwosuruno.v1.ReportWindowResponse
-	(*HeartbeatRequest)(nil),        // 6: naniwosuruno.v1.HeartbeatRequest
-	(*HeartbeatResponse)(nil),       // 7: naniwosuruno.v1.HeartbeatResponse
-	(*SubscribeEventsRequest)(nil),  // 8: naniwosuruno.v1.SubscribeEventsRequest
-	(*WindowEvent)(nil),             // 9: naniwosuruno.v1.WindowEvent
+	(*PresenceUpdate)(nil),          // 5: naniwosuruno.v1.PresenceUpdate
+	(*PresenceAck)(nil),             // 6: naniwosuruno.v1.PresenceAck
+	(*ReportWindowsRequest)(nil),    // 7: naniwosuruno.v1.ReportWindowsRequest
+	(*ReportWindowsResponse)(nil),   // 8: naniwosuruno.v1.ReportWindowsResponse
+	(*ReportWindowResponse)(nil),    // 9: naniwosuruno.v1.ReportWindowResponse
+	(*HeartbeatRequest)(nil),        // 10: naniwosuruno.v1.HeartbeatRequest
+	(*HeartbeatResponse)(nil),       // 11: naniwosuruno.v1.HeartbeatResponse
+	(*GoodbyeRequest)(nil),          // 12: naniwosuruno.v1.GoodbyeRequest
+	(*GoodbyeResponse)(nil),         // 13: naniwosuruno.v1.GoodbyeResponse
+	(*ClientControl)(nil),           // 14: naniwosuruno.v1.ClientControl
+	(*ControlClientRequest)(nil),    // 15: naniwosuruno.v1.ControlClientRequest
+	(*ControlClientResponse)(nil),   // 16: naniwosuruno.v1.ControlClientResponse
+	(*SetCustomStatusRequest)(nil),  // 17: naniwosuruno.v1.SetCustomStatusRequest
+	(*SetCustomStatusResponse)(nil), // 18: naniwosuruno.v1.SetCustomStatusResponse
+	(*GetClientConfigRequest)(nil),  // 19: naniwosuruno.v1.GetClientConfigRequest
+	(*GetClientConfigResponse)(nil), // 20: naniwosuruno.v1.GetClientConfigResponse
+	(*SubscribeEventsRequest)(nil),  // 21: naniwosuruno.v1.SubscribeEventsRequest
+	(*WindowEvent)(nil),             // 22: naniwosuruno.v1.WindowEvent
+	(*CustomStatus)(nil),            // 23: naniwosuruno.v1.CustomStatus
+	(*GetSnapshotRequest)(nil),      // 24: naniwosuruno.v1.GetSnapshotRequest
+	(*GetSnapshotResponse)(nil),     // 25: naniwosuruno.v1.GetSnapshotResponse
+	(*RelayEventsRequest)(nil),      // 26: naniwosuruno.v1.RelayEventsRequest
+	(*RelayEventsResponse)(nil),     // 27: naniwosuruno.v1.RelayEventsResponse
 }
 var file_naniwosuruno_v1_service_proto_depIdxs = []int32{
-	0, // 0: naniwosuruno.v1.AuthService.CreateChallenge:input_type -> naniwosuruno.v1.CreateChallengeRequest
-	2, // 1: naniwosuruno.v1.AuthService.VerifyChallenge:input_type -> naniwosuruno.v1.VerifyChallengeRequest
-	4, // 2: naniwosuruno.v1.WindowService.ReportWindow:input_type -> naniwosuruno.v1.ReportWindowRequest
-	6, // 3: naniwosuruno.v1.WindowService.Heartbeat:input_type -> naniwosuruno.v1.HeartbeatRequest
-	8, // 4: naniwosuruno.v1.WindowService.SubscribeEvents:input_type -> naniwosuruno.v1.SubscribeEventsRequest
-	1, // 5: naniwosuruno.v1.AuthService.CreateChallenge:output_type -> naniwosuruno.v1.CreateChallengeResponse
-	3, // 6: naniwosuruno.v1.AuthService.VerifyChallenge:output_type -> naniwosuruno.v1.VerifyChallengeResponse
-	5, // 7: naniwosuruno.v1.WindowService.ReportWindow:output_type -> naniwosuruno.v1.ReportWindowResponse
-	7, // 8: naniwosuruno.v1.WindowService.Heartbeat:output_type -> naniwosuruno.v1.HeartbeatResponse
-	9, // 9: naniwosuruno.v1.WindowService.SubscribeEvents:output_type -> naniwosuruno.v1.WindowEvent
-	5, // [5:10] is the sub-list for method output_type
-	0, // [0:5] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	4,  // 0: naniwosuruno.v1.PresenceUpdate.window:type_name -> naniwosuruno.v1.ReportWindowRequest
+	14, // 1: naniwosuruno.v1.PresenceAck.control:type_name -> naniwosuruno.v1.ClientControl
+	4,  // 2: naniwosuruno.v1.ReportWindowsRequest.windows:type_name -> naniwosuruno.v1.ReportWindowRequest
+	14, // 3: naniwosuruno.v1.HeartbeatResponse.control:type_name -> naniwosuruno.v1.ClientControl
+	14, // 4: naniwosuruno.v1.ControlClientRequest.control:type_name -> naniwosuruno.v1.ClientControl
+	23, // 5: naniwosuruno.v1.SetCustomStatusRequest.status:type_name -> naniwosuruno.v1.CustomStatus
+	23, // 6: naniwosuruno.v1.WindowEvent.custom_status:type_name -> naniwosuruno.v1.CustomStatus
+	22, // 7: naniwosuruno.v1.GetSnapshotResponse.clients:type_name -> naniwosuruno.v1.WindowEvent
+	22, // 8: naniwosuruno.v1.GetSnapshotResponse.me:type_name -> naniwosuruno.v1.WindowEvent
+	22, // 9: naniwosuruno.v1.GetSnapshotResponse.peers:type_name -> naniwosuruno.v1.WindowEvent
+	22, // 10: naniwosuruno.v1.RelayEventsRequest.events:type_name -> naniwosuruno.v1.WindowEvent
+	0,  // 11: naniwosuruno.v1.AuthService.CreateChallenge:input_type -> naniwosuruno.v1.CreateChallengeRequest
+	2,  // 12: naniwosuruno.v1.AuthService.VerifyChallenge:input_type -> naniwosuruno.v1.VerifyChallengeRequest
+	4,  // 13: naniwosuruno.v1.WindowService.ReportWindow:input_type -> naniwosuruno.v1.ReportWindowRequest
+	7,  // 14: naniwosuruno.v1.WindowService.ReportWindows:input_type -> naniwosuruno.v1.ReportWindowsRequest
+	5,  // 15: naniwosuruno.v1.WindowService.Presence:input_type -> naniwosuruno.v1.PresenceUpdate
+	10, // 16: naniwosuruno.v1.WindowService.Heartbeat:input_type -> naniwosuruno.v1.HeartbeatRequest
+	12, // 17: naniwosuruno.v1.WindowService.Goodbye:input_type -> naniwosuruno.v1.GoodbyeRequest
+	19, // 18: naniwosuruno.v1.WindowService.GetClientConfig:input_type -> naniwosuruno.v1.GetClientConfigRequest
+	15, // 19: naniwosuruno.v1.WindowService.ControlClient:input_type -> naniwosuruno.v1.ControlClientRequest
+	17, // 20: naniwosuruno.v1.WindowService.SetCustomStatus:input_type -> naniwosuruno.v1.SetCustomStatusRequest
+	21, // 21: naniwosuruno.v1.WindowService.SubscribeEvents:input_type -> naniwosuruno.v1.SubscribeEventsRequest
+	24, // 22: naniwosuruno.v1.WindowService.GetSnapshot:input_type -> naniwosuruno.v1.GetSnapshotRequest
+	26, // 23: naniwosuruno.v1.WindowService.RelayEvents:input_type -> naniwosuruno.v1.RelayEventsRequest
+	1,  // 24: naniwosuruno.v1.AuthService.CreateChallenge:output_type -> naniwosuruno.v1.CreateChallengeResponse
+	3,  // 25: naniwosuruno.v1.AuthService.VerifyChallenge:output_type -> naniwosuruno.v1.VerifyChallengeResponse
+	9,  // 26: naniwosuruno.v1.WindowService.ReportWindow:output_type -> naniwosuruno.v1.ReportWindowResponse
+	8,  // 27: naniwosuruno.v1.WindowService.ReportWindows:output_type -> naniwosuruno.v1.ReportWindowsResponse
+	6,  // 28: naniwosuruno.v1.WindowService.Presence:output_type -> naniwosuruno.v1.PresenceAck
+	11, // 29: naniwosuruno.v1.WindowService.Heartbeat:output_type -> naniwosuruno.v1.HeartbeatResponse
+	13, // 30: naniwosuruno.v1.WindowService.Goodbye:output_type -> naniwosuruno.v1.GoodbyeResponse
+	20, // 31: naniwosuruno.v1.WindowService.GetClientConfig:output_type -> naniwosuruno.v1.GetClientConfigResponse
+	16, // 32: naniwosuruno.v1.WindowService.ControlClient:output_type -> naniwosuruno.v1.ControlClientResponse
+	18, // 33: naniwosuruno.v1.WindowService.SetCustomStatus:output_type -> naniwosuruno.v1.SetCustomStatusResponse
+	22, // 34: naniwosuruno.v1.WindowService.SubscribeEvents:output_type -> naniwosuruno.v1.WindowEvent
+	25, // 35: naniwosuruno.v1.WindowService.GetSnapshot:output_type -> naniwosuruno.v1.GetSnapshotResponse
+	27, // 36: naniwosuruno.v1.WindowService.RelayEvents:output_type -> naniwosuruno.v1.RelayEventsResponse
+	24, // [24:37] is the sub-list for method output_type
+	11, // [11:24] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
 }
 
 func init() { file_naniwosuruno_v1_service_proto_init() }
@@ -594,7 +1715,7 @@ func file_naniwosuruno_v1_service_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_naniwosuruno_v1_service_proto_rawDesc), len(file_naniwosuruno_v1_service_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   10,
+			NumMessages:   28,
 			NumExtensions: 0,
 			NumServices:   2,
 		},
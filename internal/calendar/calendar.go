@@ -0,0 +1,273 @@
+// Package calendar 读取本地 .ics 日程（文件、目录或本地 URL），展开重复事件，
+// 在忙碌事件期间将 "me" 与各客户端的可见状态覆盖为如 "In a meeting until 15:30" 的文字。
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+const (
+	defaultRefresh = 5 * time.Minute
+	requestTimeout = 10 * time.Second
+	// window 为每次读取时展开重复事件的时间范围（读取时刻前后），超过该长度的事件不会被识别
+	window = 7 * 24 * time.Hour
+
+	PrivacyMeeting = "meeting" // 显示 "In a meeting until 15:30"
+	PrivacyDetails = "details" // 显示事件标题，如 "Standup until 15:30"
+	PrivacyBusy    = "busy"    // 只显示 "Busy"，不透露结束时间
+
+	emoji = "📅"
+)
+
+// Target 接收当前正在进行的忙碌事件，nil 表示没有，由 WindowService 实现
+type Target interface {
+	SetCalendarStatus(status *naniwosurunov1.CustomStatus)
+}
+
+// Interval 是一次占用时间的事件发生
+type Interval struct {
+	Start   time.Time
+	End     time.Time
+	Summary string
+	Privacy string // 所属日程的隐私级别
+}
+
+// Watcher 定期读取日程，并在忙碌事件开始与结束时通知 Target
+type Watcher struct {
+	sources    []pkg.CalendarSource
+	refresh    time.Duration
+	loc        *time.Location
+	httpClient *http.Client
+	loaded     map[int][]Interval // 各日程最近一次成功读取的结果，读取失败时沿用
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New 根据配置创建 Watcher，Path 与 URL 都为空的日程会被忽略
+func New(cfg pkg.CalendarConfig) *Watcher {
+	w := &Watcher{
+		refresh:    time.Duration(cfg.Refresh) * time.Second,
+		loc:        time.Local,
+		httpClient: &http.Client{Timeout: requestTimeout},
+		loaded:     make(map[int][]Interval),
+	}
+	if w.refresh <= 0 {
+		w.refresh = defaultRefresh
+	}
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			log.Printf("日程时区 %q 无效，使用本地时区: %v", cfg.Timezone, err)
+		} else {
+			w.loc = loc
+		}
+	}
+	for _, src := range cfg.Calendars {
+		if src.Path == "" && src.URL == "" {
+			log.Printf("日程 %q 未配置 path 或 url，已忽略", src.Name)
+			continue
+		}
+		w.sources = append(w.sources, src)
+	}
+	return w
+}
+
+// Enabled 判断是否配置了至少一个日程
+func (w *Watcher) Enabled() bool {
+	return len(w.sources) > 0
+}
+
+// Start 开始定期读取日程并通知 target
+func (w *Watcher) Start(target Target) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.run(ctx, target)
+	}()
+}
+
+// Stop 停止读取日程
+func (w *Watcher) Stop() {
+	w.cancel()
+	w.wg.Wait()
+}
+
+// run 在每次读取后以及忙碌事件的开始、结束时刻重新计算当前状态
+func (w *Watcher) run(ctx context.Context, target Target) {
+	var intervals []Interval
+	var nextLoad time.Time
+	for {
+		now := time.Now()
+		if !now.Before(nextLoad) {
+			intervals = w.load(ctx, now)
+			nextLoad = now.Add(w.refresh)
+		}
+		status, next := Current(intervals, now, w.loc)
+		target.SetCalendarStatus(status)
+
+		wake := nextLoad
+		if !next.IsZero() && next.Before(wake) {
+			wake = next
+		}
+		timer := time.NewTimer(max(time.Until(wake), time.Second))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// load 读取所有日程并展开 now 前后的忙碌事件，读取失败的日程沿用上一次的结果
+func (w *Watcher) load(ctx context.Context, now time.Time) []Interval {
+	var all []Interval
+	for i, src := range w.sources {
+		events, err := w.read(ctx, src)
+		if err != nil {
+			log.Printf("读取日程 %q 失败: %v", src.Name, err)
+		} else {
+			w.loaded[i] = BusyIntervals(events, now.Add(-window), now.Add(window), src.Privacy)
+		}
+		all = append(all, w.loaded[i]...)
+	}
+	return all
+}
+
+// read 读取一个日程来源：单个 .ics 文件、包含 .ics 文件的目录（递归），或返回 .ics 内容的 URL
+func (w *Watcher) read(ctx context.Context, src pkg.CalendarSource) ([]Event, error) {
+	if src.URL != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "text/calendar")
+		resp, err := w.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return nil, fmt.Errorf("GET %s: %s", src.URL, resp.Status)
+		}
+		return Parse(io.LimitReader(resp.Body, 16<<20), w.loc)
+	}
+
+	info, err := os.Stat(src.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return w.readFile(src.Path)
+	}
+	var events []Event
+	err = filepath.WalkDir(src.Path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".ics") {
+			return err
+		}
+		parsed, err := w.readFile(path)
+		if err != nil {
+			return err
+		}
+		events = append(events, parsed...)
+		return nil
+	})
+	return events, err
+}
+
+func (w *Watcher) readFile(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f, w.loc)
+}
+
+// BusyIntervals 展开事件并返回在 [from, to) 内进行的忙碌发生，按开始时间排序。
+// 全天、透明 (TRANSP:TRANSPARENT) 与已取消的事件不算忙碌；带 RECURRENCE-ID 的事件替换重复事件中对应的一次
+func BusyIntervals(events []Event, from, to time.Time, privacy string) []Interval {
+	overrides := make(map[string][]time.Time)
+	for _, ev := range events {
+		if !ev.RecurrenceID.IsZero() {
+			overrides[ev.UID] = append(overrides[ev.UID], ev.RecurrenceID)
+		}
+	}
+
+	var out []Interval
+	for _, ev := range events {
+		if ev.AllDay || ev.Transparent || ev.Cancelled {
+			continue
+		}
+		duration := ev.End.Sub(ev.Start)
+		for _, start := range ev.Occurrences(from, to) {
+			if ev.RecurrenceID.IsZero() && slices.ContainsFunc(overrides[ev.UID], start.Equal) {
+				continue
+			}
+			out = append(out, Interval{Start: start, End: start.Add(duration), Summary: ev.Summary, Privacy: privacy})
+		}
+	}
+	slices.SortFunc(out, func(a, b Interval) int { return a.Start.Compare(b.Start) })
+	return out
+}
+
+// Current 返回 now 时正在进行的忙碌事件对应的状态（多个事件重叠时取结束最晚的一个），
+// 以及下一次需要重新计算的时刻（某个事件开始或当前事件结束），没有时为零值
+func Current(intervals []Interval, now time.Time, loc *time.Location) (*naniwosurunov1.CustomStatus, time.Time) {
+	var active *Interval
+	var next time.Time
+	later := func(t time.Time) {
+		if t.After(now) && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	for i, iv := range intervals {
+		if !iv.Start.After(now) && iv.End.After(now) {
+			if active == nil || iv.End.After(active.End) {
+				active = &intervals[i]
+			}
+			later(iv.End)
+		} else {
+			later(iv.Start)
+		}
+	}
+	if active == nil {
+		return nil, next
+	}
+	return active.status(now, loc), next
+}
+
+// status 按日程的隐私级别生成显示的文字
+func (iv Interval) status(now time.Time, loc *time.Location) *naniwosurunov1.CustomStatus {
+	if iv.Privacy == PrivacyBusy {
+		return &naniwosurunov1.CustomStatus{Text: "Busy", Emoji: emoji}
+	}
+
+	end := iv.End.In(loc)
+	until := end.Format("15:04")
+	if y, m, d := now.In(loc).Date(); end.Year() != y || end.Month() != m || end.Day() != d {
+		until = end.Format("Jan 2 15:04")
+	}
+	text := "In a meeting"
+	if iv.Privacy == PrivacyDetails && strings.TrimSpace(iv.Summary) != "" {
+		text = strings.TrimSpace(iv.Summary)
+	}
+	return &naniwosurunov1.CustomStatus{Text: text + " until " + until, Emoji: emoji, Until: iv.End.UnixMilli()}
+}
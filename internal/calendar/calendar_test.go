@@ -0,0 +1,178 @@
+package calendar
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+func TestParse(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	events := loadFixture(t, "work.ics", berlin)
+
+	// 折叠行被合并，转义字符被还原，VALARM 中的 DURATION 不影响事件
+	standup := events["standup@example.com"]
+	if len(standup) != 3 {
+		t.Fatalf("standup events = %d, want master and two overrides", len(standup))
+	}
+	master := standup[0]
+	if master.Summary != "Team standup, daily sync with a very long title that an exporter folded" {
+		t.Errorf("summary = %q", master.Summary)
+	}
+	if master.End.Sub(master.Start) != 15*time.Minute || master.Rule == nil || len(master.ExDates) != 1 {
+		t.Errorf("standup = %+v", master)
+	}
+	if !standup[2].Cancelled || standup[2].RecurrenceID.IsZero() {
+		t.Errorf("cancelled override = %+v", standup[2])
+	}
+
+	// 没有 DTEND 时使用 DURATION，全天与透明事件被标记
+	if review := events["review@example.com"][0]; review.End.Sub(review.Start) != 90*time.Minute {
+		t.Errorf("review duration = %v, want 1h30m", review.End.Sub(review.Start))
+	}
+	if !events["holiday@example.com"][0].AllDay || !events["focus@example.com"][0].Transparent {
+		t.Error("holiday should be all-day and focus time transparent")
+	}
+}
+
+func TestBusyIntervals(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	var events []Event
+	for _, list := range loadFixture(t, "work.ics", berlin) {
+		events = append(events, list...)
+	}
+	utc := func(d, h, m int) time.Time { return time.Date(2026, 3, d, h, m, 0, 0, time.UTC) }
+
+	// 3 月 25 日被 EXDATE 排除，4 月 1 日被改到下午，4 月 3 日被取消；
+	// 透明的专注时间与全天的假期不算忙碌；3 月 29 日起为夏令时
+	got := BusyIntervals(events, utc(23, 0, 0), utc(35, 0, 0), "")
+	want := []struct {
+		start   time.Time
+		summary string
+	}{
+		{utc(23, 8, 30), "Team standup"},
+		{utc(24, 13, 0), "Design review"},
+		{utc(27, 8, 30), "Team standup"},
+		{utc(30, 7, 30), "Team standup"},
+		{utc(32, 12, 0), "Team standup (moved)"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("busy intervals = %v, want %d", got, len(want))
+	}
+	for i, w := range want {
+		if !got[i].Start.Equal(w.start) || !strings.HasPrefix(got[i].Summary, w.summary) {
+			t.Errorf("interval %d = %s %q, want %s %q", i, got[i].Start.UTC(), got[i].Summary, w.start, w.summary)
+		}
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	at := func(h, m int) time.Time { return time.Date(2026, 3, 24, h, m, 0, 0, berlin) }
+	review := Interval{Start: at(14, 0), End: at(15, 30), Summary: "Design review"}
+
+	// 1. 事件开始前没有状态，下一次计算在事件开始时
+	status, next := Current([]Interval{review}, at(13, 0), berlin)
+	if status != nil || !next.Equal(review.Start) {
+		t.Errorf("before = %v, next %v", status, next)
+	}
+
+	// 2. 各隐私级别显示的文字
+	cases := map[string]string{
+		"":             "In a meeting until 15:30",
+		PrivacyDetails: "Design review until 15:30",
+		PrivacyBusy:    "Busy",
+	}
+	for privacy, want := range cases {
+		iv := review
+		iv.Privacy = privacy
+		status, next := Current([]Interval{iv}, at(14, 30), berlin)
+		if status.GetText() != want || !next.Equal(review.End) {
+			t.Errorf("privacy %q = %v, next %v, want %q", privacy, status, next, want)
+		}
+		if (privacy == PrivacyBusy) != (status.GetUntil() == 0) {
+			t.Errorf("privacy %q until = %d", privacy, status.GetUntil())
+		}
+	}
+
+	// 3. 重叠的事件取结束最晚的一个，结束时间不在当天时带上日期
+	overnight := Interval{Start: at(14, 15), End: at(14, 15).Add(20 * time.Hour)}
+	status, _ = Current([]Interval{review, overnight}, at(14, 30), berlin)
+	if status.GetText() != "In a meeting until Mar 25 10:15" {
+		t.Errorf("overlapping = %q", status.GetText())
+	}
+}
+
+type recordingTarget struct {
+	mu     sync.Mutex
+	status []*naniwosurunov1.CustomStatus
+}
+
+func (r *recordingTarget) SetCalendarStatus(status *naniwosurunov1.CustomStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = append(r.status, status)
+}
+
+func (r *recordingTarget) last() *naniwosurunov1.CustomStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.status) == 0 {
+		return nil
+	}
+	return r.status[len(r.status)-1]
+}
+
+func TestWatcher(t *testing.T) {
+	// 1. 从本地 URL 读取正在进行的会议，结束时间按配置的时区显示
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	start := time.Now().Add(-10 * time.Minute).UTC()
+	end := time.Now().Add(50 * time.Minute).UTC()
+	ics := fmt.Sprintf("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:now\r\nSUMMARY:Planning\r\nDTSTART:%s\r\nDTEND:%s\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+		start.Format("20060102T150405Z"), end.Format("20060102T150405Z"))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/calendar")
+		_, _ = w.Write([]byte(ics))
+	}))
+	defer ts.Close()
+
+	w := New(pkg.CalendarConfig{
+		Timezone: "Asia/Tokyo",
+		Calendars: []pkg.CalendarSource{
+			{Name: "work", URL: ts.URL, Privacy: PrivacyDetails},
+			{Name: "fixtures", Path: "testdata"},
+			{Name: "empty"},
+		},
+	})
+	if !w.Enabled() || len(w.sources) != 2 {
+		t.Fatalf("sources = %+v", w.sources)
+	}
+	target := &recordingTarget{}
+	w.Start(target)
+	defer w.Stop()
+
+	want := "Planning until " + end.In(tokyo).Format("15:04")
+	deadline := time.Now().Add(2 * time.Second)
+	for target.last().GetText() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("calendar status = %v, want %q", target.last(), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 2. 目录中的所有 .ics 文件都会被读取
+	events, err := w.read(t.Context(), pkg.CalendarSource{Path: "testdata"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 6+12 {
+		t.Errorf("events in testdata = %d, want 18", len(events))
+	}
+}
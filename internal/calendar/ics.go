@@ -0,0 +1,301 @@
+package calendar
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event 是日程中的一个事件 (VEVENT)，重复事件的每次发生由 Occurrences 展开
+type Event struct {
+	UID          string
+	Summary      string
+	Start        time.Time
+	End          time.Time
+	AllDay       bool // DTSTART 为日期，全天事件不视为忙碌
+	Transparent  bool // TRANSP:TRANSPARENT，不占用时间
+	Cancelled    bool // STATUS:CANCELLED
+	Rule         *Rule
+	ExDates      []time.Time
+	RDates       []time.Time
+	RecurrenceID time.Time // 非零表示该事件替换同一 UID 重复事件中开始于此时间的那一次
+}
+
+// Parse 解析 iCalendar 内容中的所有 VEVENT，loc 用于不带时区的时间与无法识别的 TZID。
+// 无法解析的事件会被跳过，重复规则无效时只保留第一次发生
+func Parse(r io.Reader, loc *time.Location) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	var current *Event
+	var later pending
+	nested := 0 // VEVENT 内嵌套的组件（如 VALARM）层数，其中的属性不属于事件本身
+	for _, line := range lines {
+		prop, ok := parseProperty(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VEVENT") && current == nil:
+			current = &Event{}
+			later = pending{}
+		case current == nil:
+		case prop.name == "BEGIN":
+			nested++
+		case prop.name == "END" && nested > 0:
+			nested--
+		case nested > 0:
+		case prop.name == "END" && strings.EqualFold(prop.value, "VEVENT"):
+			if ev, err := finish(current, later, loc); err != nil {
+				log.Printf("忽略无法解析的日程事件 %q: %v", current.Summary, err)
+			} else {
+				events = append(events, ev)
+			}
+			current = nil
+		default:
+			if err := current.set(prop, loc, &later); err != nil {
+				log.Printf("忽略日程事件 %q 中无法解析的 %s: %v", current.Summary, prop.name, err)
+			}
+		}
+	}
+	return events, nil
+}
+
+// pending 保存需要等 DTSTART 确定后才能解析的属性
+type pending struct {
+	rrule    string
+	duration string
+}
+
+// set 将一个属性写入事件，RRULE 与 DURATION 先保存在 later 中
+func (e *Event) set(prop property, loc *time.Location, later *pending) error {
+	switch prop.name {
+	case "UID":
+		e.UID = prop.value
+	case "SUMMARY":
+		e.Summary = unescape(prop.value)
+	case "DTSTART":
+		t, allDay, err := parseTime(prop.value, prop.params, loc)
+		if err != nil {
+			return err
+		}
+		e.Start, e.AllDay = t, allDay
+	case "DTEND":
+		t, _, err := parseTime(prop.value, prop.params, loc)
+		if err != nil {
+			return err
+		}
+		e.End = t
+	case "DURATION":
+		later.duration = prop.value
+	case "TRANSP":
+		e.Transparent = strings.EqualFold(prop.value, "TRANSPARENT")
+	case "STATUS":
+		e.Cancelled = strings.EqualFold(prop.value, "CANCELLED")
+	case "RRULE":
+		later.rrule = prop.value
+	case "EXDATE", "RDATE":
+		if strings.EqualFold(prop.params["VALUE"], "PERIOD") {
+			return errors.New("PERIOD values are not supported")
+		}
+		for _, v := range strings.Split(prop.value, ",") {
+			t, _, err := parseTime(v, prop.params, loc)
+			if err != nil {
+				return err
+			}
+			if prop.name == "EXDATE" {
+				e.ExDates = append(e.ExDates, t)
+			} else {
+				e.RDates = append(e.RDates, t)
+			}
+		}
+	case "RECURRENCE-ID":
+		t, _, err := parseTime(prop.value, prop.params, loc)
+		if err != nil {
+			return err
+		}
+		e.RecurrenceID = t
+	}
+	return nil
+}
+
+// finish 补全事件的结束时间（DTEND 优先于 DURATION）并解析重复规则
+func finish(e *Event, later pending, loc *time.Location) (Event, error) {
+	if e.Start.IsZero() {
+		return Event{}, errors.New("missing DTSTART")
+	}
+	if e.End.IsZero() && later.duration != "" {
+		d, err := parseDuration(later.duration)
+		if err != nil {
+			return Event{}, err
+		}
+		e.End = e.Start.Add(d)
+	}
+	switch {
+	case e.End.IsZero() && e.AllDay:
+		e.End = e.Start.AddDate(0, 0, 1)
+	case e.End.IsZero() || e.End.Before(e.Start):
+		e.End = e.Start
+	}
+	if later.rrule != "" {
+		rule, err := ParseRule(later.rrule, loc)
+		if err != nil {
+			log.Printf("日程事件 %q 的重复规则无效，只保留第一次: %v", e.Summary, err)
+		} else {
+			e.Rule = rule
+		}
+	}
+	return *e, nil
+}
+
+// unfold 按行读取并合并折叠行：以空格或制表符开头的行是上一行的延续
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// parseProperty 解析形如 NAME;PARAM=value;PARAM="quoted":value 的一行
+func parseProperty(line string) (property, bool) {
+	quoted := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return property{}, false
+	}
+
+	prop := property{params: map[string]string{}, value: line[colon+1:]}
+	parts := splitQuoted(line[:colon], ';')
+	prop.name = strings.ToUpper(parts[0])
+	for _, p := range parts[1:] {
+		if k, v, ok := strings.Cut(p, "="); ok {
+			prop.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return prop, true
+}
+
+// splitQuoted 按 sep 切分，忽略引号内的分隔符
+func splitQuoted(s string, sep rune) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func unescape(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
+
+// parseTime 解析 DATE 或 DATE-TIME：以 Z 结尾的为 UTC，带 TZID 的使用对应时区，其余视为 loc 中的本地时间
+func parseTime(value string, params map[string]string, loc *time.Location) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(params["VALUE"], "DATE") || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	if tzid := params["TZID"]; tzid != "" {
+		if tz, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			loc = tz
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// parseDuration 解析 RFC 5545 的时长，如 PT45M、P1DT2H、P1W
+func parseDuration(value string) (time.Duration, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign, s = -1, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	var total time.Duration
+	inTime := false
+	num := ""
+	for _, r := range s[1:] {
+		switch {
+		case r >= '0' && r <= '9':
+			num += string(r)
+			continue
+		case r == 'T':
+			inTime = true
+			continue
+		}
+		n, err := strconv.Atoi(num)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		num = ""
+		switch {
+		case r == 'W' && !inTime:
+			total += time.Duration(n) * 7 * 24 * time.Hour
+		case r == 'D' && !inTime:
+			total += time.Duration(n) * 24 * time.Hour
+		case r == 'H' && inTime:
+			total += time.Duration(n) * time.Hour
+		case r == 'M' && inTime:
+			total += time.Duration(n) * time.Minute
+		case r == 'S' && inTime:
+			total += time.Duration(n) * time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+	}
+	if num != "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return sign * total, nil
+}
@@ -0,0 +1,403 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPeriods 为展开重复规则时最多遍历的周期数，避免无法命中任何日期的规则无限循环
+const maxPeriods = 100000
+
+// Rule 是 RFC 5545 的重复规则 (RRULE)，支持 DAILY、WEEKLY、MONTHLY、YEARLY 以及
+// INTERVAL、COUNT、UNTIL、BYDAY、BYMONTHDAY、BYMONTH、BYSETPOS、WKST
+type Rule struct {
+	Freq       string
+	Interval   int
+	Count      int       // 0 表示不限
+	Until      time.Time // 零值表示不限，包含该时刻
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	BySetPos   []int
+	WeekStart  time.Weekday
+}
+
+// WeekdayNum 为 BYDAY 中的一项，N 为 0 表示每个该星期几，正数为第 N 个，负数为倒数第 N 个
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// ParseRule 解析 RRULE 的值，如 "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE"，loc 用于不带时区的 UNTIL
+func ParseRule(value string, loc *time.Location) (*Rule, error) {
+	r := &Rule{Interval: 1, WeekStart: time.Monday}
+	for _, part := range strings.Split(value, ";") {
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		var err error
+		switch strings.ToUpper(k) {
+		case "FREQ":
+			r.Freq = strings.ToUpper(v)
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(v)
+			if err == nil && r.Interval < 1 {
+				err = errors.New("INTERVAL must be positive")
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(v)
+		case "UNTIL":
+			var allDay bool
+			r.Until, allDay, err = parseTime(v, nil, loc)
+			if allDay {
+				// 日期形式的 UNTIL 包含当天的所有发生
+				r.Until = r.Until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			}
+		case "BYDAY":
+			for _, d := range strings.Split(v, ",") {
+				var wd WeekdayNum
+				if wd, err = parseWeekdayNum(d); err != nil {
+					break
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseInts(v, 1, 31)
+		case "BYMONTH":
+			var months []int
+			months, err = parseInts(v, 1, 12)
+			for _, m := range months {
+				if m < 0 {
+					err = fmt.Errorf("invalid BYMONTH %d", m)
+				}
+				r.ByMonth = append(r.ByMonth, time.Month(m))
+			}
+		case "BYSETPOS":
+			r.BySetPos, err = parseInts(v, 1, 366)
+		case "WKST":
+			day, ok := weekdays[strings.ToUpper(v)]
+			if !ok {
+				err = fmt.Errorf("invalid WKST %q", v)
+			}
+			r.WeekStart = day
+		default:
+			// BYHOUR、BYWEEKNO 等规则会改变发生的时间或周次，忽略它们会得到错误的结果
+			err = fmt.Errorf("%s is not supported", strings.ToUpper(k))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch r.Freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	case "":
+		return nil, errors.New("missing FREQ")
+	default:
+		return nil, fmt.Errorf("FREQ=%s is not supported", r.Freq)
+	}
+	return r, nil
+}
+
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", s)
+	}
+	day, ok := weekdays[s[len(s)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", s)
+	}
+	wd := WeekdayNum{Day: day}
+	if n := s[:len(s)-2]; n != "" {
+		var err error
+		if wd.N, err = strconv.Atoi(n); err != nil || wd.N == 0 {
+			return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", s)
+		}
+	}
+	return wd, nil
+}
+
+// parseInts 解析以逗号分隔的整数，绝对值需在 [min, max] 之间，负数表示倒数
+func parseInts(s string, min, max int) ([]int, error) {
+	var out []int
+	for _, part := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n == 0 || n < -max || n > max || (n > 0 && n < min) {
+			return nil, fmt.Errorf("invalid value %q", part)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+// Occurrences 返回事件在 [from, to) 内仍在进行的每次发生的开始时间，按时间排序。
+// EXDATE 排除的发生仍计入 COUNT；RDATE 追加的发生不受 COUNT 与 UNTIL 限制
+func (e *Event) Occurrences(from, to time.Time) []time.Time {
+	duration := e.End.Sub(e.Start)
+	overlaps := func(t time.Time) bool {
+		end := t.Add(duration)
+		// 零时长的事件在开始时刻视为进行中
+		return t.Before(to) && (end.After(from) || (duration == 0 && !t.Before(from)))
+	}
+
+	var starts []time.Time
+	if e.Rule == nil {
+		if overlaps(e.Start) {
+			starts = append(starts, e.Start)
+		}
+	} else {
+		e.Rule.expand(e.Start, func(t time.Time) bool {
+			if !t.Before(to) {
+				return false
+			}
+			if overlaps(t) {
+				starts = append(starts, t)
+			}
+			return true
+		})
+	}
+	for _, t := range e.RDates {
+		if e.AllDay {
+			t = combine(civil(t), e.Start)
+		}
+		if overlaps(t) && !slices.ContainsFunc(starts, t.Equal) {
+			starts = append(starts, t)
+		}
+	}
+
+	starts = slices.DeleteFunc(starts, e.excluded)
+	slices.SortFunc(starts, func(a, b time.Time) int { return a.Compare(b) })
+	return starts
+}
+
+// excluded 判断某次发生是否被 EXDATE 排除，全天事件按日期比较
+func (e *Event) excluded(t time.Time) bool {
+	for _, ex := range e.ExDates {
+		if t.Equal(ex) || (e.AllDay && civil(t).Equal(civil(ex))) {
+			return true
+		}
+	}
+	return false
+}
+
+// expand 按时间顺序依次将每次发生的开始时间（第一次为 start 本身）交给 fn，fn 返回 false 时停止。
+// 每次发生保持 start 在其时区中的钟面时间，因此跨越夏令时切换后仍在同一时刻开始
+func (r *Rule) expand(start time.Time, fn func(time.Time) bool) {
+	emitted := 0
+	emit := func(t time.Time) bool {
+		if (!r.Until.IsZero() && t.After(r.Until)) || (r.Count > 0 && emitted >= r.Count) {
+			return false
+		}
+		emitted++
+		return fn(t)
+	}
+	if !emit(start) {
+		return
+	}
+
+	base := civil(start)
+	for k := 0; k < maxPeriods; k++ {
+		days, periodStart := r.period(base, k)
+		if !r.Until.IsZero() && periodStart.After(civil(r.Until.In(start.Location()))) {
+			return
+		}
+		for _, d := range days {
+			t := combine(d, start)
+			if !t.After(start) {
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+// period 返回第 k 个周期内命中规则的日期（UTC 零点表示的日历日期，已排序并应用 BYSETPOS）以及该周期的第一天
+func (r *Rule) period(base time.Time, k int) ([]time.Time, time.Time) {
+	var days []time.Time
+	var first time.Time
+	switch r.Freq {
+	case "DAILY":
+		first = base.AddDate(0, 0, k*r.Interval)
+		if r.matchMonth(first) && r.matchMonthDay(first) && r.matchWeekday(first) {
+			days = append(days, first)
+		}
+	case "WEEKLY":
+		offset := (int(base.Weekday()) - int(r.WeekStart) + 7) % 7
+		first = base.AddDate(0, 0, -offset+7*k*r.Interval)
+		for i := range 7 {
+			d := first.AddDate(0, 0, i)
+			if len(r.ByDay) == 0 && d.Weekday() != base.Weekday() {
+				continue
+			}
+			if r.matchWeekday(d) && r.matchMonth(d) {
+				days = append(days, d)
+			}
+		}
+	case "MONTHLY":
+		first = time.Date(base.Year(), base.Month()+time.Month(k*r.Interval), 1, 0, 0, 0, 0, time.UTC)
+		if r.matchMonth(first) {
+			days = r.monthDays(first, base.Day())
+		}
+	case "YEARLY":
+		first = time.Date(base.Year()+k*r.Interval, time.January, 1, 0, 0, 0, 0, time.UTC)
+		days = r.yearDays(first, base)
+	}
+	return r.setPos(days), first
+}
+
+// monthDays 返回 month 所在月份中命中 BYMONTHDAY 与 BYDAY 的日期，都未设置时为 day 当天（该月没有这一天时跳过）
+func (r *Rule) monthDays(month time.Time, day int) []time.Time {
+	n := daysIn(month)
+	var byMonthDay, byDay []time.Time
+	for _, md := range r.ByMonthDay {
+		if md < 0 {
+			md = n + md + 1
+		}
+		if md >= 1 && md <= n {
+			byMonthDay = append(byMonthDay, month.AddDate(0, 0, md-1))
+		}
+	}
+	for _, wd := range r.ByDay {
+		byDay = append(byDay, nthWeekdays(month, n, wd)...)
+	}
+
+	var days []time.Time
+	switch {
+	case len(r.ByMonthDay) == 0 && len(r.ByDay) == 0:
+		if day <= n {
+			days = append(days, month.AddDate(0, 0, day-1))
+		}
+	case len(r.ByMonthDay) == 0:
+		days = byDay
+	case len(r.ByDay) == 0:
+		days = byMonthDay
+	default:
+		for _, d := range byMonthDay {
+			if slices.ContainsFunc(byDay, d.Equal) {
+				days = append(days, d)
+			}
+		}
+	}
+	return sortDays(days)
+}
+
+// yearDays 返回 year 所在年份中命中规则的日期。未设置 BYMONTH 时：只有 BYDAY 的按全年计算第 N 个星期几，
+// 有 BYMONTHDAY 的作用于每个月，都未设置时为 base 的月与日（如 2 月 29 日只在闰年发生）
+func (r *Rule) yearDays(year, base time.Time) []time.Time {
+	months := r.ByMonth
+	if len(months) == 0 {
+		switch {
+		case len(r.ByDay) > 0 && len(r.ByMonthDay) == 0:
+			var days []time.Time
+			for _, wd := range r.ByDay {
+				days = append(days, nthWeekdays(year, daysInYear(year), wd)...)
+			}
+			return sortDays(days)
+		case len(r.ByMonthDay) > 0:
+			for m := time.January; m <= time.December; m++ {
+				months = append(months, m)
+			}
+		default:
+			months = []time.Month{base.Month()}
+		}
+	}
+
+	var days []time.Time
+	for _, m := range months {
+		days = append(days, r.monthDays(time.Date(year.Year(), m, 1, 0, 0, 0, 0, time.UTC), base.Day())...)
+	}
+	return sortDays(days)
+}
+
+// nthWeekdays 返回从 first 开始的 n 天内第 wd.N 个 wd.Day，N 为 0 时返回全部
+func nthWeekdays(first time.Time, n int, wd WeekdayNum) []time.Time {
+	var all []time.Time
+	for d := first.AddDate(0, 0, (int(wd.Day)-int(first.Weekday())+7)%7); d.Before(first.AddDate(0, 0, n)); d = d.AddDate(0, 0, 7) {
+		all = append(all, d)
+	}
+	switch {
+	case wd.N == 0:
+		return all
+	case wd.N > 0 && wd.N <= len(all):
+		return all[wd.N-1 : wd.N]
+	case wd.N < 0 && -wd.N <= len(all):
+		return all[len(all)+wd.N : len(all)+wd.N+1]
+	}
+	return nil
+}
+
+// setPos 按 BYSETPOS 从周期内的候选日期中选取，未设置时原样返回
+func (r *Rule) setPos(days []time.Time) []time.Time {
+	if len(r.BySetPos) == 0 || len(days) == 0 {
+		return days
+	}
+	var out []time.Time
+	for _, pos := range r.BySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(days) + pos
+		}
+		if i >= 0 && i < len(days) {
+			out = append(out, days[i])
+		}
+	}
+	return sortDays(out)
+}
+
+func (r *Rule) matchMonth(d time.Time) bool {
+	return len(r.ByMonth) == 0 || slices.Contains(r.ByMonth, d.Month())
+}
+
+func (r *Rule) matchMonthDay(d time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	n := daysIn(d)
+	for _, md := range r.ByMonthDay {
+		if md == d.Day() || (md < 0 && n+md+1 == d.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWeekday 只比较星期几，DAILY 与 WEEKLY 中的 BYDAY 不带序号
+func (r *Rule) matchWeekday(d time.Time) bool {
+	return len(r.ByDay) == 0 || slices.ContainsFunc(r.ByDay, func(wd WeekdayNum) bool { return wd.Day == d.Weekday() })
+}
+
+// civil 返回 t 在其时区中的日历日期，以 UTC 零点表示，便于按天计算而不受夏令时影响
+func civil(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// combine 将日历日期与 start 在其时区中的钟面时间组合
+func combine(d, start time.Time) time.Time {
+	return time.Date(d.Year(), d.Month(), d.Day(), start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+}
+
+func daysIn(month time.Time) int {
+	return time.Date(month.Year(), month.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func daysInYear(year time.Time) int {
+	return time.Date(year.Year(), time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+}
+
+func sortDays(days []time.Time) []time.Time {
+	slices.SortFunc(days, func(a, b time.Time) int { return a.Compare(b) })
+	return slices.CompactFunc(days, func(a, b time.Time) bool { return a.Equal(b) })
+}
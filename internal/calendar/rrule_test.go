@@ -0,0 +1,124 @@
+package calendar
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func loadFixture(t *testing.T, name string, loc *time.Location) map[string][]Event {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	events, err := Parse(f, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byUID := make(map[string][]Event)
+	for _, ev := range events {
+		byUID[ev.UID] = append(byUID[ev.UID], ev)
+	}
+	return byUID
+}
+
+func TestOccurrences(t *testing.T) {
+	newYork, _ := time.LoadLocation("America/New_York")
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	events := loadFixture(t, "rrule.ics", time.UTC)
+	at := func(loc *time.Location, y int, m time.Month, d, h, min int) time.Time {
+		return time.Date(y, m, d, h, min, 0, 0, loc)
+	}
+	utc := func(y int, m time.Month, d, h int) time.Time { return at(time.UTC, y, m, d, h, 0) }
+
+	cases := []struct {
+		uid  string
+		want []time.Time
+	}{
+		// 没有 31 日的月份被跳过，而不是顺延到下个月
+		{"month-31", []time.Time{utc(2026, 1, 31, 10), utc(2026, 3, 31, 10), utc(2026, 5, 31, 10), utc(2026, 7, 31, 10)}},
+		// 2 月 29 日只在闰年发生
+		{"leap-day", []time.Time{utc(2024, 2, 29, 9), utc(2028, 2, 29, 9), utc(2032, 2, 29, 9)}},
+		{"last-friday", []time.Time{utc(2026, 1, 30, 16), utc(2026, 2, 27, 16), utc(2026, 3, 27, 16), utc(2026, 4, 24, 16)}},
+		// RFC 5545 中 WKST 影响结果的示例
+		{"wkst-mo", []time.Time{utc(1997, 8, 5, 9), utc(1997, 8, 10, 9), utc(1997, 8, 19, 9), utc(1997, 8, 24, 9)}},
+		{"wkst-su", []time.Time{utc(1997, 8, 5, 9), utc(1997, 8, 17, 9), utc(1997, 8, 19, 9), utc(1997, 8, 31, 9)}},
+		// 日期形式的 UNTIL 包含当天
+		{"until-date", []time.Time{utc(2026, 3, 2, 17), utc(2026, 3, 3, 17), utc(2026, 3, 4, 17), utc(2026, 3, 5, 17)}},
+		{"last-workday", []time.Time{utc(2026, 1, 30, 15), utc(2026, 2, 27, 15), utc(2026, 3, 31, 15)}},
+		// EXDATE 排除的发生仍计入 COUNT，RDATE 追加在 COUNT 之外
+		{"count-exdate", []time.Time{utc(2026, 6, 1, 8), utc(2026, 6, 4, 8), utc(2026, 6, 10, 8)}},
+		// 夏令时切换后仍在当地 9:00 开始
+		{"dst", []time.Time{at(newYork, 2026, 3, 6, 9, 0), at(newYork, 2026, 3, 7, 9, 0), at(newYork, 2026, 3, 8, 9, 0)}},
+		// UTC 形式的 UNTIL 恰好等于最后一次的开始时间
+		{"until-utc", []time.Time{at(shanghai, 2026, 1, 5, 10, 0), at(shanghai, 2026, 1, 12, 10, 0), at(shanghai, 2026, 1, 19, 10, 0)}},
+		{"yearly-thanksgiving", []time.Time{utc(2026, 11, 26, 12), utc(2027, 11, 25, 12), utc(2028, 11, 23, 12)}},
+		// 不支持的规则只保留第一次
+		{"unsupported", []time.Time{utc(2026, 3, 1, 10)}},
+	}
+	from, to := utc(1990, 1, 1, 0), utc(2040, 1, 1, 0)
+	for _, c := range cases {
+		list := events[c.uid]
+		if len(list) != 1 {
+			t.Errorf("%s: %d events in fixture, want 1", c.uid, len(list))
+			continue
+		}
+		got := list[0].Occurrences(from, to)
+		if len(got) != len(c.want) {
+			t.Errorf("%s: got %v, want %v", c.uid, got, c.want)
+			continue
+		}
+		for i := range got {
+			if !got[i].Equal(c.want[i]) {
+				t.Errorf("%s[%d] = %v, want %v", c.uid, i, got[i], c.want[i])
+			}
+		}
+	}
+
+	// 夏令时切换当天 9:00 对应的 UTC 时间提前一小时
+	dst := events["dst"][0].Occurrences(from, to)
+	if dst[1].UTC().Hour() != 14 || dst[2].UTC().Hour() != 13 {
+		t.Errorf("dst occurrences in UTC = %v, %v, want 14:00 and 13:00", dst[1].UTC(), dst[2].UTC())
+	}
+}
+
+func TestOccurrencesWindow(t *testing.T) {
+	events := loadFixture(t, "rrule.ics", time.UTC)
+	ev := events["until-date"][0]
+
+	// 1. 只返回与时间范围重叠的发生，包括范围开始时仍在进行的一次
+	got := ev.Occurrences(time.Date(2026, 3, 3, 17, 15, 0, 0, time.UTC), time.Date(2026, 3, 4, 17, 0, 0, 0, time.UTC))
+	if len(got) != 1 || !got[0].Equal(time.Date(2026, 3, 3, 17, 0, 0, 0, time.UTC)) {
+		t.Errorf("occurrences = %v, want only March 3", got)
+	}
+
+	// 2. 范围结束后停止展开，不限次数的规则也不会一直遍历
+	rule, err := ParseRule("FREQ=DAILY", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forever := Event{Start: time.Date(2000, 1, 1, 9, 0, 0, 0, time.UTC), End: time.Date(2000, 1, 1, 10, 0, 0, 0, time.UTC), Rule: rule}
+	got = forever.Occurrences(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC))
+	if len(got) != 2 {
+		t.Errorf("daily occurrences over two days = %v, want 2", got)
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule("FREQ=MONTHLY;INTERVAL=2;BYDAY=2TU,-1FR;BYMONTHDAY=-1;WKST=SU", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.Freq != "MONTHLY" || rule.Interval != 2 || rule.WeekStart != time.Sunday ||
+		len(rule.ByDay) != 2 || rule.ByDay[1] != (WeekdayNum{N: -1, Day: time.Friday}) || rule.ByMonthDay[0] != -1 {
+		t.Errorf("rule = %+v", rule)
+	}
+
+	for _, invalid := range []string{"", "INTERVAL=2", "FREQ=HOURLY", "FREQ=DAILY;INTERVAL=0", "FREQ=WEEKLY;BYDAY=XX", "FREQ=YEARLY;BYWEEKNO=20", "FREQ=MONTHLY;BYMONTHDAY=32"} {
+		if _, err := ParseRule(invalid, time.UTC); err == nil {
+			t.Errorf("ParseRule(%q) succeeded, want error", invalid)
+		}
+	}
+}
@@ -1,7 +1,13 @@
 package cli
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
 	"github.com/nhirsama/Naniwosuruno/internal/client"
+	"github.com/nhirsama/Naniwosuruno/internal/client/localctl"
 	"github.com/spf13/cobra"
 )
 
@@ -9,10 +15,126 @@ var clientCmd = &cobra.Command{
 	Use:   "client",
 	Short: "Start the client",
 	Run: func(cmd *cobra.Command, args []string) {
-		client.Run()
+		ctx, stop := signalContext()
+		defer stop()
+		client.Run(ctx)
+	},
+}
+
+var clientStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the connection state of the local client",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		res, err := localctl.Call(localctl.SocketPath(), localctl.Request{Command: localctl.CommandStatus})
+		if err == nil {
+			printLocalStatus(res.Status)
+			return nil
+		}
+		if !errors.Is(err, localctl.ErrNotRunning) {
+			return err
+		}
+
+		// 客户端未运行时显示它最后记录的连接状态
+		status, err := client.ReadStatus(client.StatusPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			fmt.Println("no client status recorded, is the client running?")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println("client is not running, last recorded state:")
+		printLocalStatus(&localctl.Status{
+			State:     string(status.State),
+			Server:    status.Server,
+			Since:     status.Since,
+			LastError: status.LastError,
+		})
+		if !status.NextAttempt.IsZero() {
+			fmt.Printf("retry:   %s\n", status.NextAttempt.Local().Format("2006-01-02 15:04:05"))
+		}
+		return nil
 	},
 }
 
+var clientPauseDuration time.Duration
+
+var clientPauseCmd = &cobra.Command{
+	Use:   "pause",
+	Short: "Pause reporting window titles from the running client",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return callClient(localctl.Request{Command: localctl.CommandPause, Duration: clientPauseDuration})
+	},
+}
+
+var clientResumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume reporting from the running client",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return callClient(localctl.Request{Command: localctl.CommandResume})
+	},
+}
+
+var clientReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Report the current window immediately",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return callClient(localctl.Request{Command: localctl.CommandReport})
+	},
+}
+
+// callClient 向正在运行的客户端发送命令并显示其最新状态
+func callClient(req localctl.Request) error {
+	res, err := localctl.Call(localctl.SocketPath(), req)
+	if err != nil {
+		return err
+	}
+	printLocalStatus(res.Status)
+	return nil
+}
+
+func printLocalStatus(status *localctl.Status) {
+	if status == nil {
+		return
+	}
+	const timeFormat = "2006-01-02 15:04:05"
+	state := status.State
+	if status.Auth != "" {
+		state += ", " + status.Auth
+	}
+	fmt.Printf("state:   %s (since %s, %s ago)\n", state,
+		status.Since.Local().Format(timeFormat), time.Since(status.Since).Round(time.Second))
+	fmt.Printf("server:  %s\n", status.Server)
+	if status.LastError != "" {
+		fmt.Printf("error:   %s\n", status.LastError)
+	}
+	switch {
+	case status.Mode == "":
+	case status.ModeUntil.IsZero():
+		fmt.Printf("mode:    %s (until resumed)\n", status.Mode)
+	default:
+		fmt.Printf("mode:    %s (until %s)\n", status.Mode, status.ModeUntil.Local().Format(timeFormat))
+	}
+	switch {
+	case status.Custom == "":
+	case status.CustomUntil.IsZero():
+		fmt.Printf("custom:  %s\n", status.Custom)
+	default:
+		fmt.Printf("custom:  %s (until %s)\n", status.Custom, status.CustomUntil.Local().Format(timeFormat))
+	}
+	if status.LastTitle != "" {
+		fmt.Printf("title:   %s (%s)\n", status.LastTitle, status.LastReport.Local().Format(timeFormat))
+	}
+	if status.Queue > 0 {
+		fmt.Printf("queued:  %d\n", status.Queue)
+	}
+}
+
 func init() {
+	clientPauseCmd.Flags().DurationVar(&clientPauseDuration, "for", 0, "resume automatically after this duration, e.g. 30m")
+	clientCmd.AddCommand(clientStatusCmd, clientPauseCmd, clientResumeCmd, clientReportCmd)
 	rootCmd.AddCommand(clientCmd)
 }
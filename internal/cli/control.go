@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1/naniwosurunov1connect"
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+	"github.com/spf13/cobra"
+)
+
+var controlCmd = &cobra.Command{
+	Use:   "control",
+	Short: "Pause, resume or mark clients as busy",
+	Long: "Pause, resume or mark clients as busy. Commands are sent to the server in the\n" +
+		"local config (BaseUrl) and authenticated with its AdminToken. Without a client\n" +
+		"argument every client the server has seen is affected.",
+}
+
+var controlDuration time.Duration
+
+var controlPauseCmd = &cobra.Command{
+	Use:   "pause [client]",
+	Short: "Stop a client from reporting window titles",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return sendControl(args, service.StatusPaused, controlDuration)
+	},
+}
+
+var controlBusyCmd = &cobra.Command{
+	Use:   "busy [client]",
+	Short: "Show a client as busy instead of its window title",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return sendControl(args, service.StatusBusy, controlDuration)
+	},
+}
+
+var controlResumeCmd = &cobra.Command{
+	Use:   "resume [client]",
+	Short: "Resume reporting",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return sendControl(args, "", 0)
+	},
+}
+
+func sendControl(args []string, mode string, duration time.Duration) error {
+	cfg := pkg.ReadConfig()
+	if cfg.AdminToken == "" {
+		return fmt.Errorf("配置中未设置 AdminToken")
+	}
+	baseURL := strings.TrimSuffix(cfg.BaseUrl, "/")
+	if baseURL == "" {
+		baseURL = "http://localhost:9975"
+	}
+
+	control := &naniwosurunov1.ClientControl{Mode: mode}
+	if duration > 0 {
+		control.Until = time.Now().Add(duration).UnixMilli()
+	}
+	req := connect.NewRequest(&naniwosurunov1.ControlClientRequest{Control: control})
+	if len(args) > 0 {
+		req.Msg.Client = args[0]
+	}
+	req.Header().Set("Authorization", "Bearer "+cfg.AdminToken)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client := naniwosurunov1connect.NewWindowServiceClient(http.DefaultClient, baseURL)
+	res, err := client.ControlClient(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	action := "resumed"
+	if mode != "" {
+		action = mode
+	}
+	for _, name := range res.Msg.Clients {
+		if duration > 0 {
+			fmt.Printf("%s: %s for %s\n", name, action, duration)
+		} else {
+			fmt.Printf("%s: %s\n", name, action)
+		}
+	}
+	return nil
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{controlPauseCmd, controlBusyCmd} {
+		cmd.Flags().DurationVar(&controlDuration, "for", 0, "resume automatically after this duration, e.g. 2h")
+		controlCmd.AddCommand(cmd)
+	}
+	controlCmd.AddCommand(controlResumeCmd)
+	rootCmd.AddCommand(controlCmd)
+}
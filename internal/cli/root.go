@@ -24,6 +24,10 @@ func Execute() {
 		// 动态更新子命令的描述
 		if clientCmd != nil {
 			clientCmd.Short = "启动客户端"
+			clientStatusCmd.Short = "显示本机客户端的连接状态"
+			clientPauseCmd.Short = "暂停正在运行的客户端上报窗口标题"
+			clientResumeCmd.Short = "恢复正在运行的客户端上报"
+			clientReportCmd.Short = "立即上报当前窗口"
 		}
 		if serverCmd != nil {
 			serverCmd.Short = "启动服务端"
@@ -31,6 +35,21 @@ func Execute() {
 		if startCmd != nil {
 			startCmd.Short = "同时启动客户端和服务端"
 		}
+		if controlCmd != nil {
+			controlCmd.Short = "暂停、恢复客户端的上报或将其显示为忙碌"
+			controlPauseCmd.Short = "暂停客户端上报窗口标题"
+			controlBusyCmd.Short = "将客户端显示为忙碌"
+			controlResumeCmd.Short = "恢复客户端上报"
+		}
+		if statusCmd != nil {
+			statusCmd.Short = "通过正在运行的客户端设置或清除手动状态"
+			statusSetCmd.Short = "设置与当前应用一同显示的手动状态，如 \"Lunch\""
+			statusClearCmd.Short = "清除手动状态"
+		}
+		if webhookCmd != nil {
+			webhookCmd.Short = "查看事件回调"
+			webhookLogCmd.Short = "显示最近的回调投递记录"
+		}
 	}
 
 	if err := rootCmd.Execute(); err != nil {
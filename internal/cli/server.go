@@ -1,6 +1,11 @@
 package cli
 
 import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"github.com/nhirsama/Naniwosuruno/internal/server"
 	"github.com/spf13/cobra"
 )
@@ -8,11 +13,29 @@ import (
 var serverCmd = &cobra.Command{
 	Use:   "server",
 	Short: "Start the server",
-	Run: func(cmd *cobra.Command, args []string) {
-		server.Run()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signalContext()
+		defer stop()
+		return server.Run(ctx, serverOptions)
 	},
 }
 
+// serverOptions 由 server 与 start 命令共用
+var serverOptions server.Options
+
+// addServerFlags 为启动服务端的命令注册参数
+func addServerFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&serverOptions.WebDir, "web-dir", "", "serve frontend assets from this directory instead of the embedded ones")
+	cmd.Flags().StringVar(&serverOptions.OverlayToken, "overlay-token", "", "require ?key=<token> to open the /overlay page")
+	cmd.Flags().StringVar(&serverOptions.RelayTo, "relay-to", "", "forward events to an upstream instance, e.g. https://vps.example.com")
+}
+
+// signalContext 返回在收到 Ctrl+C 或 SIGTERM 时结束的 context，用于优雅退出
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
 func init() {
+	addServerFlags(serverCmd)
 	rootCmd.AddCommand(serverCmd)
 }
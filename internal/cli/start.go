@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"context"
 	"time"
 
 	"github.com/nhirsama/Naniwosuruno/internal/client"
@@ -11,18 +12,34 @@ import (
 var startCmd = &cobra.Command{
 	Use:   "start",
 	Short: "Start both client and server",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signalContext()
+		defer stop()
+
+		// 服务端在客户端退出后才关闭，保证客户端的下线通知能够送达；服务端启动失败时客户端随之退出
+		serverCtx, stopServer := context.WithCancel(context.Background())
+		clientCtx, stopClient := context.WithCancel(ctx)
+		defer stopServer()
+		defer stopClient()
+
 		// 启动服务端 (非阻塞)
-		go server.Run()
+		serverErr := make(chan error, 1)
+		go func() {
+			serverErr <- server.Run(serverCtx, serverOptions)
+			stopClient()
+		}()
 
 		// 稍微等待服务端初始化
 		time.Sleep(500 * time.Millisecond)
 
 		// 启动客户端 (阻塞)
-		client.Run()
+		client.Run(clientCtx)
+		stopServer()
+		return <-serverErr
 	},
 }
 
 func init() {
+	addServerFlags(startCmd)
 	rootCmd.AddCommand(startCmd)
 }
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"strings"
+	"time"
+
+	"github.com/nhirsama/Naniwosuruno/internal/client/localctl"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Set or clear a custom status through the running client",
+}
+
+var (
+	statusDuration time.Duration
+	statusEmoji    string
+)
+
+var statusSetCmd = &cobra.Command{
+	Use:     "set <text>",
+	Short:   "Show a custom status such as \"Lunch\" alongside the detected app",
+	Example: `  naniwosuruno status set "Lunch" --for 45m --emoji 🍜`,
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return callClient(localctl.Request{
+			Command:  localctl.CommandSetStatus,
+			Text:     strings.Join(args, " "),
+			Emoji:    statusEmoji,
+			Duration: statusDuration,
+		})
+	},
+}
+
+var statusClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Clear the custom status",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return callClient(localctl.Request{Command: localctl.CommandSetStatus})
+	},
+}
+
+func init() {
+	statusSetCmd.Flags().DurationVar(&statusDuration, "for", 0, "clear automatically after this duration, e.g. 45m")
+	statusSetCmd.Flags().StringVar(&statusEmoji, "emoji", "", "emoji shown before the text")
+	statusCmd.AddCommand(statusSetCmd, statusClearCmd)
+	rootCmd.AddCommand(statusCmd)
+}
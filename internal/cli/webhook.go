@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/nhirsama/Naniwosuruno/internal/webhook"
+	"github.com/spf13/cobra"
+)
+
+var webhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "Inspect outgoing webhooks",
+}
+
+var webhookLogOptions struct {
+	limit int
+	name  string
+}
+
+var webhookLogCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Show recent webhook delivery attempts",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := webhook.ReadLog(webhook.LogPath(webhook.DefaultDir), webhookLogOptions.limit, webhookLogOptions.name)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			fmt.Println("no deliveries")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tWEBHOOK\tEVENT\tATTEMPT\tRESULT\tSTATUS\tERROR")
+		for _, e := range entries {
+			status := "-"
+			if e.StatusCode != 0 {
+				status = fmt.Sprint(e.StatusCode)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\t%s\n",
+				e.Time.Local().Format("2006-01-02 15:04:05"), e.Webhook, e.EventID, e.Attempt, e.Result, status, e.Error)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	webhookLogCmd.Flags().IntVarP(&webhookLogOptions.limit, "limit", "n", 20, "number of entries to show, 0 for all")
+	webhookLogCmd.Flags().StringVar(&webhookLogOptions.name, "name", "", "only show deliveries of this webhook")
+	webhookCmd.AddCommand(webhookLogCmd)
+	rootCmd.AddCommand(webhookCmd)
+}
@@ -1,18 +1,24 @@
 package client
 
 import (
+	"context"
 	"crypto/ed25519"
 	"encoding/base64"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"runtime"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/nhirsama/Naniwosuruno/internal/client/LinuxKDE"
 	clientWindows "github.com/nhirsama/Naniwosuruno/internal/client/Windows"
+	"github.com/nhirsama/Naniwosuruno/internal/client/discord"
 	"github.com/nhirsama/Naniwosuruno/internal/client/inter"
+	"github.com/nhirsama/Naniwosuruno/internal/client/localctl"
+	"github.com/nhirsama/Naniwosuruno/internal/client/outbox"
+	"github.com/nhirsama/Naniwosuruno/internal/client/privacy"
 	"github.com/nhirsama/Naniwosuruno/pkg"
 )
 
@@ -34,36 +40,63 @@ type Client struct {
 	handle          inter.GetWindowTitle
 	config          *pkg.AppConfig
 	connection      *ServerConnection
+	privacy         *privacy.Filter
+	discord         *discord.Presence // 未启用 Discord 时为 nil
+	outbox          *outbox.Outbox    // 服务端不可达时缓存的窗口变化
+	settings        Settings          // 服务端下发的设置，见 settings.go
+	mode            string            // 当前生效的控制模式，见 control.go
+	localPause      bool              // 通过本机控制套接字暂停，见 local.go
+	localPauseUntil time.Time         // 本机暂停到期的时间，零值表示直到恢复
+	commands        chan localCommand // 控制套接字收到的命令，由主循环处理
+	lastReported    outbox.Entry      // 最近一次送达服务端的窗口变化
+	custom          customState       // 最近一次设置的手动状态，见 custom.go
 	lastWindowTitle string
 	heartbeatCount  uint32
 }
 
-func Run() {
-	NewClient().Start()
+// goodbyeTimeout 为退出时等待服务端确认下线的最长时间
+const goodbyeTimeout = 3 * time.Second
+
+// Run 启动客户端并阻塞到 ctx 结束
+func Run(ctx context.Context) {
+	NewClient().Start(ctx)
 }
 
 func NewClient() *Client {
 	c := &Client{
-		os:      OSType(runtime.GOOS),
-		desktop: detectDesktop(),
-		config:  pkg.ReadConfig(),
+		os:       OSType(runtime.GOOS),
+		desktop:  detectDesktop(),
+		config:   pkg.ReadConfig(),
+		settings: DefaultSettings(),
+		commands: make(chan localCommand),
 	}
 
 	c.ensureKeys()
 
 	c.connection = NewServerConnection(c.config)
+	queue, err := outbox.Open(filepath.Join(pkg.DefaultDataDir, "outbox.json"), 0)
+	if err != nil {
+		log.Fatalf("初始化离线队列失败: %v", err)
+	}
+	c.outbox = queue
+	c.privacy = privacy.NewFilter(c.config.Privacy)
+	if c.config.Discord.Enabled {
+		c.discord = discord.New(c.config.Discord, c.privacy)
+	}
 
 	return c
 }
 
-func (c *Client) Start() {
+// Start 运行主循环直到 ctx 结束，退出前通知服务端下线
+func (c *Client) Start(ctx context.Context) {
 	c.initWindowHandle()
 	log.Printf("Client started on %s (%s)", c.os, c.desktop)
 
 	c.connection.Connect()
+	local := c.listenLocal(ctx)
 
-	windowTicker := time.NewTicker(5 * time.Second)
-	heartbeatTicker := time.NewTicker(120 * time.Second)
+	windowTicker := time.NewTicker(c.settings.PollInterval)
+	heartbeatTicker := time.NewTicker(c.settings.HeartbeatInterval)
 	defer windowTicker.Stop()
 	defer heartbeatTicker.Stop()
 
@@ -75,8 +108,28 @@ func (c *Client) Start() {
 
 	for {
 		select {
+		case <-ctx.Done():
+			c.shutdown(local)
+			return
+		case cmd := <-c.commands:
+			cmd.reply <- c.handleLocal(cmd.req)
 		case <-windowTicker.C:
-			c.checkAndUpdateWindowTitle()
+			// 重连后服务端可能已将本客户端判定为超时离线，立即补发一次心跳
+			if c.connection.Maintain() {
+				c.heartbeatCount++
+				if err := c.connection.SendHeartbeat(c.heartbeatCount); err != nil {
+					log.Printf("发送心跳失败: %v", err)
+				}
+			}
+			if settings, ok := c.connection.SyncSettings(); ok {
+				c.applySettings(settings)
+				windowTicker.Reset(settings.PollInterval)
+				heartbeatTicker.Reset(settings.HeartbeatInterval)
+			}
+			c.updateMode()
+			if c.mode == "" {
+				c.checkAndUpdateWindowTitle()
+			}
 		case <-heartbeatTicker.C:
 			c.heartbeatCount++
 			if err := c.connection.SendHeartbeat(c.heartbeatCount); err != nil {
@@ -86,6 +139,63 @@ func (c *Client) Start() {
 	}
 }
 
+// applySettings 应用服务端下发的设置，隐私规则对之后的上报与 Discord 立即生效
+func (c *Client) applySettings(settings Settings) {
+	c.settings = settings
+	c.privacy.SetRemote(settings.Privacy)
+	log.Printf("已应用服务端设置 (版本 %s): 轮询 %s，心跳 %s",
+		settings.Revision, settings.PollInterval, settings.HeartbeatInterval)
+}
+
+// currentMode 返回当前生效的控制模式：本机暂停优先，其次是服务端下发的控制
+func (c *Client) currentMode() (string, time.Time) {
+	if c.localPause && (c.localPauseUntil.IsZero() || time.Now().Before(c.localPauseUntil)) {
+		return ModePaused, c.localPauseUntil
+	}
+	if mode := c.connection.Mode(); mode != "" {
+		return mode, c.connection.Status().ModeUntil
+	}
+	return "", time.Time{}
+}
+
+// updateMode 在控制模式变化（包括到期）时切换
+func (c *Client) updateMode() {
+	if mode, _ := c.currentMode(); mode != c.mode {
+		c.switchMode(mode)
+	}
+}
+
+// switchMode 切换控制模式：暂停或忙碌期间不读取也不上报窗口标题，并清除 Discord 状态；
+// 恢复后立即上报当前窗口
+func (c *Client) switchMode(mode string) {
+	if mode == "" {
+		log.Println("已恢复上报窗口标题")
+		c.lastWindowTitle = ""
+	} else if c.mode == "" {
+		log.Printf("已暂停上报窗口标题 (%s)", mode)
+		if c.discord != nil {
+			c.discord.Close()
+		}
+	}
+	c.mode = mode
+}
+
+// shutdown 关闭控制套接字，通知服务端本客户端已下线，并释放 Discord 连接
+func (c *Client) shutdown(local *localctl.Server) {
+	log.Println("正在退出客户端...")
+	if local != nil {
+		_ = local.Close()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), goodbyeTimeout)
+	defer cancel()
+	if err := c.connection.Goodbye(ctx); err != nil {
+		log.Printf("通知服务端下线失败: %v", err)
+	}
+	if c.discord != nil {
+		c.discord.Close()
+	}
+}
+
 // --- Initialization Helpers ---
 
 func detectDesktop() DesktopType {
@@ -168,19 +278,80 @@ func (c *Client) checkAndUpdateWindowTitle() {
 		return
 	}
 
-	if c.lastWindowTitle != title {
-		log.Printf("标题变更: %s", title)
+	if c.lastWindowTitle == title {
+		// 标题未变化时继续尝试补报离线期间缓存的变化
+		if c.outbox.Len() > 0 {
+			if err := c.flushOutbox(); err == nil {
+				log.Println("已补报离线期间的窗口变化")
+			}
+		}
+		return
+	}
+
+	log.Printf("标题变更: %s", title)
+	c.lastWindowTitle = title
+
+	if c.discord != nil {
+		c.discord.Update(title, string(c.os))
+	}
+
+	entry := outbox.Entry{
+		Title:      c.privacy.Apply(title),
+		OS:         string(c.os),
+		ObservedAt: time.Now().UnixMilli(),
+	}
+	// 服务端不允许上报的字段：标题以占位符代替，系统留空
+	if !c.settings.Allows("title") {
+		entry.Title = c.privacy.Placeholder()
+	}
+	if !c.settings.Allows("os") {
+		entry.OS = ""
+	}
 
-		payload := &UpdatePayload{
-			Title: title,
-			OS:    c.os,
+	// 没有积压时直接上报，失败后才写入离线队列；有积压时排在队尾，保证补报顺序
+	backlog := c.outbox.Len() > 0
+	if !backlog {
+		err := c.connection.SendUpdate(&UpdatePayload{Title: entry.Title, OS: OSType(entry.OS), ObservedAt: entry.ObservedAt})
+		if err == nil {
+			c.reported(entry)
+			return
 		}
+		log.Printf("发送更新失败: %v", err)
+	}
 
-		if err := c.connection.SendUpdate(payload); err != nil {
-			log.Printf("发送更新失败: %v", err)
+	if err := c.outbox.Push(entry); err != nil {
+		log.Printf("写入离线队列失败: %v", err)
+		return
+	}
+	if backlog {
+		if err := c.flushOutbox(); err == nil {
+			log.Println("已补报离线期间的窗口变化")
 			return
 		}
+	}
+	log.Printf("服务端不可达，已缓存 %d 条窗口变化", c.outbox.Len())
+}
+
+// flushOutbox 先通过 ReportWindows 补报较早的变化（只写入历史），再以实时上报发送最新的一条
+func (c *Client) flushOutbox() error {
+	entries := c.outbox.Entries()
+	if len(entries) == 0 {
+		return nil
+	}
+
+	if older := entries[:len(entries)-1]; len(older) > 0 {
+		if err := c.connection.SendBacklog(older); err != nil {
+			return err
+		}
+		if err := c.outbox.Remove(len(older)); err != nil {
+			return err
+		}
+	}
 
-		c.lastWindowTitle = title
+	latest := entries[len(entries)-1]
+	if err := c.connection.SendUpdate(&UpdatePayload{Title: latest.Title, OS: OSType(latest.OS), ObservedAt: latest.ObservedAt}); err != nil {
+		return err
 	}
+	c.reported(latest)
+	return c.outbox.Remove(1)
 }
@@ -7,11 +7,14 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"connectrpc.com/connect"
+	"github.com/google/uuid"
 	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
 	"github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1/naniwosurunov1connect"
+	"github.com/nhirsama/Naniwosuruno/internal/client/outbox"
 	"github.com/nhirsama/Naniwosuruno/pkg"
 	"github.com/nhirsama/Naniwosuruno/pkg/auth"
 )
@@ -20,33 +23,60 @@ type ServerConnection struct {
 	httpClient    *http.Client
 	baseURL       string
 	clientID      string
-	token         string
+	token         string // v1 会话令牌
+	staticToken   string // v0 静态 Token
+	epoch         string // 本进程的心跳纪元，服务端据此识别客户端重启
 	authenticator auth.ClientAuthenticator
 	useV1         bool
 
+	// 连接状态，见 state.go
+	status     ConnectionStatus
+	statusPath string
+	backoff    time.Duration
+	retryBase  time.Duration
+	networkKey string
+	mu         sync.Mutex
+
 	// RPC Clients
 	authClient   naniwosurunov1connect.AuthServiceClient
 	windowClient naniwosurunov1connect.WindowServiceClient
+	streamClient naniwosurunov1connect.WindowServiceClient // 用于 Presence 双向流，见 presence.go
+
+	presence      *presenceStream // 未建立 Presence 流时为 nil
+	presenceRetry time.Time       // Presence 流不可用时，下一次尝试建立的时间
+
+	// 服务端下发的设置，见 settings.go
+	latestRevision  string // 最近一次心跳或 Presence 确认中的设置版本，由 mu 保护
+	appliedRevision string
+	settingsSynced  bool
 }
 
 type UpdatePayload struct {
-	Title string `json:"title"`
-	OS    OSType `json:"os"`
+	Title      string `json:"title"`
+	OS         OSType `json:"os"`
+	ObservedAt int64  `json:"observed_at,omitempty"` // 观测时间 (Unix 毫秒)，仅 API v1 使用
 }
 
 func NewServerConnection(cfg *pkg.AppConfig) *ServerConnection {
 	sc := &ServerConnection{
-		httpClient: &http.Client{Timeout: 10 * time.Second},
-		clientID:   cfg.ClientID,
-		token:      cfg.Token,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		clientID:    cfg.ClientID,
+		staticToken: cfg.Token,
+		epoch:       uuid.New().String(),
+		statusPath:  StatusPath,
+		backoff:     minBackoff,
+		retryBase:   minBackoff,
+		networkKey:  networkKey(),
 	}
 
 	sc.configureBaseURL(cfg.BaseUrl)
+	sc.status = ConnectionStatus{State: StateDisconnected, Server: sc.baseURL, Since: time.Now()}
 	sc.initAuthenticator(cfg.PrivateKey)
 
 	// Initialize RPC Clients
 	sc.authClient = naniwosurunov1connect.NewAuthServiceClient(sc.httpClient, sc.baseURL)
 	sc.windowClient = naniwosurunov1connect.NewWindowServiceClient(sc.httpClient, sc.baseURL)
+	sc.streamClient = naniwosurunov1connect.NewWindowServiceClient(newStreamingClient(sc.baseURL), sc.baseURL)
 
 	return sc
 }
@@ -70,19 +100,33 @@ func (s *ServerConnection) initAuthenticator(privateKey string) {
 	s.authenticator = authenticator
 }
 
+// Connect 进行 v1 握手：成功时进入 authenticated；服务端不可达时进入 disconnected 并按退避重连；
+// 服务端拒绝时降级到 v0，之后定期重新协商
 func (s *ServerConnection) Connect() {
 	if s.authenticator == nil || s.clientID == "" {
 		log.Println("跳过握手，使用 API v0 (Static Token)")
 		s.useV1 = false
+		s.setState(StateDegraded, nil, time.Time{})
 		return
 	}
 
-	if err := s.authenticateV1(); err != nil {
-		log.Printf("认证失败: %v, 回退到 API v0", err)
-		s.useV1 = false
-	} else {
+	s.closePresence()
+	s.presenceRetry = time.Time{}
+	s.setState(StateConnecting, nil, time.Time{})
+	err := s.authenticateV1()
+	switch {
+	case err == nil:
 		log.Println("认证成功，使用 API v1")
 		s.useV1 = true
+		s.backoff = s.retryBase
+		s.setState(StateAuthenticated, nil, time.Time{})
+	case isUnreachable(err):
+		s.markDisconnected(err)
+	default:
+		log.Printf("认证失败: %v, 回退到 API v0", err)
+		s.useV1 = false
+		s.backoff = s.retryBase
+		s.setState(StateDegraded, err, time.Now().Add(renegotiateInterval))
 	}
 }
 
@@ -122,42 +166,88 @@ func (s *ServerConnection) authenticateV1() error {
 }
 
 func (s *ServerConnection) SendUpdate(payload *UpdatePayload) error {
+	if err := s.ready(); err != nil {
+		return err
+	}
 	if !s.useV1 {
-		return s.sendUpdateV0(payload)
+		return s.observe(s.sendUpdateV0(payload))
+	}
+	if s.streaming() {
+		err := s.presence.send(&naniwosurunov1.PresenceUpdate{Window: &naniwosurunov1.ReportWindowRequest{
+			Title:      payload.Title,
+			Os:         string(payload.OS),
+			ObservedAt: payload.ObservedAt,
+		}})
+		if err == nil {
+			return nil
+		}
+		// 流已断开，本次改用普通上报，下一轮 Maintain 会尝试重建
+		log.Printf("通过 Presence 流发送失败: %v", err)
+		s.presence.cancel()
 	}
 
-	return s.sendUpdateV1(payload)
+	return s.observe(s.sendUpdateV1(payload))
 }
 
 func (s *ServerConnection) SendHeartbeat(count uint32) error {
-	if !s.useV1 {
+	if err := s.ready(); err != nil {
+		return err
+	}
+	// Presence 流自带保活，只在轮询模式下发送心跳
+	if !s.useV1 || s.streaming() {
 		return nil
 	}
 
 	ctx := context.Background()
 	req := connect.NewRequest(&naniwosurunov1.HeartbeatRequest{
 		Count: count,
+		Epoch: s.epoch,
 	})
 	req.Header().Set("Authorization", "Bearer "+s.token)
 
-	_, err := s.windowClient.Heartbeat(ctx, req)
+	res, err := s.windowClient.Heartbeat(ctx, req)
 	if err != nil {
 		if connect.CodeOf(err) == connect.CodeUnauthenticated {
 			if reAuthErr := s.authenticateV1(); reAuthErr != nil {
 				return fmt.Errorf("heartbeat re-auth failed: %w", reAuthErr)
 			}
 			req.Header().Set("Authorization", "Bearer "+s.token)
-			_, err = s.windowClient.Heartbeat(ctx, req)
+			res, err = s.windowClient.Heartbeat(ctx, req)
 		}
 	}
+	if err == nil {
+		s.observeRevision(res.Msg.ConfigRevision)
+		s.observeControl(res.Msg.Control)
+	}
+	return s.observe(err)
+}
+
+// Goodbye 在客户端正常退出时通知服务端立即将本客户端标记为离线，并关闭 Presence 流
+func (s *ServerConnection) Goodbye(ctx context.Context) error {
+	defer s.closePresence()
+	if !s.useV1 || s.ready() != nil {
+		return nil
+	}
+
+	req := connect.NewRequest(&naniwosurunov1.GoodbyeRequest{Epoch: s.epoch})
+	req.Header().Set("Authorization", "Bearer "+s.token)
+	_, err := s.windowClient.Goodbye(ctx, req)
+	if connect.CodeOf(err) == connect.CodeUnauthenticated {
+		if reAuthErr := s.authenticateV1(); reAuthErr != nil {
+			return fmt.Errorf("goodbye re-auth failed: %w", reAuthErr)
+		}
+		req.Header().Set("Authorization", "Bearer "+s.token)
+		_, err = s.windowClient.Goodbye(ctx, req)
+	}
 	return err
 }
 
 func (s *ServerConnection) sendUpdateV1(payload *UpdatePayload) error {
 	ctx := context.Background()
 	req := connect.NewRequest(&naniwosurunov1.ReportWindowRequest{
-		Title: payload.Title,
-		Os:    string(payload.OS),
+		Title:      payload.Title,
+		Os:         string(payload.OS),
+		ObservedAt: payload.ObservedAt,
 	})
 	req.Header().Set("Authorization", "Bearer "+s.token)
 
@@ -179,6 +269,40 @@ func (s *ServerConnection) sendUpdateV1(payload *UpdatePayload) error {
 	return err
 }
 
+// SendBacklog 补报离线期间缓存的窗口变化；API v0 不支持补报，这些变化会被丢弃
+func (s *ServerConnection) SendBacklog(entries []outbox.Entry) error {
+	if err := s.ready(); err != nil {
+		return err
+	}
+	if !s.useV1 {
+		log.Printf("API v0 不支持补报，丢弃 %d 条离线期间的窗口变化", len(entries))
+		return nil
+	}
+
+	windows := make([]*naniwosurunov1.ReportWindowRequest, 0, len(entries))
+	for _, e := range entries {
+		windows = append(windows, &naniwosurunov1.ReportWindowRequest{
+			Title:      e.Title,
+			Os:         e.OS,
+			ObservedAt: e.ObservedAt,
+		})
+	}
+
+	ctx := context.Background()
+	req := connect.NewRequest(&naniwosurunov1.ReportWindowsRequest{Windows: windows})
+	req.Header().Set("Authorization", "Bearer "+s.token)
+
+	_, err := s.windowClient.ReportWindows(ctx, req)
+	if connect.CodeOf(err) == connect.CodeUnauthenticated {
+		if reAuthErr := s.authenticateV1(); reAuthErr != nil {
+			return fmt.Errorf("重新认证失败: %w", reAuthErr)
+		}
+		req.Header().Set("Authorization", "Bearer "+s.token)
+		_, err = s.windowClient.ReportWindows(ctx, req)
+	}
+	return s.observe(err)
+}
+
 func (s *ServerConnection) sendUpdateV0(payload *UpdatePayload) error {
 	payloadBytes, _ := json.Marshal(payload)
 	path := "/api/v0/update"
@@ -188,11 +312,11 @@ func (s *ServerConnection) sendUpdateV0(payload *UpdatePayload) error {
 		return fmt.Errorf("创建请求失败: %v", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.AddCookie(&http.Cookie{Name: "token", Value: s.token})
+	req.AddCookie(&http.Cookie{Name: "token", Value: s.staticToken})
 
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("发送失败: %v", err)
+		return fmt.Errorf("发送失败: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"connectrpc.com/connect"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+)
+
+const (
+	ModePaused = "paused" // 暂停上报窗口标题
+	ModeBusy   = "busy"   // 服务端显示为忙碌，同样不上报窗口标题
+)
+
+// errNotV1 表示当前未使用 API v1，服务端无法得知本机的暂停
+var errNotV1 = errors.New("not connected with API v1")
+
+// observeControl 记录心跳或 Presence 确认中携带的控制，变化时写入日志与状态文件
+func (s *ServerConnection) observeControl(control *naniwosurunov1.ClientControl) {
+	var mode string
+	var until time.Time
+	if control != nil {
+		mode = control.Mode
+		if control.Until > 0 {
+			until = time.UnixMilli(control.Until)
+		}
+	}
+
+	s.mu.Lock()
+	if s.status.Mode == mode && s.status.ModeUntil.Equal(until) {
+		s.mu.Unlock()
+		return
+	}
+	s.status.Mode = mode
+	s.status.ModeUntil = until
+	status := s.status
+	s.mu.Unlock()
+
+	switch {
+	case mode == "":
+		log.Println("服务端已恢复上报")
+	case until.IsZero():
+		log.Printf("服务端要求 %s，直到手动恢复", mode)
+	default:
+		log.Printf("服务端要求 %s，直到 %s", mode, until.Local().Format("2006-01-02 15:04:05"))
+	}
+	s.saveStatus(status)
+}
+
+// Mode 返回当前生效的控制模式，未设置或已到期时返回空字符串
+func (s *ServerConnection) Mode() string {
+	status := s.Status()
+	if status.Mode == "" || (!status.ModeUntil.IsZero() && !time.Now().Before(status.ModeUntil)) {
+		return ""
+	}
+	return status.Mode
+}
+
+// SetControl 以本客户端的会话令牌设置自身的控制模式，mode 为空表示恢复；成功后立即生效，无需等待下一次确认
+func (s *ServerConnection) SetControl(mode string, until time.Time) error {
+	if err := s.ready(); err != nil {
+		return err
+	}
+	if !s.useV1 {
+		return errNotV1
+	}
+
+	control := &naniwosurunov1.ClientControl{Mode: mode}
+	if !until.IsZero() {
+		control.Until = until.UnixMilli()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req := connect.NewRequest(&naniwosurunov1.ControlClientRequest{Client: s.clientID, Control: control})
+	req.Header().Set("Authorization", "Bearer "+s.token)
+	_, err := s.windowClient.ControlClient(ctx, req)
+	if connect.CodeOf(err) == connect.CodeUnauthenticated {
+		if reAuthErr := s.authenticateV1(); reAuthErr != nil {
+			return fmt.Errorf("control re-auth failed: %w", reAuthErr)
+		}
+		req.Header().Set("Authorization", "Bearer "+s.token)
+		_, err = s.windowClient.ControlClient(ctx, req)
+	}
+	if err != nil {
+		return s.observe(err)
+	}
+	if mode == "" {
+		control = nil
+	}
+	s.observeControl(control)
+	return nil
+}
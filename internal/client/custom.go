@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"connectrpc.com/connect"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+)
+
+// customState 是通过本客户端最近一次设置的手动状态，仅用于本机状态查询
+type customState struct {
+	Text  string
+	Emoji string
+	Until time.Time // 零值表示直到清除
+}
+
+func (c customState) active(now time.Time) bool {
+	return c.Text != "" && (c.Until.IsZero() || now.Before(c.Until))
+}
+
+// String 返回如 "🍜 Lunch" 的文字
+func (c customState) String() string {
+	if c.Emoji == "" {
+		return c.Text
+	}
+	return c.Emoji + " " + c.Text
+}
+
+// SetCustomStatus 设置本客户端的手动状态，text 为空表示清除，until 为零值表示直到清除
+func (s *ServerConnection) SetCustomStatus(text, emoji string, until time.Time) error {
+	if err := s.ready(); err != nil {
+		return err
+	}
+	if !s.useV1 {
+		return errNotV1
+	}
+
+	status := &naniwosurunov1.CustomStatus{Text: text, Emoji: emoji}
+	if !until.IsZero() {
+		status.Until = until.UnixMilli()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req := connect.NewRequest(&naniwosurunov1.SetCustomStatusRequest{Status: status})
+	req.Header().Set("Authorization", "Bearer "+s.token)
+	_, err := s.windowClient.SetCustomStatus(ctx, req)
+	if connect.CodeOf(err) == connect.CodeUnauthenticated {
+		if reAuthErr := s.authenticateV1(); reAuthErr != nil {
+			return fmt.Errorf("custom status re-auth failed: %w", reAuthErr)
+		}
+		req.Header().Set("Authorization", "Bearer "+s.token)
+		_, err = s.windowClient.SetCustomStatus(ctx, req)
+	}
+	if err != nil {
+		return s.observe(err)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+//go:build !windows
+
+package discord
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// dial 依次尝试 Discord 可能监听的 Unix Socket：$XDG_RUNTIME_DIR 等临时目录下的 discord-ipc-0 至 discord-ipc-9，
+// 以及 Flatpak、Snap 版本的子目录
+func dial() (io.ReadWriteCloser, error) {
+	var dirs []string
+	for _, env := range []string{"XDG_RUNTIME_DIR", "TMPDIR", "TMP", "TEMP"} {
+		if dir := os.Getenv(env); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	dirs = append(dirs, "/tmp")
+
+	for _, dir := range dirs {
+		for _, sub := range []string{"", "app/com.discordapp.Discord", "snap.discord"} {
+			for i := 0; i < 10; i++ {
+				path := filepath.Join(dir, sub, fmt.Sprintf("discord-ipc-%d", i))
+				if c, err := net.DialTimeout("unix", path, time.Second); err == nil {
+					return c, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("discord ipc socket not found")
+}
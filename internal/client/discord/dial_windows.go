@@ -0,0 +1,19 @@
+//go:build windows
+
+package discord
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// dial 打开 Discord 在 Windows 下监听的命名管道 \\.\pipe\discord-ipc-0 至 discord-ipc-9
+func dial() (io.ReadWriteCloser, error) {
+	for i := 0; i < 10; i++ {
+		if f, err := os.OpenFile(fmt.Sprintf(`\\.\pipe\discord-ipc-%d`, i), os.O_RDWR, 0); err == nil {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("discord ipc pipe not found")
+}
@@ -0,0 +1,140 @@
+package discord
+
+import (
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/nhirsama/Naniwosuruno/internal/client/privacy"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+const (
+	// maxFieldLength 是 Discord 对 details 等字段的长度限制
+	maxFieldLength = 128
+	// requestTimeout 为一次 IPC 请求（握手或设置活动）的超时，Discord 卡住时不阻塞客户端主循环
+	requestTimeout = 2 * time.Second
+)
+
+// Presence 将客户端的当前窗口同步为 Discord Rich Presence。
+// Discord 未运行时静默跳过，在下一次焦点变化时重新连接。
+type Presence struct {
+	cfg     pkg.DiscordConfig
+	filter  *privacy.Filter
+	apps    []*regexp.Regexp
+	dial    func() (io.ReadWriteCloser, error)
+	pid     int
+	timeout time.Duration // 一次 IPC 请求的超时，测试中可调小
+
+	conn      *conn
+	connected bool   // 用于只在连接状态变化时打印日志
+	title     string // 最近一次的原始标题，变化时重置计时起点
+	since     time.Time
+}
+
+// New 创建 Presence，标题在展示前经过与上报服务端相同的隐私过滤
+func New(cfg pkg.DiscordConfig, filter *privacy.Filter) *Presence {
+	p := &Presence{
+		cfg:     cfg,
+		filter:  filter,
+		dial:    dial,
+		pid:     os.Getpid(),
+		timeout: requestTimeout,
+	}
+	for _, expr := range cfg.Apps {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			log.Printf("Discord 应用规则无效 %q: %v", expr, err)
+			continue
+		}
+		p.apps = append(p.apps, re)
+	}
+	return p
+}
+
+// Update 在焦点变化时调用：允许展示的窗口以变化时刻为起点显示已用时间，其余窗口清除 Presence
+func (p *Presence) Update(title, osName string) {
+	if title != p.title {
+		p.title = title
+		p.since = time.Now()
+	}
+
+	var activity *Activity
+	if p.allowed(title) {
+		activity = &Activity{
+			Details:    truncate(p.filter.Apply(title)),
+			State:      "on " + osName,
+			Timestamps: &Timestamps{Start: p.since.Unix()},
+		}
+		if p.cfg.LargeImage != "" {
+			activity.Assets = &Assets{LargeImage: p.cfg.LargeImage, LargeText: "Naniwosuruno"}
+		}
+	} else if p.conn == nil {
+		// 没有连接时也就没有需要清除的 Presence
+		return
+	}
+
+	_ = p.send(activity)
+}
+
+// allowed 判断标题是否在应用白名单中且未被隐私规则整体隐藏
+func (p *Presence) allowed(title string) bool {
+	if title == "" || p.filter.Hidden(title) {
+		return false
+	}
+	for _, re := range p.apps {
+		if re.MatchString(title) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Presence) send(activity *Activity) error {
+	if p.conn == nil {
+		rwc, err := p.dial()
+		if err == nil {
+			p.conn, err = handshake(rwc, p.cfg.ApplicationID, p.timeout)
+			if err != nil {
+				rwc.Close()
+			}
+		}
+		if err != nil {
+			if p.connected {
+				log.Printf("Discord 连接断开: %v", err)
+			}
+			p.connected = false
+			return err
+		}
+		log.Println("已连接 Discord")
+		p.connected = true
+	}
+
+	if err := p.conn.setActivity(p.pid, activity); err != nil {
+		log.Printf("设置 Discord 状态失败: %v", err)
+		p.conn.rwc.Close()
+		p.conn = nil
+		return err
+	}
+	return nil
+}
+
+// Close 清除 Presence 并断开连接
+func (p *Presence) Close() {
+	if p.conn == nil {
+		return
+	}
+	_ = p.conn.setActivity(p.pid, nil)
+	_ = p.conn.Close()
+	p.conn = nil
+}
+
+func truncate(s string) string {
+	runes := []rune(s)
+	if len(runes) <= maxFieldLength {
+		return s
+	}
+	return string(runes[:maxFieldLength-1]) + "…"
+}
@@ -0,0 +1,201 @@
+//go:build !windows
+
+package discord
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nhirsama/Naniwosuruno/internal/client/privacy"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+type setActivityArgs struct {
+	PID      int       `json:"pid"`
+	Activity *Activity `json:"activity"`
+}
+
+// fakeDiscord 在 $XDG_RUNTIME_DIR/discord-ipc-0 上模拟 Discord 客户端的 IPC 服务
+type fakeDiscord struct {
+	handshakes chan map[string]interface{}
+	activities chan *setActivityArgs
+}
+
+func newFakeDiscord(t *testing.T) *fakeDiscord {
+	dir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+	ln, err := net.Listen("unix", filepath.Join(dir, "discord-ipc-0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	fd := &fakeDiscord{
+		handshakes: make(chan map[string]interface{}, 4),
+		activities: make(chan *setActivityArgs, 16),
+	}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go fd.serve(t, c)
+		}
+	}()
+	return fd
+}
+
+func (fd *fakeDiscord) serve(t *testing.T, c net.Conn) {
+	defer c.Close()
+	for {
+		op, data, err := readFrame(c)
+		if err != nil {
+			return
+		}
+		switch op {
+		case opHandshake:
+			var hs map[string]interface{}
+			_ = json.Unmarshal(data, &hs)
+			fd.handshakes <- hs
+			_ = writeFrame(c, opFrame, message{Cmd: "DISPATCH", Evt: "READY"})
+			// 握手后发送一次 PING，客户端应回复 PONG 而不影响后续请求
+			_ = writeFrameBytes(c, opPing, []byte(`{}`))
+		case opPong:
+		case opFrame:
+			var msg message
+			_ = json.Unmarshal(data, &msg)
+			if msg.Cmd != "SET_ACTIVITY" {
+				t.Errorf("unexpected command %q", msg.Cmd)
+				continue
+			}
+			args := &setActivityArgs{}
+			_ = json.Unmarshal(msg.Args, args)
+			fd.activities <- args
+			_ = writeFrame(c, opFrame, message{Cmd: "SET_ACTIVITY", Nonce: msg.Nonce, Data: msg.Args})
+		case opClose:
+			return
+		}
+	}
+}
+
+func (fd *fakeDiscord) nextActivity(t *testing.T) *setActivityArgs {
+	t.Helper()
+	select {
+	case args := <-fd.activities:
+		return args
+	case <-time.After(3 * time.Second):
+		t.Fatal("no SET_ACTIVITY received")
+		return nil
+	}
+}
+
+func TestPresence(t *testing.T) {
+	fd := newFakeDiscord(t)
+
+	filter := privacy.NewFilter(pkg.PrivacyConfig{Hide: []string{"(?i)secret"}, Redact: []string{`\d{4,}`}})
+	p := New(pkg.DiscordConfig{Enabled: true, ApplicationID: "1234", Apps: []string{"GoLand", "Firefox"}}, filter)
+
+	// 1. 首次更新时握手，并设置经过隐私过滤的活动
+	p.Update("ticket 98765 - GoLand", "linux")
+	select {
+	case hs := <-fd.handshakes:
+		if hs["client_id"] != "1234" || hs["v"] != float64(1) {
+			t.Fatalf("unexpected handshake: %v", hs)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no handshake received")
+	}
+	args := fd.nextActivity(t)
+	if args.Activity == nil || args.Activity.Details != "ticket *** - GoLand" || args.Activity.State != "on linux" || args.PID == 0 {
+		t.Fatalf("unexpected activity: %+v", args.Activity)
+	}
+	start := args.Activity.Timestamps.Start
+	if time.Since(time.Unix(start, 0)) > time.Minute {
+		t.Fatalf("unexpected start timestamp: %d", start)
+	}
+
+	// 2. 不在白名单或被隐藏的窗口会清除活动
+	p.Update("Slack", "linux")
+	if args := fd.nextActivity(t); args.Activity != nil {
+		t.Fatalf("expected cleared activity, got %+v", args.Activity)
+	}
+	p.Update("Secret Project - GoLand", "linux")
+	if args := fd.nextActivity(t); args.Activity != nil {
+		t.Fatalf("expected cleared activity for hidden title, got %+v", args.Activity)
+	}
+
+	// 3. 复用同一连接，不会重复握手
+	p.Update("Firefox", "linux")
+	if args := fd.nextActivity(t); args.Activity == nil || args.Activity.Details != "Firefox" {
+		t.Fatalf("unexpected activity: %+v", args.Activity)
+	}
+	select {
+	case hs := <-fd.handshakes:
+		t.Fatalf("unexpected second handshake: %v", hs)
+	default:
+	}
+
+	p.Close()
+}
+
+func TestPresenceWithoutDiscord(t *testing.T) {
+	// Discord 未运行时静默跳过
+	p := New(pkg.DiscordConfig{Enabled: true, Apps: []string{"GoLand"}}, privacy.NewFilter(pkg.PrivacyConfig{}))
+	dials := 0
+	p.dial = func() (io.ReadWriteCloser, error) {
+		dials++
+		return nil, errors.New("discord ipc socket not found")
+	}
+	p.Update("GoLand", "linux")
+	// 没有连接时切换到不展示的窗口无需连接
+	p.Update("Slack", "linux")
+	if dials != 1 {
+		t.Fatalf("dials = %d, want 1", dials)
+	}
+	p.Close()
+}
+
+func TestPresenceTimeout(t *testing.T) {
+	// Discord 完成握手后不再响应：请求在超时后放弃，下一次更新重新连接
+	p := New(pkg.DiscordConfig{Enabled: true, Apps: []string{"GoLand"}}, privacy.NewFilter(pkg.PrivacyConfig{}))
+	p.timeout = 50 * time.Millisecond
+	dials := 0
+	p.dial = func() (io.ReadWriteCloser, error) {
+		dials++
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			if _, _, err := readFrame(server); err != nil {
+				return
+			}
+			_ = writeFrame(server, opFrame, message{Cmd: "DISPATCH", Evt: "READY"})
+			// 之后只读取请求，从不应答
+			for {
+				if _, _, err := readFrame(server); err != nil {
+					return
+				}
+			}
+		}()
+		return client, nil
+	}
+
+	start := time.Now()
+	p.Update("GoLand", "linux")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Update blocked for %v", elapsed)
+	}
+	if p.conn != nil {
+		t.Fatal("connection kept after a timed out request")
+	}
+	p.Update("GoLand 2", "linux")
+	if dials != 2 {
+		t.Errorf("dials = %d, want 2", dials)
+	}
+	p.Close()
+}
@@ -0,0 +1,180 @@
+package discord
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Discord IPC 的帧格式为：操作码（uint32 小端）+ 数据长度（uint32 小端）+ JSON 数据
+const (
+	opHandshake uint32 = 0
+	opFrame     uint32 = 1
+	opClose     uint32 = 2
+	opPing      uint32 = 3
+	opPong      uint32 = 4
+
+	maxFrameSize = 64 * 1024
+)
+
+// message 是 FRAME 中命令与响应共用的结构
+type message struct {
+	Cmd   string          `json:"cmd"`
+	Evt   string          `json:"evt,omitempty"`
+	Nonce string          `json:"nonce,omitempty"`
+	Args  json.RawMessage `json:"args,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// Activity 是 SET_ACTIVITY 中的活动内容，字段含义见 Discord Rich Presence 文档
+type Activity struct {
+	Details    string      `json:"details,omitempty"`
+	State      string      `json:"state,omitempty"`
+	Timestamps *Timestamps `json:"timestamps,omitempty"`
+	Assets     *Assets     `json:"assets,omitempty"`
+}
+
+type Timestamps struct {
+	Start int64 `json:"start,omitempty"` // Unix 秒，Discord 据此显示已经过的时间
+}
+
+type Assets struct {
+	LargeImage string `json:"large_image,omitempty"`
+	LargeText  string `json:"large_text,omitempty"`
+}
+
+func writeFrame(w io.Writer, op uint32, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeFrameBytes(w, op, data)
+}
+
+func writeFrameBytes(w io.Writer, op uint32, data []byte) error {
+	buf := make([]byte, 8+len(data))
+	binary.LittleEndian.PutUint32(buf[0:4], op)
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(len(data)))
+	copy(buf[8:], data)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readFrame(r io.Reader) (uint32, []byte, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	op := binary.LittleEndian.Uint32(header[0:4])
+	length := binary.LittleEndian.Uint32(header[4:8])
+	if length > maxFrameSize {
+		return 0, nil, fmt.Errorf("discord frame too large: %d", length)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	return op, data, nil
+}
+
+// conn 是一条已完成握手的 IPC 连接，同一时间只允许一个请求
+type conn struct {
+	rwc     io.ReadWriteCloser
+	timeout time.Duration
+}
+
+// deadline 为本次请求设置读写截止时间。Unix Socket 与 Windows 命名管道都支持，
+// 不支持截止时间的连接保持阻塞
+func (c *conn) deadline() {
+	if d, ok := c.rwc.(interface{ SetDeadline(time.Time) error }); ok && c.timeout > 0 {
+		_ = d.SetDeadline(time.Now().Add(c.timeout))
+	}
+}
+
+// handshake 发送 HANDSHAKE 并等待 READY 事件
+func handshake(rwc io.ReadWriteCloser, applicationID string, timeout time.Duration) (*conn, error) {
+	c := &conn{rwc: rwc, timeout: timeout}
+	c.deadline()
+	if err := writeFrame(rwc, opHandshake, map[string]interface{}{"v": 1, "client_id": applicationID}); err != nil {
+		return nil, err
+	}
+	msg, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	if msg.Evt != "READY" {
+		return nil, fmt.Errorf("unexpected handshake response: %s %s", msg.Cmd, msg.Evt)
+	}
+	return c, nil
+}
+
+// read 读取下一条 FRAME，期间自动应答 PING；收到 CLOSE 时返回 Discord 给出的原因
+func (c *conn) read() (*message, error) {
+	for {
+		op, data, err := readFrame(c.rwc)
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case opFrame:
+			msg := &message{}
+			if err := json.Unmarshal(data, msg); err != nil {
+				return nil, err
+			}
+			return msg, nil
+		case opPing:
+			if err := writeFrameBytes(c.rwc, opPong, data); err != nil {
+				return nil, err
+			}
+		case opClose:
+			var reason struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			}
+			_ = json.Unmarshal(data, &reason)
+			return nil, fmt.Errorf("discord closed connection: %d %s", reason.Code, reason.Message)
+		}
+	}
+}
+
+// setActivity 设置（activity 为 nil 时清除）当前进程的活动，并等待对应 nonce 的响应
+func (c *conn) setActivity(pid int, activity *Activity) error {
+	args, err := json.Marshal(struct {
+		PID      int       `json:"pid"`
+		Activity *Activity `json:"activity"`
+	}{pid, activity})
+	if err != nil {
+		return err
+	}
+
+	c.deadline()
+	nonce := uuid.New().String()
+	if err := writeFrame(c.rwc, opFrame, message{Cmd: "SET_ACTIVITY", Args: args, Nonce: nonce}); err != nil {
+		return err
+	}
+
+	for {
+		msg, err := c.read()
+		if err != nil {
+			return err
+		}
+		if msg.Nonce != nonce {
+			continue
+		}
+		if msg.Evt == "ERROR" {
+			return errors.New("discord rejected activity: " + string(msg.Data))
+		}
+		return nil
+	}
+}
+
+func (c *conn) Close() error {
+	c.deadline()
+	_ = writeFrame(c.rwc, opClose, map[string]interface{}{})
+	return c.rwc.Close()
+}
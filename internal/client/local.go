@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/nhirsama/Naniwosuruno/internal/client/localctl"
+	"github.com/nhirsama/Naniwosuruno/internal/client/outbox"
+)
+
+// localCommand 是控制套接字收到的一条命令，交由主循环处理，避免与主循环并发访问客户端状态
+type localCommand struct {
+	req   localctl.Request
+	reply chan localctl.Response
+}
+
+// listenLocal 打开本机控制套接字，ctx 结束后不再接受命令；失败时（例如已有客户端在运行）只记录日志，客户端照常工作
+func (c *Client) listenLocal(ctx context.Context) *localctl.Server {
+	path := localctl.SocketPath()
+	srv, err := localctl.Listen(path, func(req localctl.Request) localctl.Response {
+		cmd := localCommand{req: req, reply: make(chan localctl.Response, 1)}
+		select {
+		case c.commands <- cmd:
+			return <-cmd.reply
+		case <-ctx.Done():
+			// 主循环已退出，不再处理命令
+			return localctl.Response{Error: "client is shutting down"}
+		}
+	})
+	if err != nil {
+		log.Printf("无法打开本机控制套接字: %v", err)
+		return nil
+	}
+	log.Printf("本机控制套接字: %s", path)
+	return srv
+}
+
+// handleLocal 在主循环中执行一条本机命令
+func (c *Client) handleLocal(req localctl.Request) localctl.Response {
+	var err error
+	switch req.Command {
+	case localctl.CommandStatus:
+	case localctl.CommandPause:
+		err = c.pauseLocally(req.Duration)
+	case localctl.CommandResume:
+		err = c.resumeLocally()
+	case localctl.CommandReport:
+		err = c.reportNow()
+	case localctl.CommandSetStatus:
+		err = c.setCustomStatus(req.Text, req.Emoji, req.Duration)
+	default:
+		err = fmt.Errorf("unknown command %q", req.Command)
+	}
+	if err != nil {
+		return localctl.Response{Error: err.Error()}
+	}
+	return localctl.Response{Status: c.localStatus()}
+}
+
+// pauseLocally 在本机暂停上报，并尽量通知服务端以 paused 代替窗口标题发布；服务端不可达时本机暂停仍然生效
+func (c *Client) pauseLocally(duration time.Duration) error {
+	c.localPause = true
+	c.localPauseUntil = time.Time{}
+	if duration > 0 {
+		c.localPauseUntil = time.Now().Add(duration)
+	}
+	if err := c.connection.SetControl(ModePaused, c.localPauseUntil); err != nil {
+		log.Printf("无法通知服务端暂停: %v", err)
+	}
+	c.updateMode()
+	return nil
+}
+
+// resumeLocally 取消本机暂停，同时清除本客户端在服务端设置的控制；管理员设置的控制不受影响，仍然生效
+func (c *Client) resumeLocally() error {
+	c.localPause = false
+	err := c.connection.SetControl("", time.Time{})
+	c.updateMode()
+	switch {
+	case err == nil, errors.Is(err, errNotV1):
+		return nil
+	case connect.CodeOf(err) == connect.CodeFailedPrecondition:
+		return errors.New("本机暂停已取消，但管理员设置的控制仍然生效")
+	}
+	return fmt.Errorf("本机已恢复，但无法通知服务端: %w", err)
+}
+
+// setCustomStatus 通过服务端设置手动状态，text 为空表示清除。手动状态只保存在服务端，服务端不可达时返回错误
+func (c *Client) setCustomStatus(text, emoji string, duration time.Duration) error {
+	var until time.Time
+	if text != "" && duration > 0 {
+		until = time.Now().Add(duration)
+	}
+	if err := c.connection.SetCustomStatus(text, emoji, until); err != nil {
+		if errors.Is(err, errNotV1) {
+			return errors.New("custom status requires API v1")
+		}
+		return err
+	}
+	c.custom = customState{Text: text, Emoji: emoji, Until: until}
+	if text == "" {
+		log.Println("已清除手动状态")
+	} else {
+		log.Printf("已设置手动状态: %s", c.custom)
+	}
+	return nil
+}
+
+// reportNow 立即上报当前窗口（即使标题未变化）并发送一次心跳
+func (c *Client) reportNow() error {
+	if c.mode != "" {
+		return fmt.Errorf("reporting is %s", c.mode)
+	}
+	c.lastWindowTitle = ""
+	c.checkAndUpdateWindowTitle()
+	c.heartbeatCount++
+	return c.connection.SendHeartbeat(c.heartbeatCount)
+}
+
+// reported 记录最近一次送达服务端的窗口变化，供本机状态查询
+func (c *Client) reported(entry outbox.Entry) {
+	c.lastReported = entry
+}
+
+func (c *Client) localStatus() *localctl.Status {
+	conn := c.connection.Status()
+	status := &localctl.Status{
+		State:     string(conn.State),
+		Server:    conn.Server,
+		Since:     conn.Since,
+		LastError: conn.LastError,
+		LastTitle: c.lastReported.Title,
+		Queue:     c.outbox.Len(),
+	}
+	switch conn.State {
+	case StateAuthenticated:
+		status.Auth = "v1"
+	case StateDegraded:
+		status.Auth = "v0"
+	}
+	if c.lastReported.ObservedAt > 0 {
+		status.LastReport = time.UnixMilli(c.lastReported.ObservedAt)
+	}
+	status.Mode, status.ModeUntil = c.currentMode()
+	if c.custom.active(time.Now()) {
+		status.Custom = c.custom.String()
+		status.CustomUntil = c.custom.Until
+	}
+	return status
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/internal/client/localctl"
+	"github.com/nhirsama/Naniwosuruno/internal/client/outbox"
+	"github.com/nhirsama/Naniwosuruno/internal/client/privacy"
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+func TestLocalPauseResume(t *testing.T) {
+	_, windows, conn := newPresenceServer(t, true)
+	queue, err := outbox.Open(filepath.Join(t.TempDir(), "outbox.json"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{connection: conn, outbox: queue, privacy: privacy.NewFilter(pkg.PrivacyConfig{}), settings: DefaultSettings()}
+
+	// 1. 本机暂停立即生效，并通知服务端以 paused 代替窗口标题发布
+	res := c.handleLocal(localctl.Request{Command: localctl.CommandPause, Duration: time.Hour})
+	if res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	if res.Status.Mode != ModePaused || res.Status.Auth != "v1" || time.Until(res.Status.ModeUntil) < 59*time.Minute {
+		t.Errorf("status after pause = %+v", res.Status)
+	}
+	waitClient(t, windows, "paused", func(_, status string) bool { return status == service.StatusPaused })
+
+	// 2. 暂停期间不能强制上报
+	if res := c.handleLocal(localctl.Request{Command: localctl.CommandReport}); res.Error == "" {
+		t.Error("report accepted while paused")
+	}
+
+	// 3. 恢复后本机与服务端同时恢复
+	res = c.handleLocal(localctl.Request{Command: localctl.CommandResume})
+	if res.Error != "" || res.Status.Mode != "" {
+		t.Fatalf("resume = %+v (%s)", res.Status, res.Error)
+	}
+	waitClient(t, windows, "online", func(_, status string) bool { return status == service.StatusOnline })
+
+	// 4. 本机恢复不能清除管理员设置的控制
+	admin := connect.NewRequest(&naniwosurunov1.ControlClientRequest{Client: "laptop", Control: &naniwosurunov1.ClientControl{Mode: service.StatusBusy}})
+	admin.Header().Set("Authorization", "Bearer "+adminToken)
+	if _, err := windows.ControlClient(context.Background(), admin); err != nil {
+		t.Fatal(err)
+	}
+	if res := c.handleLocal(localctl.Request{Command: localctl.CommandResume}); res.Error == "" {
+		t.Error("local resume cleared the control set by an administrator")
+	}
+	waitClient(t, windows, "still busy", func(_, status string) bool { return status == service.StatusBusy })
+
+	// 5. 未知命令返回错误
+	if res := c.handleLocal(localctl.Request{Command: "reboot"}); res.Error == "" {
+		t.Error("unknown command accepted")
+	}
+}
+
+func TestLocalCustomStatus(t *testing.T) {
+	_, windows, conn := newPresenceServer(t, true)
+	queue, err := outbox.Open(filepath.Join(t.TempDir(), "outbox.json"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{connection: conn, outbox: queue, privacy: privacy.NewFilter(pkg.PrivacyConfig{}), settings: DefaultSettings()}
+
+	// 1. 通过控制套接字设置的手动状态发送到服务端，并出现在本机状态中
+	res := c.handleLocal(localctl.Request{Command: localctl.CommandSetStatus, Text: "Lunch", Emoji: "🍜", Duration: 45 * time.Minute})
+	if res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	if res.Status.Custom != "🍜 Lunch" || time.Until(res.Status.CustomUntil) < 44*time.Minute {
+		t.Errorf("status after set = %+v", res.Status)
+	}
+	ev, ok := windows.LookupClient(service.PrimaryClientName)
+	if !ok || service.CustomStatusText(ev.CustomStatus) != "🍜 Lunch" || ev.CustomStatus.Until == 0 {
+		t.Errorf("server custom status = %v, want 🍜 Lunch with expiry", ev.GetCustomStatus())
+	}
+
+	// 2. 文字为空时清除
+	res = c.handleLocal(localctl.Request{Command: localctl.CommandSetStatus})
+	if res.Error != "" || res.Status.Custom != "" {
+		t.Fatalf("clear = %+v (%s)", res.Status, res.Error)
+	}
+	if ev, _ := windows.LookupClient(service.PrimaryClientName); ev.CustomStatus != nil {
+		t.Errorf("server custom status after clear = %v, want none", ev.CustomStatus)
+	}
+}
@@ -0,0 +1,178 @@
+// Package localctl 提供正在运行的客户端的本机控制套接字，CLI 通过它查询状态、暂停或恢复上报、设置手动状态。
+// 每个连接只处理一条请求：客户端写入一行 JSON 请求，服务端回复一行 JSON 响应后关闭连接。
+package localctl
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	CommandStatus = "status" // 查询客户端状态
+	CommandPause  = "pause"  // 暂停上报窗口标题，Duration 为 0 表示直到恢复
+	CommandResume = "resume" // 恢复上报
+	CommandReport = "report" // 立即上报当前窗口并发送一次心跳
+	// CommandSetStatus 设置手动状态，Text 为空表示清除，Duration 为 0 表示直到清除
+	CommandSetStatus = "set-status"
+
+	// callTimeout 为 CLI 等待客户端响应的最长时间，客户端主循环可能正在等待一次网络请求
+	callTimeout = 20 * time.Second
+)
+
+// ErrNotRunning 表示套接字不存在或无人监听，即本机没有正在运行的客户端
+var ErrNotRunning = errors.New("client is not running")
+
+// Request 是发往客户端的一条命令
+type Request struct {
+	Command  string        `json:"command"`
+	Duration time.Duration `json:"duration,omitempty"` // pause 与 set-status 的持续时间
+	Text     string        `json:"text,omitempty"`     // set-status 的状态文字
+	Emoji    string        `json:"emoji,omitempty"`    // set-status 的表情
+}
+
+// Response 是客户端对命令的回复，Error 非空表示命令失败
+type Response struct {
+	Error  string  `json:"error,omitempty"`
+	Status *Status `json:"status,omitempty"`
+}
+
+// Status 是正在运行的客户端的状态
+type Status struct {
+	State       string    `json:"state"`          // 连接状态，见 client.ConnState
+	Auth        string    `json:"auth,omitempty"` // "v1"（挑战签名）或 "v0"（静态 Token），未连接时为空
+	Server      string    `json:"server"`
+	Since       time.Time `json:"since"`
+	LastError   string    `json:"last_error,omitempty"`
+	Mode        string    `json:"mode,omitempty"` // "paused"、"busy"，正常上报时为空
+	ModeUntil   time.Time `json:"mode_until,omitzero"`
+	LastTitle   string    `json:"last_title,omitempty"` // 最近一次上报的标题（已经过隐私过滤）
+	LastReport  time.Time `json:"last_report,omitzero"`
+	Queue       int       `json:"queue"`            // 离线队列中等待补报的窗口变化数
+	Custom      string    `json:"custom,omitempty"` // 通过本客户端设置的手动状态，如 "🍜 Lunch"
+	CustomUntil time.Time `json:"custom_until,omitzero"`
+}
+
+// Handler 处理一条命令
+type Handler func(Request) Response
+
+// SocketPath 返回控制套接字的默认路径：优先使用 XDG_RUNTIME_DIR，否则使用临时目录下按用户区分的子目录。
+// Windows 10 起同样支持 Unix 域套接字，因此各平台共用这一实现
+func SocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "naniwosuruno", "client.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("naniwosuruno-%d", os.Getuid()), "client.sock")
+}
+
+// Server 在控制套接字上接收命令
+type Server struct {
+	listener net.Listener
+	path     string
+	handler  Handler
+	wg       sync.WaitGroup
+}
+
+// Listen 在 path 上监听并开始处理命令。遗留的套接字文件会被清理，已有客户端在监听时返回错误
+func Listen(path string, handler Handler) (*Server, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("无法创建控制套接字目录: %w", err)
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("控制套接字 %s 已被另一个客户端占用", path)
+	}
+	_ = os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("监听控制套接字失败: %w", err)
+	}
+	// 只允许当前用户连接
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("设置控制套接字权限失败: %w", err)
+	}
+
+	s := &Server{listener: listener, path: path, handler: handler}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("控制套接字异常: %v", err)
+			}
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(callTimeout))
+
+	var req Request
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		return
+	}
+	res := Response{}
+	if err := json.Unmarshal(line, &req); err != nil {
+		res.Error = fmt.Sprintf("invalid request: %v", err)
+	} else {
+		res = s.handler(req)
+	}
+	_ = json.NewEncoder(conn).Encode(res)
+}
+
+// Close 停止监听、等待进行中的命令完成并删除套接字文件
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	_ = os.Remove(s.path)
+	return err
+}
+
+// Call 向 path 上的客户端发送一条命令，客户端未运行时返回 ErrNotRunning
+func Call(path string, req Request) (Response, error) {
+	var res Response
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err != nil {
+		return res, ErrNotRunning
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(callTimeout))
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return res, err
+	}
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		return res, err
+	}
+	if err := json.NewDecoder(conn).Decode(&res); err != nil {
+		return res, fmt.Errorf("读取客户端响应失败: %w", err)
+	}
+	if res.Error != "" {
+		return res, errors.New(res.Error)
+	}
+	return res, nil
+}
@@ -0,0 +1,51 @@
+package localctl
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCall(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client.sock")
+
+	// 1. 没有客户端监听时返回 ErrNotRunning
+	if _, err := Call(path, Request{Command: CommandStatus}); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("Call without server = %v, want ErrNotRunning", err)
+	}
+
+	srv, err := Listen(path, func(req Request) Response {
+		switch req.Command {
+		case CommandStatus:
+			return Response{Status: &Status{State: "authenticated", Queue: 3}}
+		case CommandPause:
+			if req.Duration != 30*time.Minute {
+				return Response{Error: "unexpected duration"}
+			}
+			return Response{}
+		}
+		return Response{Error: "unknown command"}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	// 2. 同一路径上不能再启动第二个客户端
+	if _, err := Listen(path, nil); err == nil {
+		t.Error("second Listen on the same socket succeeded")
+	}
+
+	// 3. 请求与响应按 JSON 往返，命令失败时返回错误
+	res, err := Call(path, Request{Command: CommandStatus})
+	if err != nil || res.Status == nil || res.Status.Queue != 3 {
+		t.Fatalf("status = %+v, %v", res.Status, err)
+	}
+	if _, err := Call(path, Request{Command: CommandPause, Duration: 30 * time.Minute}); err != nil {
+		t.Errorf("pause: %v", err)
+	}
+	if _, err := Call(path, Request{Command: "reboot"}); err == nil || err.Error() != "unknown command" {
+		t.Errorf("unknown command error = %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+// Package outbox 在服务端不可达时将窗口变化连同观测时间缓存到磁盘，重连后按顺序补报，避免历史出现空档
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const defaultMaxEntries = 1000
+
+// Entry 是一次未能送达的窗口变化，Title 已经过隐私过滤
+type Entry struct {
+	Title      string `json:"title"`
+	OS         string `json:"os"`
+	ObservedAt int64  `json:"observed_at"` // 观测时间 (Unix 毫秒)
+}
+
+// Outbox 是有界的持久化队列，每次变更都会整体写回磁盘，客户端重启后仍可补报
+type Outbox struct {
+	path    string
+	max     int
+	entries []Entry
+	mu      sync.Mutex
+}
+
+// Open 打开（或创建）队列文件并加载其中尚未补报的条目
+func Open(path string, max int) (*Outbox, error) {
+	if max <= 0 {
+		max = defaultMaxEntries
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("无法创建离线队列目录: %w", err)
+	}
+
+	o := &Outbox{path: path, max: max}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return o, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取离线队列失败: %w", err)
+	}
+	if err := json.Unmarshal(data, &o.entries); err != nil {
+		return nil, fmt.Errorf("解析离线队列失败: %w", err)
+	}
+	return o, nil
+}
+
+// Push 追加一条窗口变化，队列已满时丢弃最旧的条目
+func (o *Outbox) Push(e Entry) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.entries) >= o.max {
+		o.entries = o.entries[1:]
+	}
+	o.entries = append(o.entries, e)
+	return o.saveLocked()
+}
+
+// Entries 按观测顺序返回所有条目的副本
+func (o *Outbox) Entries() []Entry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]Entry(nil), o.entries...)
+}
+
+// Remove 移除最旧的 n 条（已补报的）条目
+func (o *Outbox) Remove(n int) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.entries = o.entries[min(n, len(o.entries)):]
+	return o.saveLocked()
+}
+
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.entries)
+}
+
+// saveLocked 先写入临时文件再重命名，避免写到一半崩溃导致队列损坏
+func (o *Outbox) saveLocked() error {
+	data, err := json.Marshal(o.entries)
+	if err != nil {
+		return fmt.Errorf("序列化离线队列失败: %w", err)
+	}
+	tmp := o.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("写入离线队列失败: %w", err)
+	}
+	return os.Rename(tmp, o.path)
+}
@@ -0,0 +1,36 @@
+package outbox
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestOutboxBoundedAndPersistent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.json")
+	o, err := Open(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1. 超出上限时丢弃最旧的条目
+	for i, title := range []string{"GoLand", "Firefox", "Slack"} {
+		if err := o.Push(Entry{Title: title, OS: "linux", ObservedAt: int64(i + 1)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if entries := o.Entries(); len(entries) != 2 || entries[0].Title != "Firefox" || entries[1].Title != "Slack" {
+		t.Fatalf("entries = %+v, want Firefox, Slack", entries)
+	}
+
+	// 2. 重新打开后条目仍在，移除已补报的条目同样持久化
+	if err := o.Remove(1); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := Open(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries := reopened.Entries(); len(entries) != 1 || entries[0].Title != "Slack" || entries[0].ObservedAt != 3 {
+		t.Errorf("reopened entries = %+v, want Slack", entries)
+	}
+}
@@ -0,0 +1,190 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"golang.org/x/net/http2"
+)
+
+const (
+	// defaultPresenceKeepalive 在服务端未告知保活超时时使用
+	defaultPresenceKeepalive = 15 * time.Second
+	// presenceAckTimeout 为等待首个确认的时间，经过只支持 HTTP/1.1 的代理时流无法建立，会在这里超时或报错
+	presenceAckTimeout = 10 * time.Second
+)
+
+// newStreamingClient 创建用于 Presence 双向流的 HTTP 客户端：双向流需要 HTTP/2，明文地址使用 h2c，
+// 且不能设置整体超时，否则长连接会被定期切断
+func newStreamingClient(baseURL string) *http.Client {
+	if strings.HasPrefix(baseURL, "https://") {
+		return &http.Client{}
+	}
+	return &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+}
+
+// presenceStream 是一条已确认的 Presence 双向流，后台协程负责接收确认与定期保活
+type presenceStream struct {
+	stream *connect.BidiStreamForClient[naniwosurunov1.PresenceUpdate, naniwosurunov1.PresenceAck]
+	cancel context.CancelFunc
+	done   chan struct{} // 流结束时关闭
+	mu     sync.Mutex    // Send 不能并发调用
+}
+
+func (p *presenceStream) send(msg *naniwosurunov1.PresenceUpdate) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stream.Send(msg)
+}
+
+func (p *presenceStream) alive() bool {
+	select {
+	case <-p.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// close 先半关闭请求流，让服务端收到 EOF 后立即将客户端标记为离线，再等待流结束
+func (p *presenceStream) close() {
+	p.mu.Lock()
+	_ = p.stream.CloseRequest()
+	p.mu.Unlock()
+	select {
+	case <-p.done:
+	case <-time.After(presenceAckTimeout):
+	}
+	p.cancel()
+	<-p.done
+}
+
+func (p *presenceStream) keepalive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			if err := p.send(&naniwosurunov1.PresenceUpdate{}); err != nil {
+				p.cancel()
+				return
+			}
+		}
+	}
+}
+
+// openPresence 打开 Presence 流并等待服务端的首个确认，成功后焦点变化改由流发送，心跳不再需要
+func (s *ServerConnection) openPresence() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := s.streamClient.Presence(ctx)
+	stream.RequestHeader().Set("Authorization", "Bearer "+s.token)
+	if err := stream.Send(&naniwosurunov1.PresenceUpdate{}); err != nil {
+		cancel()
+		return err
+	}
+
+	first := make(chan *naniwosurunov1.PresenceAck, 1)
+	failed := make(chan error, 1)
+	go func() {
+		ack, err := stream.Receive()
+		if err != nil {
+			failed <- err
+			return
+		}
+		first <- ack
+	}()
+
+	var ack *naniwosurunov1.PresenceAck
+	select {
+	case ack = <-first:
+	case err := <-failed:
+		cancel()
+		return err
+	case <-time.After(presenceAckTimeout):
+		cancel()
+		return errors.New("presence stream not acknowledged")
+	}
+
+	s.observeRevision(ack.ConfigRevision)
+	s.observeControl(ack.Control)
+	p := &presenceStream{stream: stream, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(p.done)
+		for {
+			ack, err := stream.Receive()
+			if err != nil {
+				p.cancel()
+				return
+			}
+			s.observeRevision(ack.ConfigRevision)
+			s.observeControl(ack.Control)
+		}
+	}()
+
+	interval := defaultPresenceKeepalive
+	if ack.KeepaliveTimeout > 0 {
+		// 在服务端超时前至少保活两次，容忍一次丢失
+		interval = min(interval, time.Duration(ack.KeepaliveTimeout)*time.Second/3)
+	}
+	go p.keepalive(interval)
+	s.presence = p
+	return nil
+}
+
+// closePresence 关闭当前的 Presence 流，服务端会立即将本客户端标记为离线
+func (s *ServerConnection) closePresence() {
+	if s.presence == nil {
+		return
+	}
+	s.presence.close()
+	s.presence = nil
+}
+
+// streaming 报告 Presence 流是否可用
+func (s *ServerConnection) streaming() bool {
+	return s.presence != nil && s.presence.alive()
+}
+
+// maintainPresence 在已认证时保持 Presence 流打开，无法建立时退回轮询并在稍后重试。
+// 返回 true 表示原有的流已断开且未能重建，调用方应立即以心跳恢复在线状态
+func (s *ServerConnection) maintainPresence() bool {
+	if s.Status().State != StateAuthenticated || s.streaming() || time.Now().Before(s.presenceRetry) {
+		return false
+	}
+
+	dropped := s.presence != nil
+	s.closePresence()
+	err := s.openPresence()
+	if connect.CodeOf(err) == connect.CodeUnauthenticated {
+		// 服务端重启后会话失效，重新认证后再试一次
+		if err = s.authenticateV1(); err == nil {
+			err = s.openPresence()
+		}
+	}
+	if err != nil {
+		if dropped || s.presenceRetry.IsZero() {
+			log.Printf("Presence 流不可用，使用心跳轮询: %v", err)
+		}
+		s.presenceRetry = time.Now().Add(renegotiateInterval)
+		return dropped
+	}
+	log.Println("Presence 流已建立")
+	return false
+}
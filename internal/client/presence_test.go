@@ -0,0 +1,167 @@
+package client
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1/naniwosurunov1connect"
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+	"github.com/nhirsama/Naniwosuruno/pkg/auth"
+	"github.com/r3labs/sse/v2"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// adminToken 为测试服务端配置中的 AdminToken，用于下发控制
+const adminToken = "admin-token"
+
+func newPresenceServer(t *testing.T, h2 bool) (*httptest.Server, *service.WindowService, *ServerConnection) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loader := &pkg.JSONConfigLoader{DataDir: t.TempDir(), FileName: "config.json"}
+	if err := loader.Save(&pkg.AppConfig{Token: "0123456789abcdef0123456789abcdef", AdminToken: adminToken}); err != nil {
+		t.Fatal(err)
+	}
+	cm, err := pkg.NewConfigManagerWithLoader(loader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator := auth.NewStatefulAuthenticator(&trustedKeys{keys: map[string]ed25519.PublicKey{"laptop": pub}})
+	windows := service.NewWindowService(service.NewEventBroker(sse.New(), nil, 0), authenticator, cm)
+	mux := http.NewServeMux()
+	mux.Handle(naniwosurunov1connect.NewAuthServiceHandler(service.NewAuthService(authenticator)))
+	mux.Handle(naniwosurunov1connect.NewWindowServiceHandler(windows))
+	var handler http.Handler = mux
+	if h2 {
+		handler = h2c.NewHandler(mux, &http2.Server{})
+	}
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	conn := NewServerConnection(&pkg.AppConfig{BaseUrl: srv.URL, ClientID: "laptop", PrivateKey: base64.StdEncoding.EncodeToString(priv)})
+	conn.statusPath = filepath.Join(t.TempDir(), "client-status.json")
+	t.Cleanup(conn.closePresence)
+	conn.Connect()
+	conn.Maintain()
+	return srv, windows, conn
+}
+
+func waitClient(t *testing.T, windows *service.WindowService, what string, cond func(title, status string) bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if ev, ok := windows.LookupClient("laptop"); ok && cond(ev.Title, ev.Status) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPresenceStream(t *testing.T) {
+	_, windows, conn := newPresenceServer(t, true)
+
+	// 1. 建立流后客户端立即在线，焦点变化经由流发送
+	if !conn.streaming() {
+		t.Fatal("presence stream not established")
+	}
+	waitClient(t, windows, "online", func(_, status string) bool { return status == service.StatusOnline })
+	if err := conn.SendUpdate(&UpdatePayload{Title: "GoLand", OS: Linux}); err != nil {
+		t.Fatal(err)
+	}
+	waitClient(t, windows, "focus via stream", func(title, _ string) bool { return title == "GoLand" })
+
+	// 2. 流关闭后服务端立即将客户端标记为离线，无需等待心跳超时
+	conn.closePresence()
+	waitClient(t, windows, "offline", func(_, status string) bool { return status == service.StatusOffline })
+}
+
+func TestPresenceFallsBackToPolling(t *testing.T) {
+	// 服务端只支持 HTTP/1.1 时无法建立双向流，退回 ReportWindow 与 Heartbeat
+	_, windows, conn := newPresenceServer(t, false)
+	if conn.streaming() {
+		t.Fatal("presence stream established over HTTP/1.1")
+	}
+	if got := conn.Status().State; got != StateAuthenticated {
+		t.Fatalf("state = %s, want %s", got, StateAuthenticated)
+	}
+	if err := conn.SendUpdate(&UpdatePayload{Title: "Firefox", OS: Linux}); err != nil {
+		t.Fatal(err)
+	}
+	waitClient(t, windows, "focus via polling", func(title, status string) bool {
+		return title == "Firefox" && status == service.StatusOnline
+	})
+}
+
+func TestSyncSettings(t *testing.T) {
+	_, _, conn := newPresenceServer(t, true)
+
+	// 1. 认证后首次同步获取服务端设置
+	settings, ok := conn.SyncSettings()
+	if !ok {
+		t.Fatal("settings not fetched after authentication")
+	}
+	if settings.PollInterval != 5*time.Second || settings.HeartbeatInterval != 120*time.Second || !settings.Allows("title") {
+		t.Errorf("settings = %+v", settings)
+	}
+
+	// 2. 版本未变化时不重复获取
+	conn.observeRevision(settings.Revision)
+	if _, ok := conn.SyncSettings(); ok {
+		t.Error("settings refetched without revision change")
+	}
+
+	// 3. 心跳或确认中的版本变化后重新获取
+	conn.observeRevision("changed")
+	if _, ok := conn.SyncSettings(); !ok {
+		t.Error("settings not refetched after revision change")
+	}
+}
+
+func TestControlPushedOverPresence(t *testing.T) {
+	_, windows, conn := newPresenceServer(t, true)
+	control := func(mode string) {
+		t.Helper()
+		req := connect.NewRequest(&naniwosurunov1.ControlClientRequest{Client: "laptop", Control: &naniwosurunov1.ClientControl{Mode: mode}})
+		req.Header().Set("Authorization", "Bearer "+adminToken)
+		if _, err := windows.ControlClient(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitMode := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for conn.Mode() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("mode = %q, want %q", conn.Mode(), want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// 1. 控制变化由服务端主动经 Presence 流推送，无需等待客户端发送消息
+	control(ModePaused)
+	waitMode(ModePaused)
+	if status, err := ReadStatus(conn.statusPath); err != nil || status.Mode != ModePaused {
+		t.Errorf("status file mode = %q (%v), want paused", status.Mode, err)
+	}
+
+	// 2. 恢复后同样立即送达
+	control("")
+	waitMode("")
+}
@@ -0,0 +1,101 @@
+package privacy
+
+import (
+	"log"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+const (
+	defaultPlaceholder = "Private"
+	redacted           = "***"
+)
+
+// Filter 在窗口标题离开本机前隐藏或脱敏其中的敏感内容。
+// 规则由本地配置与服务端下发的规则两部分组成，下发的规则只会追加，不能放宽本地规则。
+type Filter struct {
+	local  rules
+	remote rules
+	mu     sync.RWMutex
+}
+
+type rules struct {
+	hide        []*regexp.Regexp
+	redact      []*regexp.Regexp
+	placeholder string
+}
+
+func newRules(cfg pkg.PrivacyConfig) rules {
+	return rules{
+		hide:        compile(cfg.Hide),
+		redact:      compile(cfg.Redact),
+		placeholder: cfg.Placeholder,
+	}
+}
+
+// NewFilter 编译配置中的规则，无效的正则会被记录并忽略
+func NewFilter(cfg pkg.PrivacyConfig) *Filter {
+	return &Filter{local: newRules(cfg)}
+}
+
+// SetRemote 替换服务端下发的规则，下发的占位符仅在本地未配置时生效
+func (f *Filter) SetRemote(cfg pkg.PrivacyConfig) {
+	remote := newRules(cfg)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.remote = remote
+}
+
+// Placeholder 返回被隐藏的标题显示的内容
+func (f *Filter) Placeholder() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	switch {
+	case f.local.placeholder != "":
+		return f.local.placeholder
+	case f.remote.placeholder != "":
+		return f.remote.placeholder
+	}
+	return defaultPlaceholder
+}
+
+func compile(exprs []string) []*regexp.Regexp {
+	var res []*regexp.Regexp
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			log.Printf("隐私规则无效 %q: %v", expr, err)
+			continue
+		}
+		res = append(res, re)
+	}
+	return res
+}
+
+// Apply 返回过滤后的标题：命中 hide 规则时整体替换为占位符，否则将命中 redact 规则的部分替换为 "***"
+func (f *Filter) Apply(title string) string {
+	if f.Hidden(title) {
+		return f.Placeholder()
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, re := range slices.Concat(f.local.redact, f.remote.redact) {
+		title = re.ReplaceAllString(title, redacted)
+	}
+	return title
+}
+
+// Hidden 判断标题是否被整体隐藏
+func (f *Filter) Hidden(title string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, re := range slices.Concat(f.local.hide, f.remote.hide) {
+		if re.MatchString(title) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package privacy
+
+import (
+	"testing"
+
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+func TestFilter(t *testing.T) {
+	f := NewFilter(pkg.PrivacyConfig{
+		Hide:   []string{"(?i)bank", "("},
+		Redact: []string{`[\w.]+@[\w.]+`},
+	})
+
+	cases := map[string]string{
+		"My Bank - Firefox":                       "Private",
+		"Inbox (alice@example.com) - Thunderbird": "Inbox (***) - Thunderbird",
+		"main.go - GoLand":                        "main.go - GoLand",
+	}
+	for in, want := range cases {
+		if got := f.Apply(in); got != want {
+			t.Errorf("Apply(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if !f.Hidden("bank") || f.Hidden("GoLand") {
+		t.Error("Hidden() mismatch")
+	}
+}
+
+func TestFilterRemoteRules(t *testing.T) {
+	f := NewFilter(pkg.PrivacyConfig{Hide: []string{"(?i)bank"}})
+
+	// 1. 下发的规则追加在本地规则之后，本地规则仍然生效
+	f.SetRemote(pkg.PrivacyConfig{Hide: []string{"Slack"}, Redact: []string{`#\w+`}, Placeholder: "Busy"})
+	cases := map[string]string{
+		"My Bank - Firefox": "Busy",
+		"Slack | general":   "Busy",
+		"#secret - Discord": "*** - Discord",
+		"main.go - GoLand":  "main.go - GoLand",
+	}
+	for in, want := range cases {
+		if got := f.Apply(in); got != want {
+			t.Errorf("Apply(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	// 2. 再次下发时替换之前的规则
+	f.SetRemote(pkg.PrivacyConfig{})
+	if got := f.Apply("Slack | general"); got != "Slack | general" {
+		t.Errorf("Apply after reset = %q", got)
+	}
+
+	// 3. 本地配置的占位符优先
+	local := NewFilter(pkg.PrivacyConfig{Hide: []string{"bank"}, Placeholder: "Hidden"})
+	local.SetRemote(pkg.PrivacyConfig{Placeholder: "Busy"})
+	if got := local.Apply("bank"); got != "Hidden" {
+		t.Errorf("placeholder = %q, want Hidden", got)
+	}
+}
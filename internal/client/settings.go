@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	"connectrpc.com/connect"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+const (
+	defaultPollInterval      = 5 * time.Second
+	defaultHeartbeatInterval = 120 * time.Second
+)
+
+// Settings 是服务端下发的客户端设置，连接旧版服务端或使用 v0 时保持默认值
+type Settings struct {
+	PollInterval      time.Duration
+	HeartbeatInterval time.Duration
+	Privacy           pkg.PrivacyConfig
+	Fields            []string // 允许上报的字段，nil 表示全部
+	Revision          string
+}
+
+// DefaultSettings 返回未获取到服务端设置时使用的默认值
+func DefaultSettings() Settings {
+	return Settings{PollInterval: defaultPollInterval, HeartbeatInterval: defaultHeartbeatInterval}
+}
+
+// Allows 判断字段是否允许上报
+func (s Settings) Allows(field string) bool {
+	return s.Fields == nil || slices.Contains(s.Fields, field)
+}
+
+func settingsFromProto(msg *naniwosurunov1.GetClientConfigResponse) Settings {
+	settings := DefaultSettings()
+	if msg.PollInterval > 0 {
+		settings.PollInterval = time.Duration(msg.PollInterval) * time.Second
+	}
+	if msg.HeartbeatInterval > 0 {
+		settings.HeartbeatInterval = time.Duration(msg.HeartbeatInterval) * time.Second
+	}
+	settings.Privacy = pkg.PrivacyConfig{Hide: msg.Hide, Redact: msg.Redact, Placeholder: msg.Placeholder}
+	if len(msg.Fields) > 0 {
+		settings.Fields = msg.Fields
+	}
+	settings.Revision = msg.Revision
+	return settings
+}
+
+// observeRevision 记录心跳或 Presence 确认中携带的设置版本
+func (s *ServerConnection) observeRevision(revision string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latestRevision = revision
+}
+
+// SyncSettings 在认证后首次调用或服务端设置版本变化时获取新的设置，返回 false 表示无需更新。
+// 服务端不支持 GetClientConfig 时不再重试，继续使用当前设置
+func (s *ServerConnection) SyncSettings() (Settings, bool) {
+	if !s.useV1 || s.Status().State != StateAuthenticated {
+		return Settings{}, false
+	}
+	s.mu.Lock()
+	latest := s.latestRevision
+	s.mu.Unlock()
+	if s.settingsSynced && latest == s.appliedRevision {
+		return Settings{}, false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req := connect.NewRequest(&naniwosurunov1.GetClientConfigRequest{})
+	req.Header().Set("Authorization", "Bearer "+s.token)
+	res, err := s.windowClient.GetClientConfig(ctx, req)
+	if err != nil {
+		if connect.CodeOf(err) == connect.CodeUnimplemented {
+			s.settingsSynced = true
+			s.appliedRevision = latest
+			return Settings{}, false
+		}
+		log.Printf("获取服务端下发的设置失败: %v", s.observe(err))
+		return Settings{}, false
+	}
+
+	s.settingsSynced = true
+	s.appliedRevision = res.Msg.Revision
+	return settingsFromProto(res.Msg), true
+}
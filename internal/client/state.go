@@ -0,0 +1,211 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+// ConnState 是客户端与服务端之间的连接状态
+type ConnState string
+
+const (
+	StateConnecting    ConnState = "connecting"    // 正在进行 v1 握手
+	StateAuthenticated ConnState = "authenticated" // 已通过 v1 认证
+	StateDegraded      ConnState = "degraded-v0"   // 服务端可达但 v1 不可用，使用静态 Token，定期重新协商
+	StateDisconnected  ConnState = "disconnected"  // 服务端不可达，按退避时间重连
+)
+
+const (
+	minBackoff = 2 * time.Second
+	maxBackoff = 5 * time.Minute
+	// renegotiateInterval 为降级到 v0 后重新尝试 v1 握手的间隔
+	renegotiateInterval = 5 * time.Minute
+)
+
+// StatusPath 是客户端记录连接状态的文件，供 `client status` 读取
+var StatusPath = filepath.Join(pkg.DefaultDataDir, "client-status.json")
+
+// errDisconnected 表示连接处于断开状态、尚未到重连时间，请求不会发出
+var errDisconnected = errors.New("server unreachable, waiting to reconnect")
+
+// ConnectionStatus 是连接状态的快照，每次状态变化时写入 StatusPath
+type ConnectionStatus struct {
+	State       ConnState `json:"state"`
+	Server      string    `json:"server"`
+	Since       time.Time `json:"since"`
+	LastError   string    `json:"last_error,omitempty"`
+	NextAttempt time.Time `json:"next_attempt,omitzero"` // 下一次重连或重新协商的时间
+	Mode        string    `json:"mode,omitempty"`        // 服务端下发的控制模式 ("paused"、"busy")，见 control.go
+	ModeUntil   time.Time `json:"mode_until,omitzero"`   // 控制到期的时间，零值表示直到恢复
+}
+
+// ReadStatus 读取客户端最近一次记录的连接状态
+func ReadStatus(path string) (ConnectionStatus, error) {
+	var status ConnectionStatus
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return status, err
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return status, fmt.Errorf("解析连接状态失败: %w", err)
+	}
+	return status, nil
+}
+
+// Status 返回当前的连接状态
+func (s *ServerConnection) Status() ConnectionStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// setState 切换连接状态，记录日志并写入状态文件；next 为零值表示不安排重试
+func (s *ServerConnection) setState(state ConnState, err error, next time.Time) {
+	s.mu.Lock()
+	prev := s.status.State
+	s.status.State = state
+	s.status.NextAttempt = next
+	s.status.LastError = ""
+	if err != nil {
+		s.status.LastError = err.Error()
+	}
+	if prev != state {
+		s.status.Since = time.Now()
+	}
+	status := s.status
+	s.mu.Unlock()
+
+	switch {
+	case prev == state:
+	case err != nil:
+		log.Printf("连接状态: %s -> %s (%v)", prev, state, err)
+	default:
+		log.Printf("连接状态: %s -> %s", prev, state)
+	}
+	s.saveStatus(status)
+}
+
+// saveStatus 写入状态文件，未设置路径时跳过
+func (s *ServerConnection) saveStatus(status ConnectionStatus) {
+	if s.statusPath == "" {
+		return
+	}
+	if err := writeStatus(s.statusPath, status); err != nil {
+		log.Printf("写入连接状态失败: %v", err)
+	}
+}
+
+func writeStatus(path string, status ConnectionStatus) error {
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// markDisconnected 进入断开状态，并按带抖动的指数退避安排下一次重连
+func (s *ServerConnection) markDisconnected(err error) {
+	s.useV1 = false
+	s.closePresence()
+	// 加入随机抖动，避免服务端重启后所有客户端同时重连
+	wait := s.backoff/2 + rand.N(s.backoff/2+1)
+	s.backoff = min(s.backoff*2, maxBackoff)
+	s.setState(StateDisconnected, err, time.Now().Add(wait))
+}
+
+// observe 根据请求结果更新连接状态：服务端不可达时进入断开状态
+func (s *ServerConnection) observe(err error) error {
+	if isUnreachable(err) {
+		s.markDisconnected(err)
+	}
+	return err
+}
+
+// ready 在断开状态且未到重连时间时直接返回错误，避免每次上报都等待超时
+func (s *ServerConnection) ready() error {
+	if s.Status().State == StateDisconnected {
+		return errDisconnected
+	}
+	return nil
+}
+
+// Maintain 由客户端主循环定期调用：网络变化时立即重新认证，断开或降级状态到期后重新协商，已认证时维持 Presence 流。
+// 返回 true 表示重新连上或 Presence 流断开，调用方应立即发送一次心跳
+func (s *ServerConnection) Maintain() bool {
+	if key := networkKey(); key != s.networkKey {
+		s.networkKey = key
+		log.Println("检测到网络变化，重新认证")
+		s.backoff = s.retryBase
+		return s.reconnect()
+	}
+
+	status := s.Status()
+	if status.State == StateAuthenticated {
+		return s.maintainPresence()
+	}
+	if status.State != StateDisconnected && status.State != StateDegraded {
+		return false
+	}
+	if status.NextAttempt.IsZero() || time.Now().Before(status.NextAttempt) {
+		return false
+	}
+	return s.reconnect()
+}
+
+func (s *ServerConnection) reconnect() bool {
+	s.Connect()
+	s.maintainPresence()
+	state := s.Status().State
+	return state == StateAuthenticated || state == StateDegraded
+}
+
+// isUnreachable 判断错误是否由网络或服务端不可用导致，而不是服务端明确拒绝
+func isUnreachable(err error) bool {
+	if err == nil || errors.Is(err, errDisconnected) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	switch connect.CodeOf(err) {
+	case connect.CodeUnavailable, connect.CodeDeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// networkKey 以本机所有非回环地址作为网络环境的指纹，切换 Wi-Fi、VPN 等都会改变它
+func networkKey() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ""
+	}
+	var keys []string
+	for _, addr := range addrs {
+		if ip, ok := addr.(*net.IPNet); ok && !ip.IP.IsLoopback() {
+			keys = append(keys, ip.String())
+		}
+	}
+	slices.Sort(keys)
+	return strings.Join(keys, ",")
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1/naniwosurunov1connect"
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+	"github.com/nhirsama/Naniwosuruno/pkg/auth"
+	"github.com/r3labs/sse/v2"
+)
+
+// trustedKeys 是可在测试中途加入公钥的 KeyProvider
+type trustedKeys struct {
+	keys map[string]ed25519.PublicKey
+	mu   sync.Mutex
+}
+
+func (k *trustedKeys) GetClientPublicKey(clientID string) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if key, ok := k.keys[clientID]; ok {
+		return key, nil
+	}
+	return nil, errors.New("client not found")
+}
+
+func (k *trustedKeys) trust(clientID string, key ed25519.PublicKey) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[clientID] = key
+}
+
+func TestConnectionStateMachine(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := &trustedKeys{keys: make(map[string]ed25519.PublicKey)}
+	authenticator := auth.NewStatefulAuthenticator(keys)
+	mux := http.NewServeMux()
+	mux.Handle(naniwosurunov1connect.NewAuthServiceHandler(service.NewAuthService(authenticator)))
+	mux.Handle(naniwosurunov1connect.NewWindowServiceHandler(service.NewWindowService(service.NewEventBroker(sse.New(), nil, 0), authenticator, nil)))
+	var down atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	conn := NewServerConnection(&pkg.AppConfig{BaseUrl: srv.URL, ClientID: "laptop", PrivateKey: base64.StdEncoding.EncodeToString(priv)})
+	conn.statusPath = filepath.Join(t.TempDir(), "client-status.json")
+	conn.retryBase = time.Millisecond
+	conn.backoff = time.Millisecond
+	expire := func() {
+		conn.mu.Lock()
+		conn.status.NextAttempt = time.Now().Add(-time.Second)
+		conn.mu.Unlock()
+	}
+
+	// 1. 服务端不可达时进入 disconnected，之后的上报不再发出请求
+	down.Store(true)
+	conn.Connect()
+	if got := conn.Status().State; got != StateDisconnected {
+		t.Fatalf("state = %s, want %s", got, StateDisconnected)
+	}
+	if err := conn.SendUpdate(&UpdatePayload{Title: "GoLand", OS: Linux}); !errors.Is(err, errDisconnected) {
+		t.Errorf("SendUpdate while disconnected = %v", err)
+	}
+
+	// 2. 服务端恢复但尚未信任公钥：降级到 v0 并安排重新协商
+	down.Store(false)
+	expire()
+	if !conn.Maintain() {
+		t.Error("Maintain did not reconnect")
+	}
+	status := conn.Status()
+	if status.State != StateDegraded || status.NextAttempt.Before(time.Now().Add(renegotiateInterval-time.Minute)) {
+		t.Fatalf("status = %+v, want degraded with renegotiation scheduled", status)
+	}
+
+	// 3. 公钥加入后，到期的重新协商切换回 v1
+	keys.trust("laptop", pub)
+	if conn.Maintain() {
+		t.Error("renegotiated before the scheduled time")
+	}
+	expire()
+	conn.Maintain()
+	if got := conn.Status().State; got != StateAuthenticated {
+		t.Fatalf("state = %s, want %s", got, StateAuthenticated)
+	}
+	if err := conn.SendUpdate(&UpdatePayload{Title: "GoLand", OS: Linux}); err != nil {
+		t.Errorf("SendUpdate after renegotiation: %v", err)
+	}
+
+	// 4. 状态文件记录最近一次状态，供 client status 读取
+	recorded, err := ReadStatus(conn.statusPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recorded.State != StateAuthenticated || recorded.Server != srv.URL {
+		t.Errorf("recorded status = %+v", recorded)
+	}
+}
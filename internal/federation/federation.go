@@ -0,0 +1,218 @@
+package federation
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+	// stableAfter 为连接保持多久后视为稳定，之后断开时退避时间从头计算
+	stableAfter = time.Minute
+
+	requestTimeout = 10 * time.Second
+	// readTimeout 需大于对端 WebSocket 的 ping 间隔（54 秒）
+	readTimeout = 90 * time.Second
+)
+
+var unmarshaler = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+// Target 接收对端的快照、实时事件与连接状态，由 WindowService 实现
+type Target interface {
+	ReplaceRemote(peer string, snap *naniwosurunov1.GetSnapshotResponse)
+	ApplyRemote(peer string, ev *naniwosurunov1.WindowEvent)
+	SetPeerHealth(peer string, up bool)
+}
+
+// Manager 为每个对端维持一条订阅：先拉取 /api/v1/snapshot，再从快照的事件 ID 起订阅 /api/v1/ws，断开后按指数退避自动重连
+type Manager struct {
+	peers      []pkg.PeerConfig
+	target     Target
+	httpClient *http.Client
+	dialer     *websocket.Dialer
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewManager 校验对端配置，名称为空或包含 "/" 的对端会被忽略
+func NewManager(cfg pkg.FederationConfig, target Target) *Manager {
+	m := &Manager{
+		target:     target,
+		httpClient: &http.Client{Timeout: requestTimeout},
+		dialer:     &websocket.Dialer{HandshakeTimeout: requestTimeout},
+	}
+	for _, peer := range cfg.Peers {
+		if peer.Name == "" || strings.Contains(peer.Name, service.RemoteSeparator) || peer.URL == "" {
+			log.Printf("忽略无效的联邦对端: %q (%s)", peer.Name, peer.URL)
+			continue
+		}
+		m.peers = append(m.peers, peer)
+	}
+	return m
+}
+
+func (m *Manager) Enabled() bool {
+	return len(m.peers) > 0
+}
+
+func (m *Manager) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	for _, peer := range m.peers {
+		// 连上之前先标记为离线，使面板立即显示所有对端
+		m.target.SetPeerHealth(peer.Name, false)
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			m.runPeer(ctx, peer)
+		}()
+	}
+}
+
+func (m *Manager) Stop() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+func (m *Manager) runPeer(ctx context.Context, peer pkg.PeerConfig) {
+	backoff := minBackoff
+	for {
+		start := time.Now()
+		err := m.session(ctx, peer)
+		if ctx.Err() != nil {
+			return
+		}
+		m.target.SetPeerHealth(peer.Name, false)
+		log.Printf("联邦对端 %s 连接断开: %v", peer.Name, err)
+
+		if time.Since(start) > stableAfter {
+			backoff = minBackoff
+		}
+		// 加入随机抖动，避免多个对端同时重启后集中重连
+		wait := backoff/2 + rand.N(backoff/2+1)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// session 完成一次快照拉取与事件订阅，直到连接断开或 ctx 结束
+func (m *Manager) session(ctx context.Context, peer pkg.PeerConfig) error {
+	snap, err := m.fetchSnapshot(ctx, peer)
+	if err != nil {
+		return err
+	}
+
+	wsURL, err := eventsURL(peer.URL, snap.LastEventId)
+	if err != nil {
+		return err
+	}
+	conn, resp, err := m.dialer.DialContext(ctx, wsURL, authHeader(peer))
+	if err != nil {
+		if resp != nil {
+			return fmt.Errorf("dial %s: %s", wsURL, resp.Status)
+		}
+		return err
+	}
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	m.target.ReplaceRemote(peer.Name, snap)
+	m.target.SetPeerHealth(peer.Name, true)
+
+	_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+	conn.SetPingHandler(func(data string) error {
+		_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(requestTimeout))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+
+		ev := &naniwosurunov1.WindowEvent{}
+		if err := unmarshaler.Unmarshal(data, ev); err != nil {
+			log.Printf("解析联邦对端 %s 的事件失败: %v", peer.Name, err)
+			continue
+		}
+		m.target.ApplyRemote(peer.Name, ev)
+	}
+}
+
+func (m *Manager) fetchSnapshot(ctx context.Context, peer pkg.PeerConfig) (*naniwosurunov1.GetSnapshotResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(peer.URL, "/")+"/api/v1/snapshot", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = authHeader(peer)
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch snapshot: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, err
+	}
+	snap := &naniwosurunov1.GetSnapshotResponse{}
+	if err := unmarshaler.Unmarshal(data, snap); err != nil {
+		return nil, fmt.Errorf("parse snapshot: %w", err)
+	}
+	return snap, nil
+}
+
+func authHeader(peer pkg.PeerConfig) http.Header {
+	h := http.Header{}
+	if peer.Token != "" {
+		h.Set("Authorization", "Bearer "+peer.Token)
+	}
+	return h
+}
+
+// eventsURL 将对端的 HTTP 地址转换为 WebSocket 事件流地址，并从 sinceID 之后续传
+func eventsURL(base string, sinceID uint64) (string, error) {
+	u, err := url.Parse(strings.TrimSuffix(base, "/") + "/api/v1/ws")
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	case "http":
+		u.Scheme = "ws"
+	default:
+		return "", fmt.Errorf("unsupported peer url scheme %q", u.Scheme)
+	}
+	u.RawQuery = url.Values{"since_id": {strconv.FormatUint(sinceID, 10)}}.Encode()
+	return u.String(), nil
+}
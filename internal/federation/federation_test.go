@@ -0,0 +1,193 @@
+package federation
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/internal/server/v1"
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+	"github.com/r3labs/sse/v2"
+)
+
+// trackingListener 记录所有已接受的连接，用于模拟对端网络中断
+type trackingListener struct {
+	net.Listener
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func (l *trackingListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err == nil {
+		l.mu.Lock()
+		l.conns = append(l.conns, c)
+		l.mu.Unlock()
+	}
+	return c, err
+}
+
+func (l *trackingListener) closeAll() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, c := range l.conns {
+		c.Close()
+	}
+	l.conns = nil
+}
+
+func newWindowService(t *testing.T, viewerToken string) (*pkg.ConfigManager, *service.WindowService) {
+	t.Helper()
+	cm, err := pkg.NewConfigManagerWithLoader(&pkg.JSONConfigLoader{DataDir: t.TempDir(), FileName: "config.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm.GetConfig().ViewerToken = viewerToken
+	return cm, service.NewWindowService(service.NewEventBroker(sse.New(), nil, 0), nil, cm)
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func peerStatus(ws *service.WindowService, name string) string {
+	for _, p := range ws.Snapshot().Peers {
+		if p.Client == name {
+			return p.Status
+		}
+	}
+	return ""
+}
+
+func remoteTitle(ws *service.WindowService, key string) string {
+	ev, ok := ws.LookupClient(key)
+	if !ok {
+		return ""
+	}
+	return ev.Title
+}
+
+func TestFederation(t *testing.T) {
+	// 对端服务端：需要 ViewerToken 才能订阅
+	peerCM, peer := newWindowService(t, "alice-viewer")
+	peer.ReportLegacyWindow("laptop-id", "laptop", "GoLand", "linux")
+
+	h := v1.NewHandler(peerCM, peer)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/snapshot", h.HandleSnapshot)
+	mux.HandleFunc("/api/v1/ws", h.HandleWebSocket)
+
+	srv := httptest.NewUnstartedServer(mux)
+	ln := &trackingListener{Listener: srv.Listener}
+	srv.Listener = ln
+	srv.Start()
+	defer srv.Close()
+
+	_, local := newWindowService(t, "")
+	m := NewManager(pkg.FederationConfig{Peers: []pkg.PeerConfig{
+		{Name: "alice", URL: srv.URL, Token: "alice-viewer"},
+		{Name: "bad/name", URL: srv.URL},
+	}}, local)
+	if len(m.peers) != 1 {
+		t.Fatalf("peers = %d, want 1", len(m.peers))
+	}
+	m.Start()
+	defer m.Stop()
+
+	// 1. 对端的 "me" 与客户端以命名空间合并进本地快照
+	waitFor(t, "peer online", func() bool { return peerStatus(local, "alice") == service.StatusOnline })
+	waitFor(t, "snapshot merged", func() bool { return remoteTitle(local, "alice") == "GoLand" })
+	if got := remoteTitle(local, "alice/laptop-id"); got != "GoLand" {
+		t.Fatalf("alice/laptop-id title = %q", got)
+	}
+
+	// 2. 实时事件
+	peer.ReportLegacyWindow("laptop-id", "laptop", "Steam", "linux")
+	waitFor(t, "live event", func() bool { return remoteTitle(local, "alice") == "Steam" })
+
+	// 3. 网络中断时标记对端离线，之后自动重连并补上断开期间的变化
+	ln.closeAll()
+	waitFor(t, "peer offline", func() bool { return peerStatus(local, "alice") == service.StatusOffline })
+	peer.ReportLegacyWindow("laptop-id", "laptop", "Firefox", "linux")
+	waitFor(t, "peer reconnected", func() bool { return peerStatus(local, "alice") == service.StatusOnline })
+	waitFor(t, "resynced", func() bool { return remoteTitle(local, "alice") == "Firefox" })
+}
+
+func TestFederationRejectsWrongToken(t *testing.T) {
+	peerCM, peer := newWindowService(t, "secret")
+	h := v1.NewHandler(peerCM, peer)
+	srv := httptest.NewServer(http.HandlerFunc(h.HandleSnapshot))
+	defer srv.Close()
+
+	_, local := newWindowService(t, "")
+	m := NewManager(pkg.FederationConfig{}, local)
+	if _, err := m.fetchSnapshot(t.Context(), pkg.PeerConfig{Name: "bob", URL: srv.URL, Token: "wrong"}); err == nil {
+		t.Fatal("expected unauthorized error")
+	}
+}
+
+func TestApplyRemoteSkipsForwardedClients(t *testing.T) {
+	_, local := newWindowService(t, "")
+
+	// 对端自己联邦来的客户端与对端的 peer 事件不会被再次合并，避免互相订阅时形成环路
+	local.ApplyRemote("alice", &naniwosurunov1.WindowEvent{ClientId: "bob/me", Client: "bob", Title: "x", Type: "focus"})
+	local.ApplyRemote("alice", &naniwosurunov1.WindowEvent{ClientId: "bob", Client: "bob", Status: "online", Type: "peer"})
+	if snap := local.Snapshot(); len(snap.Clients) != 0 {
+		t.Fatalf("clients = %v, want none", snap.Clients)
+	}
+
+	// 对端快照中消失的客户端被移除
+	local.ReplaceRemote("alice", &naniwosurunov1.GetSnapshotResponse{Clients: []*naniwosurunov1.WindowEvent{{ClientId: "c1", Client: "laptop", Status: "online"}}})
+	local.ReplaceRemote("alice", &naniwosurunov1.GetSnapshotResponse{})
+	if _, ok := local.LookupClient("alice/c1"); ok {
+		t.Fatal("alice/c1 should be removed")
+	}
+}
+
+func TestApplyRemoteCustomStatus(t *testing.T) {
+	cm, _ := newWindowService(t, "")
+	broker := service.NewEventBroker(sse.New(), nil, 0)
+	local := service.NewWindowService(broker, nil, cm)
+	events, cancel := broker.Subscribe(16)
+	defer cancel()
+
+	// 1. 对端客户端首次出现
+	ev := &naniwosurunov1.WindowEvent{ClientId: "me", Client: "me", Title: "GoLand", Status: "online", Type: "focus"}
+	local.ApplyRemote("alice", ev)
+	<-events
+
+	// 2. 只设置手动状态，窗口与状态不变，仍然重新发布
+	ev.CustomStatus = &naniwosurunov1.CustomStatus{Text: "Lunch", Emoji: "🍜"}
+	local.ApplyRemote("alice", ev)
+	select {
+	case got := <-events:
+		if got.GetCustomStatus().GetText() != "Lunch" {
+			t.Fatalf("custom status = %v", got.CustomStatus)
+		}
+	default:
+		t.Fatal("custom status change was not published")
+	}
+	if got, _ := local.LookupClient("alice"); got.GetCustomStatus().GetText() != "Lunch" {
+		t.Fatalf("LookupClient(alice) custom status = %v", got.GetCustomStatus())
+	}
+
+	// 3. 清除手动状态同样重新发布；内容不变时不重复发布
+	ev.CustomStatus = nil
+	local.ApplyRemote("alice", ev)
+	local.ApplyRemote("alice", ev)
+	if n := len(events); n != 1 {
+		t.Fatalf("published %d events, want 1", n)
+	}
+}
@@ -0,0 +1,211 @@
+package history
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const defaultMaxEvents = 10000
+
+// Store 定义了事件历史的持久化行为，事件按 ID 单调递增追加
+type Store interface {
+	Append(ev *naniwosurunov1.WindowEvent) error
+	// Since 返回 ID 大于 id 的事件；若 id 之后的事件已被淘汰（断档），ok 为 false
+	Since(id uint64) (events []*naniwosurunov1.WindowEvent, ok bool)
+	// Recent 返回最近的至多 n 条事件，按 ID 升序
+	Recent(n int) []*naniwosurunov1.WindowEvent
+	LastID() uint64
+}
+
+// FileStore 实现了基于 JSON Lines 文件的事件历史，内存中保留最近 maxEvents 条用于快速查询
+type FileStore struct {
+	path      string
+	maxEvents int
+	events    []*naniwosurunov1.WindowEvent
+	lines     int // 文件中的行数，超过 maxEvents 的两倍时触发压缩
+	lastID    uint64
+	file      *os.File
+	mu        sync.RWMutex
+}
+
+var (
+	lineMarshaler   = protojson.MarshalOptions{UseProtoNames: true}
+	lineUnmarshaler = protojson.UnmarshalOptions{DiscardUnknown: true}
+)
+
+// NewFileStore 打开（或创建）历史文件并加载其中最近的事件
+func NewFileStore(path string, maxEvents int) (*FileStore, error) {
+	if maxEvents <= 0 {
+		maxEvents = defaultMaxEvents
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("无法创建历史目录: %w", err)
+	}
+
+	s := &FileStore{path: path, maxEvents: maxEvents}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("打开历史文件失败: %w", err)
+	}
+	s.file = f
+	return s, nil
+}
+
+func (s *FileStore) load() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取历史文件失败: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		s.lines++
+		ev := &naniwosurunov1.WindowEvent{}
+		if err := lineUnmarshaler.Unmarshal(scanner.Bytes(), ev); err != nil {
+			// 进程异常退出可能留下半行，跳过即可
+			log.Printf("跳过损坏的历史记录（第 %d 行）: %v", s.lines, err)
+			continue
+		}
+		s.push(ev)
+	}
+	return scanner.Err()
+}
+
+// push 将事件加入内存窗口，调用方需持有锁
+func (s *FileStore) push(ev *naniwosurunov1.WindowEvent) {
+	s.events = append(s.events, ev)
+	if len(s.events) > s.maxEvents {
+		s.events = append(s.events[:0:0], s.events[len(s.events)-s.maxEvents:]...)
+	}
+	if ev.Id > s.lastID {
+		s.lastID = ev.Id
+	}
+}
+
+func (s *FileStore) Append(ev *naniwosurunov1.WindowEvent) error {
+	data, err := lineMarshaler.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("序列化事件失败: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.push(ev)
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("写入历史文件失败: %w", err)
+	}
+	s.lines++
+	if s.lines > 2*s.maxEvents {
+		return s.compact()
+	}
+	return nil
+}
+
+// compact 用内存中保留的事件重写历史文件，防止文件无限增长，调用方需持有锁。
+// 任何一步失败都会删除临时文件并继续追加到原文件，下次追加时再重试压缩。
+func (s *FileStore) compact() error {
+	tmp := s.path + ".tmp"
+	if err := writeEvents(tmp, s.events); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("压缩历史文件失败: %w", err)
+	}
+
+	// Windows 上无法替换仍被打开的文件，因此先关闭，替换失败时重新打开原文件
+	s.file.Close()
+	renameErr := os.Rename(tmp, s.path)
+	if renameErr != nil {
+		os.Remove(tmp)
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("打开历史文件失败: %w", err)
+	}
+	s.file = f
+	if renameErr != nil {
+		return fmt.Errorf("压缩历史文件失败: %w", renameErr)
+	}
+	s.lines = len(s.events)
+	return nil
+}
+
+// writeEvents 把事件逐行写入 path 并落盘
+func writeEvents(path string, events []*naniwosurunov1.WindowEvent) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, ev := range events {
+		data, err := lineMarshaler.Marshal(ev)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		w.Write(data)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *FileStore) Since(id uint64) ([]*naniwosurunov1.WindowEvent, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if id == s.lastID {
+		return nil, true
+	}
+	// 订阅者记录的 ID 比服务端还新（例如历史文件被清空），同样视为断档
+	if id > s.lastID || len(s.events) == 0 || s.events[0].Id > id+1 {
+		return nil, false
+	}
+	i := sort.Search(len(s.events), func(i int) bool { return s.events[i].Id > id })
+	return append([]*naniwosurunov1.WindowEvent(nil), s.events[i:]...), true
+}
+
+func (s *FileStore) Recent(n int) []*naniwosurunov1.WindowEvent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if n <= 0 || n > len(s.events) {
+		n = len(s.events)
+	}
+	return append([]*naniwosurunov1.WindowEvent(nil), s.events[len(s.events)-n:]...)
+}
+
+func (s *FileStore) LastID() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastID
+}
+
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
@@ -0,0 +1,112 @@
+package history
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	store, err := NewFileStore(path, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for id := uint64(1); id <= 12; id++ {
+		if err := store.Append(&naniwosurunov1.WindowEvent{Id: id, Title: "t"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 1. 内存中只保留最近 5 条，续传点仍在窗口内
+	events, ok := store.Since(9)
+	if !ok || len(events) != 3 || events[0].Id != 10 {
+		t.Errorf("Since(9) = %v, %v", events, ok)
+	}
+
+	// 2. 已被淘汰的续传点视为断档
+	if _, ok := store.Since(3); ok {
+		t.Error("Since(3) should report a gap")
+	}
+
+	// 3. 已是最新时无需续传；比服务端还新的 ID 视为断档
+	if events, ok := store.Since(12); !ok || len(events) != 0 {
+		t.Errorf("Since(12) = %v, %v", events, ok)
+	}
+	if _, ok := store.Since(100); ok {
+		t.Error("Since(100) should report a gap")
+	}
+	store.Close()
+
+	// 4. 重新打开后恢复最后的 ID 与最近事件（文件已被压缩过）
+	reopened, err := NewFileStore(path, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if reopened.LastID() != 12 {
+		t.Errorf("LastID after reopen = %d, want 12", reopened.LastID())
+	}
+	if recent := reopened.Recent(2); len(recent) != 2 || recent[1].Id != 12 {
+		t.Errorf("Recent(2) = %v", recent)
+	}
+}
+
+func TestFileStoreCompactFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	store, err := NewFileStore(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// 1. 让临时文件无法创建（同名的非空目录）
+	tmp := path + ".tmp"
+	if err := os.MkdirAll(filepath.Join(tmp, "busy"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// 2. 压缩失败时返回错误，但事件仍追加到原文件
+	var failed bool
+	for id := uint64(1); id <= 6; id++ {
+		if err := store.Append(&naniwosurunov1.WindowEvent{Id: id, Title: "t"}); err != nil {
+			failed = true
+		}
+	}
+	if !failed {
+		t.Fatal("expected compaction error")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := len(splitLines(data)); lines != 6 {
+		t.Errorf("history file has %d lines, want 6", lines)
+	}
+
+	// 3. 临时路径恢复可用后，下一次追加完成压缩
+	if err := os.RemoveAll(tmp); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Append(&naniwosurunov1.WindowEvent{Id: 7, Title: "t"}); err != nil {
+		t.Fatalf("Append after recovery: %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	if lines := len(splitLines(data)); lines != 2 {
+		t.Errorf("compacted file has %d lines, want 2", lines)
+	}
+}
+
+func splitLines(data []byte) [][]byte {
+	var lines [][]byte
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(line) > 0 {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
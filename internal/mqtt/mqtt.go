@@ -0,0 +1,240 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+const (
+	defaultClientID        = "naniwosuruno"
+	defaultTopicPrefix     = "naniwosuruno"
+	defaultDiscoveryPrefix = "homeassistant"
+
+	payloadOnline  = "online"
+	payloadOffline = "offline"
+
+	qos = 1
+
+	// minBackoff 为事件订阅意外结束后重新订阅前的等待时间
+	minBackoff = time.Second
+)
+
+// EventStreamer 先发送全量快照再持续推送实时事件，由 WindowService 实现
+type EventStreamer interface {
+	StreamEvents(ctx context.Context, sinceID uint64, send func(*naniwosurunov1.WindowEvent) error) error
+}
+
+// Publisher 将每个客户端的最新状态以保留消息发布到 MQTT，并按需发送 Home Assistant 自动发现配置
+type Publisher struct {
+	cfg    pkg.MQTTConfig
+	client paho.Client
+
+	// states 保存每个客户端的最新状态，(重新)连接后全部重发：clean session 下连接建立前发布的消息会被丢弃，
+	// Broker 重启也可能丢失保留消息
+	states map[string]*naniwosurunov1.WindowEvent
+	mu     sync.Mutex
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewPublisher 根据配置创建 Publisher，服务端可用性主题的遗嘱消息为 "offline"
+func NewPublisher(cfg pkg.MQTTConfig) *Publisher {
+	if cfg.ClientID == "" {
+		cfg.ClientID = defaultClientID
+	}
+	if cfg.TopicPrefix == "" {
+		cfg.TopicPrefix = defaultTopicPrefix
+	}
+	if cfg.DiscoveryPrefix == "" {
+		cfg.DiscoveryPrefix = defaultDiscoveryPrefix
+	}
+
+	p := &Publisher{
+		cfg:    cfg,
+		states: make(map[string]*naniwosurunov1.WindowEvent),
+	}
+
+	opts := paho.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetWill(p.availabilityTopic(), payloadOffline, qos, true).
+		SetAutoReconnect(true).
+		// 启动时 Broker 不可用也在后台持续重试，期间发布的消息会在连上后发送
+		SetConnectRetry(true).
+		SetOnConnectHandler(p.onConnect).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			log.Printf("MQTT 连接断开: %v", err)
+		})
+	p.client = paho.NewClient(opts)
+	return p
+}
+
+func (p *Publisher) availabilityTopic() string {
+	return p.cfg.TopicPrefix + "/status"
+}
+
+func (p *Publisher) stateTopic(slug string) string {
+	return fmt.Sprintf("%s/%s/state", p.cfg.TopicPrefix, slug)
+}
+
+// onConnect 在每次（重新）连接后标记服务端在线，并重发所有客户端的发现配置与最新状态
+func (p *Publisher) onConnect(c paho.Client) {
+	log.Printf("MQTT 已连接: %s", p.cfg.Broker)
+	c.Publish(p.availabilityTopic(), qos, true, payloadOnline)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for slug, ev := range p.states {
+		p.sendLocked(slug, ev, true)
+	}
+}
+
+// Start 连接 Broker 并开始发布 events 中的状态，连接在后台进行，不会阻塞
+func (p *Publisher) Start(events EventStreamer) {
+	p.client.Connect()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+	go p.run(ctx, events)
+}
+
+// Stop 停止发布并主动断开连接，断开前将服务端可用性置为 offline
+func (p *Publisher) Stop() {
+	p.cancel()
+	<-p.done
+	if p.client.IsConnected() {
+		p.client.Publish(p.availabilityTopic(), qos, true, payloadOffline).WaitTimeout(time.Second)
+	}
+	p.client.Disconnect(250)
+}
+
+func (p *Publisher) run(ctx context.Context, events EventStreamer) {
+	defer close(p.done)
+
+	var lastID uint64
+	for {
+		err := events.StreamEvents(ctx, lastID, func(ev *naniwosurunov1.WindowEvent) error {
+			p.publish(ev)
+			lastID = ev.Id
+			return nil
+		})
+		if ctx.Err() != nil || errors.Is(err, service.ErrClosed) {
+			return
+		}
+		if !errors.Is(err, service.ErrSubscriberTooSlow) {
+			log.Printf("MQTT 事件订阅结束: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(minBackoff):
+		}
+	}
+}
+
+// publish 记录客户端的最新状态并发布，首次出现的客户端同时发送发现配置。
+// 联邦对端的连接状态不是客户端，不发布，避免 Home Assistant 为其创建设备
+func (p *Publisher) publish(ev *naniwosurunov1.WindowEvent) {
+	if ev.Type == service.EventTypePeer {
+		return
+	}
+	slug := Slug(ev.ClientId)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, known := p.states[slug]
+	p.states[slug] = ev
+	if p.client.IsConnectionOpen() {
+		p.sendLocked(slug, ev, !known)
+	}
+}
+
+// sendLocked 以保留消息发布状态，状态主题的内容与 SSE 事件的 JSON 相同
+func (p *Publisher) sendLocked(slug string, ev *naniwosurunov1.WindowEvent, announce bool) {
+	if announce && p.cfg.Discovery {
+		p.announce(slug, ev)
+	}
+
+	payload, err := service.MarshalEvent(ev)
+	if err != nil {
+		log.Printf("序列化 MQTT 状态失败: %v", err)
+		return
+	}
+	p.client.Publish(p.stateTopic(slug), qos, true, payload)
+}
+
+// announce 为客户端发送 Home Assistant 发现配置：是否在用（binary_sensor）、当前应用与状态（sensor）
+func (p *Publisher) announce(slug string, ev *naniwosurunov1.WindowEvent) {
+	name := ev.Client
+	if name == "" {
+		name = ev.ClientId
+	}
+	nodeID := "naniwosuruno_" + slug
+	device := map[string]interface{}{
+		"identifiers":  []string{nodeID},
+		"name":         name,
+		"manufacturer": "Naniwosuruno",
+	}
+
+	entities := []struct {
+		component string
+		object    string
+		config    map[string]interface{}
+	}{
+		{"binary_sensor", "in_use", map[string]interface{}{
+			"name":           "In use",
+			"value_template": "{{ 'ON' if value_json.status == 'online' else 'OFF' }}",
+			"device_class":   "occupancy",
+		}},
+		{"sensor", "current_app", map[string]interface{}{
+			"name":                  "Current app",
+			"value_template":        "{{ value_json.title }}",
+			"json_attributes_topic": p.stateTopic(slug),
+			"icon":                  "mdi:application",
+		}},
+		{"sensor", "status", map[string]interface{}{
+			"name":           "Status",
+			"value_template": "{{ value_json.status }}",
+			"icon":           "mdi:account-clock",
+		}},
+	}
+
+	for _, e := range entities {
+		e.config["unique_id"] = nodeID + "_" + e.object
+		e.config["state_topic"] = p.stateTopic(slug)
+		e.config["availability_topic"] = p.availabilityTopic()
+		e.config["device"] = device
+
+		payload, err := json.Marshal(e.config)
+		if err != nil {
+			continue
+		}
+		topic := fmt.Sprintf("%s/%s/%s/%s/config", p.cfg.DiscoveryPrefix, e.component, nodeID, e.object)
+		p.client.Publish(topic, qos, true, payload)
+	}
+}
+
+var slugPattern = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// Slug 将客户端 ID 转换为可用于 MQTT 主题与 Home Assistant 对象 ID 的形式
+func Slug(id string) string {
+	if s := slugPattern.ReplaceAllString(id, "_"); s != "" {
+		return s
+	}
+	return "unknown"
+}
@@ -0,0 +1,209 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+	"github.com/r3labs/sse/v2"
+)
+
+type message struct {
+	topic   string
+	payload string
+	retain  bool
+}
+
+// fakeBroker 是一个仅实现 MQTT 3.1.1 中 CONNECT、PUBLISH、PINGREQ 与 DISCONNECT 的最小 Broker
+type fakeBroker struct {
+	ln       net.Listener
+	will     chan message
+	messages []message
+	mu       sync.Mutex
+}
+
+func newFakeBroker(t *testing.T) *fakeBroker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &fakeBroker{ln: ln, will: make(chan message, 1)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return b
+}
+
+func (b *fakeBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		header, body, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		switch header >> 4 {
+		case 1: // CONNECT
+			flags := body[7]
+			rest := body[10:]
+			_, rest = readString(rest) // client id
+			if flags&0x04 != 0 {
+				var topic, payload string
+				topic, rest = readString(rest)
+				payload, _ = readString(rest)
+				b.will <- message{topic: topic, payload: payload, retain: flags&0x20 != 0}
+			}
+			conn.Write([]byte{0x20, 0x02, 0x00, 0x00})
+		case 3: // PUBLISH
+			topic, rest := readString(body)
+			if (header>>1)&0x03 > 0 {
+				conn.Write([]byte{0x40, 0x02, rest[0], rest[1]})
+				rest = rest[2:]
+			}
+			b.mu.Lock()
+			b.messages = append(b.messages, message{topic: topic, payload: string(rest), retain: header&0x01 != 0})
+			b.mu.Unlock()
+		case 12: // PINGREQ
+			conn.Write([]byte{0xd0, 0x00})
+		case 14: // DISCONNECT
+			return
+		}
+	}
+}
+
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(b&0x7f) * multiplier
+		if b&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+	body := make([]byte, length)
+	_, err = io.ReadFull(r, body)
+	return header, body, err
+}
+
+func readString(b []byte) (string, []byte) {
+	n := int(binary.BigEndian.Uint16(b))
+	return string(b[2 : 2+n]), b[2+n:]
+}
+
+// waitTopic 等待指定主题上出现满足 match 的消息，match 为 nil 时匹配任意消息
+func (b *fakeBroker) waitTopic(t *testing.T, topic string, match func(message) bool) message {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		b.mu.Lock()
+		for _, m := range b.messages {
+			if m.topic == topic && (match == nil || match(m)) {
+				b.mu.Unlock()
+				return m
+			}
+		}
+		b.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", topic)
+	return message{}
+}
+
+func payloadIs(payload string) func(message) bool {
+	return func(m message) bool { return m.payload == payload }
+}
+
+func TestPublisher(t *testing.T) {
+	fb := newFakeBroker(t)
+
+	dir := t.TempDir()
+	cm, err := pkg.NewConfigManagerWithLoader(&pkg.JSONConfigLoader{DataDir: dir, FileName: "config.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := service.NewEventBroker(sse.New(), nil, 0)
+	ws := service.NewWindowService(broker, nil, cm)
+
+	p := NewPublisher(pkg.MQTTConfig{Broker: "tcp://" + fb.ln.Addr().String(), Discovery: true})
+	p.Start(ws)
+
+	// 1. 遗嘱消息为保留的 offline，连接后服务端可用性置为 online
+	select {
+	case will := <-fb.will:
+		if will.topic != "naniwosuruno/status" || will.payload != "offline" || !will.retain {
+			t.Fatalf("unexpected will: %+v", will)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no CONNECT received")
+	}
+	if m := fb.waitTopic(t, "naniwosuruno/status", payloadIs("online")); !m.retain {
+		t.Fatalf("unexpected availability: %+v", m)
+	}
+
+	// 2. 启动时的快照会发布 "me" 的状态与发现配置
+	disc := fb.waitTopic(t, "homeassistant/binary_sensor/naniwosuruno_me/in_use/config", nil)
+	var cfg map[string]interface{}
+	if err := json.Unmarshal([]byte(disc.payload), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg["state_topic"] != "naniwosuruno/me/state" || cfg["unique_id"] != "naniwosuruno_me_in_use" || !disc.retain {
+		t.Fatalf("unexpected discovery config: %s", disc.payload)
+	}
+	fb.waitTopic(t, "naniwosuruno/me/state", nil)
+
+	// 3. 客户端上报后状态以保留消息发布到 naniwosuruno/<client>/state；联邦对端的连接状态不发布
+	ws.SetPeerHealth("alice", true)
+	ws.ReportLegacyWindow("laptop 1", "Laptop", "GoLand", "linux")
+	state := fb.waitTopic(t, "naniwosuruno/laptop_1/state", nil)
+	if !state.retain || !strings.Contains(state.payload, `"title":"GoLand"`) {
+		t.Fatalf("unexpected state: %+v", state)
+	}
+	fb.mu.Lock()
+	for _, m := range fb.messages {
+		if strings.Contains(m.topic, "alice") {
+			t.Errorf("peer event published to %s", m.topic)
+		}
+	}
+	fb.mu.Unlock()
+
+	// 4. 正常停止时将可用性置为 offline
+	p.Stop()
+	fb.waitTopic(t, "naniwosuruno/status", payloadIs("offline"))
+}
+
+func TestSlug(t *testing.T) {
+	cases := map[string]string{
+		"me":                 "me",
+		"3f2b6c1e-aaaa-bbbb": "3f2b6c1e-aaaa-bbbb",
+		"My Laptop/#":        "My_Laptop_",
+		"":                   "unknown",
+	}
+	for in, want := range cases {
+		if got := Slug(in); got != want {
+			t.Errorf("Slug(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+// Package relay 将本机服务端处理过的事件转发到上游实例，使位于 NAT 后的服务端无需入站端口也能对外展示状态
+package relay
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/google/uuid"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1/naniwosurunov1connect"
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+	"github.com/nhirsama/Naniwosuruno/pkg/auth"
+)
+
+const (
+	// maxBuffer 为上游不可达时最多缓存的事件数，超出后丢弃缓存，恢复后改为发送一份全量快照
+	maxBuffer = 10000
+	maxBatch  = 100
+
+	// keepaliveInterval 需明显小于上游的客户端超时（360 秒）
+	keepaliveInterval = time.Minute
+	minBackoff        = time.Second
+	maxBackoff        = time.Minute
+	requestTimeout    = 10 * time.Second
+)
+
+// errOverflow 表示缓存已满，需要从快照重新开始订阅
+var errOverflow = errors.New("relay buffer full")
+
+// EventStreamer 提供本机的事件流，由 WindowService 实现
+type EventStreamer interface {
+	StreamEvents(ctx context.Context, sinceID uint64, send func(*naniwosurunov1.WindowEvent) error) error
+}
+
+// Relay 订阅本机事件并按顺序批量发送到上游的 RelayEvents。
+// 中继使用配置中的 ClientID 与 PrivateKey 通过挑战签名认证，上游需将其公钥加入 Clients。
+type Relay struct {
+	upstream      string
+	clientID      string
+	authenticator auth.ClientAuthenticator
+	authClient    naniwosurunov1connect.AuthServiceClient
+	windowClient  naniwosurunov1connect.WindowServiceClient
+	token         string
+
+	mu         sync.Mutex
+	buffer     []*naniwosurunov1.WindowEvent
+	resync     bool   // 缓存内容为全量快照，尚未送达上游
+	generation uint64 // 缓存被快照替换的次数，用于识别过期的发送结果
+	wake       chan struct{}
+
+	keepalive time.Duration
+	retryBase time.Duration
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// New 创建中继，配置中没有密钥时会生成一对并保存，同时打印需要添加到上游的公钥
+func New(upstream string, cfg *pkg.AppConfig) (*Relay, error) {
+	if err := ensureIdentity(cfg); err != nil {
+		return nil, err
+	}
+	authenticator, err := auth.NewClientAuthenticatorFromBase64(cfg.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("初始化中继身份失败: %w", err)
+	}
+
+	upstream = strings.TrimSuffix(upstream, "/")
+	httpClient := &http.Client{Timeout: requestTimeout}
+	return &Relay{
+		upstream:      upstream,
+		clientID:      cfg.ClientID,
+		authenticator: authenticator,
+		authClient:    naniwosurunov1connect.NewAuthServiceClient(httpClient, upstream),
+		windowClient:  naniwosurunov1connect.NewWindowServiceClient(httpClient, upstream),
+		wake:          make(chan struct{}, 1),
+		keepalive:     keepaliveInterval,
+		retryBase:     minBackoff,
+	}, nil
+}
+
+func ensureIdentity(cfg *pkg.AppConfig) error {
+	if cfg.PrivateKey != "" && cfg.ClientID != "" {
+		return nil
+	}
+
+	log.Println("未配置中继身份，生成新的密钥对...")
+	pubKey, privKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return fmt.Errorf("生成密钥失败: %w", err)
+	}
+	cfg.PrivateKey = base64.StdEncoding.EncodeToString(privKey)
+	if cfg.ClientID == "" {
+		cfg.ClientID = uuid.New().String()
+	}
+	if err := pkg.SaveConfig(cfg); err != nil {
+		return fmt.Errorf("保存配置失败: %w", err)
+	}
+
+	fmt.Println("==================================================")
+	fmt.Println("中继密钥已生成，请将以下条目加入上游配置的 Clients：")
+	fmt.Printf("Client ID: %s\n", cfg.ClientID)
+	fmt.Printf("Public Key: %s\n", base64.StdEncoding.EncodeToString(pubKey))
+	fmt.Println("==================================================")
+	return nil
+}
+
+// Start 开始订阅本机事件并转发到上游
+func (r *Relay) Start(events EventStreamer) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.wg.Add(2)
+	go func() {
+		defer r.wg.Done()
+		r.follow(ctx, events)
+	}()
+	go func() {
+		defer r.wg.Done()
+		r.run(ctx)
+	}()
+	log.Printf("中继已启动，上游: %s", r.upstream)
+}
+
+func (r *Relay) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// Pending 返回尚未送达上游的事件数
+func (r *Relay) Pending() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.buffer)
+}
+
+// follow 将本机事件写入缓存；首次订阅、断档过大或缓存溢出时事件流以快照开头，此时用快照替换整个缓存
+func (r *Relay) follow(ctx context.Context, events EventStreamer) {
+	var lastID uint64
+	for {
+		first := true
+		err := events.StreamEvents(ctx, lastID, func(ev *naniwosurunov1.WindowEvent) error {
+			if first && ev.Type == service.EventTypeSnapshot {
+				r.reset()
+			}
+			first = false
+			if !r.enqueue(ev) {
+				lastID = 0
+				return errOverflow
+			}
+			lastID = max(lastID, ev.Id)
+			return nil
+		})
+		if ctx.Err() != nil || errors.Is(err, service.ErrClosed) {
+			return
+		}
+		if errors.Is(err, errOverflow) {
+			log.Printf("中继缓存已满 (%d 条)，上游恢复后将改为发送全量快照", maxBuffer)
+		}
+		// 溢出时缓存要等到快照到来才会被替换，稍作等待避免空转
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(minBackoff):
+		}
+	}
+}
+
+// reset 清空缓存，之后写入的事件构成一份全量快照
+func (r *Relay) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buffer = nil
+	r.resync = true
+	r.generation++
+	r.notify()
+}
+
+// enqueue 缓存一条需要转发的事件，缓存已满时返回 false。合成的 "me" 由上游自行推导，联邦来的事件不再转发
+func (r *Relay) enqueue(ev *naniwosurunov1.WindowEvent) bool {
+	if ev.ClientId == service.PrimaryClientName || service.IsRemote(ev) {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.buffer) >= maxBuffer {
+		return false
+	}
+	r.buffer = append(r.buffer, ev)
+	r.notify()
+	return true
+}
+
+// notify 唤醒发送协程，调用方需持有锁
+func (r *Relay) notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next 取出下一批待发送的事件；快照必须在一个批次内送达，否则上游会把尚未发送的客户端标记为离线
+func (r *Relay) next() ([]*naniwosurunov1.WindowEvent, bool, uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := len(r.buffer)
+	if !r.resync {
+		n = min(n, maxBatch)
+	}
+	return r.buffer[:n:n], r.resync, r.generation
+}
+
+// ack 丢弃已送达的事件；发送期间缓存被快照替换时结果作废
+func (r *Relay) ack(generation uint64, n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if generation != r.generation {
+		return
+	}
+	r.buffer = r.buffer[n:]
+	r.resync = false
+}
+
+func (r *Relay) run(ctx context.Context) {
+	backoff := r.retryBase
+	up := true
+	var lastSent time.Time
+	for {
+		batch, resync, generation := r.next()
+		if len(batch) == 0 && !resync && time.Since(lastSent) < r.keepalive {
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.wake:
+			case <-time.After(r.keepalive - time.Since(lastSent)):
+			}
+			continue
+		}
+
+		if err := r.send(ctx, batch, resync); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if up {
+				log.Printf("上游 %s 不可达，事件将暂存在本机: %v", r.upstream, err)
+				up = false
+			}
+			// 加入随机抖动，避免上游重启后所有中继同时重连
+			wait := backoff/2 + rand.N(backoff/2+1)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+			backoff = min(backoff*2, maxBackoff)
+			continue
+		}
+
+		if !up {
+			log.Printf("已重新连接上游 %s，待发送 %d 条事件", r.upstream, r.Pending())
+			up = true
+		}
+		backoff = r.retryBase
+		lastSent = time.Now()
+		r.ack(generation, len(batch))
+	}
+}
+
+// send 发送一批事件，会话过期时重新认证并重试一次
+func (r *Relay) send(ctx context.Context, batch []*naniwosurunov1.WindowEvent, resync bool) error {
+	if r.token == "" {
+		if err := r.authenticate(ctx); err != nil {
+			return err
+		}
+	}
+
+	req := connect.NewRequest(&naniwosurunov1.RelayEventsRequest{Events: batch, Resync: resync})
+	req.Header().Set("Authorization", "Bearer "+r.token)
+	_, err := r.windowClient.RelayEvents(ctx, req)
+	if connect.CodeOf(err) == connect.CodeUnauthenticated {
+		if err := r.authenticate(ctx); err != nil {
+			return err
+		}
+		req.Header().Set("Authorization", "Bearer "+r.token)
+		_, err = r.windowClient.RelayEvents(ctx, req)
+	}
+	return err
+}
+
+// authenticate 使用与客户端相同的挑战签名流程获取会话令牌
+func (r *Relay) authenticate(ctx context.Context) error {
+	r.token = ""
+	res, err := r.authClient.CreateChallenge(ctx, connect.NewRequest(&naniwosurunov1.CreateChallengeRequest{
+		ClientId: r.clientID,
+	}))
+	if err != nil {
+		return fmt.Errorf("create challenge failed: %w", err)
+	}
+
+	sig, err := r.authenticator.SignChallenge(res.Msg.Challenge)
+	if err != nil {
+		return fmt.Errorf("sign failed: %w", err)
+	}
+
+	verifyRes, err := r.authClient.VerifyChallenge(ctx, connect.NewRequest(&naniwosurunov1.VerifyChallengeRequest{
+		ClientId:  r.clientID,
+		Signature: sig,
+	}))
+	if err != nil {
+		return fmt.Errorf("verify failed: %w", err)
+	}
+	r.token = verifyRes.Msg.Token
+	return nil
+}
@@ -0,0 +1,115 @@
+package relay
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1/naniwosurunov1connect"
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+	"github.com/nhirsama/Naniwosuruno/pkg/auth"
+	"github.com/r3labs/sse/v2"
+)
+
+type staticKeys map[string]ed25519.PublicKey
+
+func (k staticKeys) GetClientPublicKey(clientID string) ([]byte, error) {
+	if key, ok := k[clientID]; ok {
+		return key, nil
+	}
+	return nil, errors.New("client not found")
+}
+
+func newWindowService(t *testing.T, authenticator auth.StatefulAuthenticator, relays ...string) *service.WindowService {
+	t.Helper()
+	cm, err := pkg.NewConfigManagerWithLoader(&pkg.JSONConfigLoader{DataDir: t.TempDir(), FileName: "config.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range relays {
+		cm.GetConfig().Clients = append(cm.GetConfig().Clients, pkg.ClientConfig{ID: id, Name: id, Relay: true})
+	}
+	return service.NewWindowService(service.NewEventBroker(sse.New(), nil, 0), authenticator, cm)
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRelayBuffersWhileUpstreamDown(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1. 上游：真实的认证与窗口服务，down 为 true 时模拟不可达
+	authenticator := auth.NewStatefulAuthenticator(staticKeys{"home": pub})
+	upstream := newWindowService(t, authenticator, "home")
+	mux := http.NewServeMux()
+	mux.Handle(naniwosurunov1connect.NewAuthServiceHandler(service.NewAuthService(authenticator)))
+	mux.Handle(naniwosurunov1connect.NewWindowServiceHandler(upstream))
+	var down atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	// 2. 下游：中继启动前已有的客户端通过首次快照同步到上游
+	home := newWindowService(t, nil)
+	home.ReportLegacyWindow("laptop-id", "laptop", "GoLand", "linux")
+
+	r, err := New(srv.URL, &pkg.AppConfig{ClientID: "home", PrivateKey: base64.StdEncoding.EncodeToString(priv)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.retryBase = 20 * time.Millisecond
+	r.Start(home)
+	defer r.Stop()
+
+	waitFor(t, "snapshot relayed", func() bool {
+		ev, ok := upstream.LookupClient("laptop")
+		return ok && ev.Title == "GoLand" && ev.Status == service.StatusOnline
+	})
+	if me, _ := upstream.LookupClient("me"); me.Title != "GoLand" || me.Source != "laptop" {
+		t.Fatalf("upstream me = %q from %q, want GoLand from laptop", me.Title, me.Source)
+	}
+
+	// 3. 上游不可达期间的事件暂存在本机
+	down.Store(true)
+	home.ReportLegacyWindow("laptop-id", "laptop", "Firefox", "linux")
+	home.ReportLegacyWindow("desktop-id", "desktop", "Steam", "windows")
+	waitFor(t, "events buffered", func() bool { return r.Pending() == 2 })
+	if ev, _ := upstream.LookupClient("laptop"); ev.Title != "GoLand" {
+		t.Fatalf("upstream saw %q while down", ev.Title)
+	}
+
+	// 4. 恢复后按顺序补发，上游据此重新选出 "me"
+	down.Store(false)
+	waitFor(t, "buffered events delivered", func() bool {
+		ev, ok := upstream.LookupClient("desktop")
+		return ok && ev.Title == "Steam" && r.Pending() == 0
+	})
+	if ev, _ := upstream.LookupClient("laptop"); ev.Title != "Firefox" {
+		t.Errorf("upstream laptop = %q, want Firefox", ev.Title)
+	}
+	if me, _ := upstream.LookupClient("me"); me.Title != "Steam" || me.Source != "desktop" {
+		t.Errorf("upstream me = %q from %q, want Steam from desktop", me.Title, me.Source)
+	}
+}
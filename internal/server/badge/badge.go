@@ -0,0 +1,198 @@
+package badge
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/internal/server/common"
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+const (
+	defaultLabel        = "currently using"
+	defaultLabelColor   = "#555"
+	defaultOnlineColor  = "#4c1"
+	defaultIdleColor    = "#dfb317"
+	defaultOfflineColor = "#9f9f9f"
+
+	// 徽章常被 GitHub 等第三方代理缓存，缓存时间需要足够短才能及时反映状态
+	cacheControl = "public, max-age=30"
+)
+
+// ClientLookup 按客户端 ID、名称或 "me" 查询当前状态，由 WindowService 实现
+type ClientLookup interface {
+	LookupClient(key string) (*naniwosurunov1.WindowEvent, bool)
+}
+
+type Handler struct {
+	ConfigManager *pkg.ConfigManager
+	Clients       ClientLookup
+}
+
+func NewHandler(cm *pkg.ConfigManager, clients ClientLookup) *Handler {
+	return &Handler{
+		ConfigManager: cm,
+		Clients:       clients,
+	}
+}
+
+// badge 是渲染一个徽章所需的全部信息
+type badge struct {
+	Style      string
+	Label      string
+	Message    string
+	LabelColor string
+	Color      string
+}
+
+// ServeHTTP 处理 /badge/{client|me}.svg 与 /badge/{client|me}.json（shields.io endpoint 格式）
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !common.AuthorizeViewer(h.ConfigManager, r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/badge/")
+	var key, format string
+	switch {
+	case strings.HasSuffix(name, ".svg"):
+		key, format = strings.TrimSuffix(name, ".svg"), "svg"
+	case strings.HasSuffix(name, ".json"):
+		key, format = strings.TrimSuffix(name, ".json"), "json"
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	b := h.build(key, r)
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"schemaVersion": 1,
+			"label":         b.Label,
+			"message":       b.Message,
+			"color":         strings.TrimPrefix(b.Color, "#"),
+			"labelColor":    strings.TrimPrefix(b.LabelColor, "#"),
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
+	_, _ = w.Write([]byte(renderSVG(b)))
+}
+
+// build 根据客户端状态、配置与查询参数确定徽章内容；未知客户端按离线处理，避免泄露客户端是否存在
+func (h *Handler) build(key string, r *http.Request) badge {
+	cfg := pkg.BadgeConfig{}
+	if h.ConfigManager != nil {
+		cfg = h.ConfigManager.GetConfig().Badge
+	}
+	q := r.URL.Query()
+
+	b := badge{
+		Style:      firstNonEmpty(q.Get("style"), cfg.Style, "flat"),
+		Label:      firstNonEmpty(q.Get("label"), cfg.Label, defaultLabel),
+		LabelColor: firstNonEmpty(q.Get("label_color"), cfg.LabelColor, defaultLabelColor),
+	}
+
+	ev, ok := h.Clients.LookupClient(key)
+	status := service.StatusOffline
+	if ok {
+		status = ev.Status
+	}
+
+	switch status {
+	case service.StatusOnline:
+		b.Message = ev.Title
+		// color 为 online_color 的简写
+		b.Color = firstNonEmpty(q.Get("online_color"), q.Get("color"), cfg.OnlineColor, defaultOnlineColor)
+	case service.StatusIdle, service.StatusPaused, service.StatusBusy:
+		b.Message = status
+		b.Color = firstNonEmpty(q.Get("idle_color"), cfg.IdleColor, defaultIdleColor)
+	default:
+		b.Message = "offline"
+		b.Color = firstNonEmpty(q.Get("offline_color"), cfg.OfflineColor, defaultOfflineColor)
+	}
+	// 手动状态代替窗口标题显示，颜色仍反映在线状态
+	if ok {
+		if custom := service.CustomStatusText(ev.CustomStatus); custom != "" {
+			b.Message = custom
+		}
+	}
+	if strings.TrimSpace(b.Message) == "" {
+		b.Message = "unknown"
+	}
+	return b
+}
+
+// renderSVG 生成与 shields.io 相近的徽章，文字宽度按字符粗略估算
+func renderSVG(b badge) string {
+	labelWidth := textWidth(b.Label) + 10
+	messageWidth := textWidth(b.Message) + 10
+	width := labelWidth + messageWidth
+
+	radius := 3
+	if b.Style == "flat-square" {
+		radius = 0
+	}
+
+	label := html.EscapeString(b.Label)
+	message := html.EscapeString(b.Message)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`, width, label, message)
+	fmt.Fprintf(&sb, `<title>%s: %s</title>`, label, message)
+	if b.Style != "flat-square" {
+		sb.WriteString(`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+	}
+	fmt.Fprintf(&sb, `<clipPath id="r"><rect width="%d" height="20" rx="%d" fill="#fff"/></clipPath>`, width, radius)
+	sb.WriteString(`<g clip-path="url(#r)">`)
+	fmt.Fprintf(&sb, `<rect width="%d" height="20" fill="%s"/>`, labelWidth, html.EscapeString(b.LabelColor))
+	fmt.Fprintf(&sb, `<rect x="%d" width="%d" height="20" fill="%s"/>`, labelWidth, messageWidth, html.EscapeString(b.Color))
+	if b.Style != "flat-square" {
+		fmt.Fprintf(&sb, `<rect width="%d" height="20" fill="url(#s)"/>`, width)
+	}
+	sb.WriteString(`</g>`)
+	sb.WriteString(`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`)
+	fmt.Fprintf(&sb, `<text x="%d" y="14" fill="#010101" fill-opacity=".3">%s</text><text x="%d" y="13">%s</text>`,
+		labelWidth/2, label, labelWidth/2, label)
+	fmt.Fprintf(&sb, `<text x="%d" y="14" fill="#010101" fill-opacity=".3">%s</text><text x="%d" y="13">%s</text>`,
+		labelWidth+messageWidth/2, message, labelWidth+messageWidth/2, message)
+	sb.WriteString(`</g></svg>`)
+	return sb.String()
+}
+
+// textWidth 估算 11px Verdana 下文字的像素宽度，宽字符（如中日文）按两倍计算
+func textWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		if utf8.RuneLen(r) > 2 {
+			width += 12
+		} else {
+			width += 7
+		}
+	}
+	return width
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package badge
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+type staticLookup map[string]*naniwosurunov1.WindowEvent
+
+func (l staticLookup) LookupClient(key string) (*naniwosurunov1.WindowEvent, bool) {
+	ev, ok := l[key]
+	return ev, ok
+}
+
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	cm, err := pkg.NewConfigManagerWithLoader(&pkg.JSONConfigLoader{DataDir: t.TempDir(), FileName: "config.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewHandler(cm, staticLookup{
+		"me":     {Client: "me", Title: "<GoLand>", Status: "online"},
+		"laptop": {Client: "laptop", Title: "Firefox", Status: "idle"},
+	})
+}
+
+func TestSVGBadge(t *testing.T) {
+	h := newTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/badge/me.svg?label=using", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "image/svg+xml") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != cacheControl {
+		t.Errorf("Cache-Control = %q", cc)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "&lt;GoLand&gt;") || strings.Contains(body, "<GoLand>") {
+		t.Error("title should be rendered escaped")
+	}
+	if !strings.Contains(body, ">using<") || !strings.Contains(body, defaultOnlineColor) {
+		t.Error("label override or online color missing")
+	}
+
+	// 未知客户端按离线渲染
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/badge/unknown.svg", nil))
+	if !strings.Contains(rec.Body.String(), ">offline<") {
+		t.Error("unknown client should render as offline")
+	}
+}
+
+func TestShieldsEndpoint(t *testing.T) {
+	h := newTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/badge/laptop.json", nil))
+
+	var res map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res["schemaVersion"] != float64(1) || res["message"] != "idle" || res["color"] != strings.TrimPrefix(defaultIdleColor, "#") {
+		t.Errorf("unexpected endpoint response %v", res)
+	}
+}
+
+func TestColorOverrides(t *testing.T) {
+	h := newTestHandler(t)
+	cases := map[string]string{
+		"/badge/me.svg?color=%23123":              "#123",
+		"/badge/me.svg?online_color=%23234":       "#234",
+		"/badge/laptop.svg?idle_color=%23345":     "#345",
+		"/badge/unknown.svg?offline_color=%23456": "#456",
+		"/badge/laptop.svg?offline_color=%23456":  defaultIdleColor,
+		"/badge/unknown.svg?online_color=%23234":  defaultOfflineColor,
+	}
+	for target, want := range cases {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if !strings.Contains(rec.Body.String(), `fill="`+want+`"`) {
+			t.Errorf("%s: color %s missing", target, want)
+		}
+	}
+}
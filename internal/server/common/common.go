@@ -20,20 +20,38 @@ func GetTokenFromRequest(r *http.Request) string {
 	return ""
 }
 
-func ServeSSE(sseServer *sse.Server, w http.ResponseWriter, r *http.Request) {
+// ServeSSE 提供 SSE 事件流：新连接先收到一帧快照，携带 Last-Event-ID 重连时从历史中续传
+func ServeSSE(sseServer *sse.Server, resumer EventResumer, w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Last-Event-ID")
 
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusOK)
 		return
 	}
-	sseServer.ServeHTTP(w, r)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported!", http.StatusInternalServerError)
+		return
+	}
+	lastEventID := r.Header.Get("Last-Event-ID")
+	sseServer.ServeHTTP(&resumeWriter{
+		ResponseWriter: w,
+		flusher:        flusher,
+		prelude:        func() []byte { return ssePrelude(resumer, lastEventID) },
+	}, r)
+}
+
+// UpdateReporter 接收来自旧版 API 的窗口更新，由 WindowService 实现，
+// 以便 v0 客户端与 v1 客户端共享同一套状态机与事件格式
+type UpdateReporter interface {
+	ReportLegacyWindow(clientID, name, title, os string)
 }
 
-// ProcessUpdate 处理具体的窗口信息更新逻辑，并分发到 SSE 流中
-func ProcessUpdate(sseServer *sse.Server, w http.ResponseWriter, r *http.Request, clientName string) {
+// ProcessUpdate 处理具体的窗口信息更新逻辑，并交由 reporter 更新状态、分发事件
+func ProcessUpdate(reporter UpdateReporter, w http.ResponseWriter, r *http.Request, clientID, clientName string) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -52,9 +70,7 @@ func ProcessUpdate(sseServer *sse.Server, w http.ResponseWriter, r *http.Request
 	}
 	_ = json.Unmarshal(body, &updateData)
 
-	// 构造统一格式的消息负载，包含客户端显示名称
-	payload := fmt.Sprintf(`{"title": %q, "os": %q, "client": %q}`, updateData.Title, updateData.OS, clientName)
-	sseServer.TryPublish("focus", &sse.Event{Data: []byte(payload)})
+	reporter.ReportLegacyWindow(clientID, clientName, updateData.Title, updateData.OS)
 
 	fmt.Fprintf(w, "Update received from %s", clientName)
 }
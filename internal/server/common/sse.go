@@ -0,0 +1,69 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+)
+
+// EventResumer 为 SSE 订阅者提供初始快照，或断线期间错过的事件，由 WindowService 实现
+type EventResumer interface {
+	Snapshot() *naniwosurunov1.GetSnapshotResponse
+	ResumeFrom(lastID uint64) ([]*naniwosurunov1.WindowEvent, *naniwosurunov1.GetSnapshotResponse)
+}
+
+// resumeWriter 在 SSE 服务端第一次 Flush（即响应头写出、订阅者已注册）时，先写入续传内容。
+// 订阅者注册之后才计算续传内容，因此不会丢事件，可能重复的事件由前端按 ID 去重。
+type resumeWriter struct {
+	http.ResponseWriter
+	flusher http.Flusher
+	prelude func() []byte
+	written bool
+}
+
+func (w *resumeWriter) Flush() {
+	if !w.written {
+		w.written = true
+		_, _ = w.ResponseWriter.Write(w.prelude())
+	}
+	w.flusher.Flush()
+}
+
+// ssePrelude 根据 Last-Event-ID 生成续传的 SSE 帧：能续传时重放历史事件，否则发送一帧 snapshot
+func ssePrelude(resumer EventResumer, lastEventID string) []byte {
+	var events []*naniwosurunov1.WindowEvent
+	var snap *naniwosurunov1.GetSnapshotResponse
+
+	if id, err := strconv.ParseUint(lastEventID, 10, 64); err == nil && lastEventID != "" {
+		events, snap = resumer.ResumeFrom(id)
+	} else {
+		snap = resumer.Snapshot()
+	}
+
+	var buf bytes.Buffer
+	if snap != nil {
+		data, err := service.MarshalSnapshot(snap)
+		if err != nil {
+			log.Printf("序列化快照失败: %v", err)
+			return nil
+		}
+		writeSSEFrame(&buf, snap.LastEventId, service.EventTypeSnapshot, data)
+	}
+	for _, ev := range events {
+		data, err := service.MarshalEvent(ev)
+		if err != nil {
+			continue
+		}
+		writeSSEFrame(&buf, ev.Id, ev.Type, data)
+	}
+	return buf.Bytes()
+}
+
+func writeSSEFrame(buf *bytes.Buffer, id uint64, event string, data []byte) {
+	fmt.Fprintf(buf, "id: %d\nevent: %s\ndata: %s\n\n", id, event, data)
+}
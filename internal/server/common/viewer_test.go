@@ -0,0 +1,68 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1/naniwosurunov1connect"
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+	"github.com/r3labs/sse/v2"
+)
+
+func TestViewerInterceptor(t *testing.T) {
+	cm, err := pkg.NewConfigManagerWithLoader(&pkg.JSONConfigLoader{DataDir: t.TempDir(), FileName: "config.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm.GetConfig().ViewerToken = "secret"
+	windows := service.NewWindowService(service.NewEventBroker(sse.New(), nil, 0), nil, cm)
+	defer windows.Close()
+	windows.ReportLegacyWindow("laptop", "Laptop", "GoLand", "linux")
+
+	mux := http.NewServeMux()
+	mux.Handle(naniwosurunov1connect.NewWindowServiceHandler(windows, connect.WithInterceptors(
+		ViewerInterceptor(cm, naniwosurunov1connect.WindowServiceGetSnapshotProcedure, naniwosurunov1connect.WindowServiceSubscribeEventsProcedure),
+	)))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	client := naniwosurunov1connect.NewWindowServiceClient(srv.Client(), srv.URL)
+	ctx := context.Background()
+
+	// 1. 未携带 ViewerToken 时快照与事件订阅都被拒绝
+	if _, err := client.GetSnapshot(ctx, connect.NewRequest(&naniwosurunov1.GetSnapshotRequest{})); connect.CodeOf(err) != connect.CodeUnauthenticated {
+		t.Errorf("GetSnapshot without token = %v, want unauthenticated", err)
+	}
+	stream, err := client.SubscribeEvents(ctx, connect.NewRequest(&naniwosurunov1.SubscribeEventsRequest{}))
+	if err == nil {
+		if stream.Receive() {
+			t.Error("SubscribeEvents without token received an event")
+		}
+		err = stream.Err()
+		stream.Close()
+	}
+	if connect.CodeOf(err) != connect.CodeUnauthenticated {
+		t.Errorf("SubscribeEvents without token = %v, want unauthenticated", err)
+	}
+
+	// 2. 携带 ViewerToken 后可以读取
+	req := connect.NewRequest(&naniwosurunov1.GetSnapshotRequest{})
+	req.Header().Set("Authorization", "Bearer secret")
+	res, err := client.GetSnapshot(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Msg.Clients) == 0 {
+		t.Error("snapshot with token is empty")
+	}
+
+	// 3. 其余 RPC 不受影响，仍由会话令牌鉴权
+	beat := connect.NewRequest(&naniwosurunov1.HeartbeatRequest{})
+	if _, err := client.Heartbeat(ctx, beat); connect.CodeOf(err) == connect.CodeUnauthenticated && err.Error() == connect.NewError(connect.CodeUnauthenticated, errViewerToken).Error() {
+		t.Errorf("Heartbeat rejected by viewer interceptor: %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+package feed
+
+import (
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/internal/server/common"
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+const (
+	defaultTitle      = "Naniwosuruno"
+	defaultMaxEntries = 50
+	maxEntriesLimit   = 500
+)
+
+// EventHistory 提供最近的事件历史，由 history.Store 实现
+type EventHistory interface {
+	Recent(n int) []*naniwosurunov1.WindowEvent
+}
+
+// Handler 将焦点变化与上下线事件输出为 Atom / RSS 订阅源
+type Handler struct {
+	ConfigManager *pkg.ConfigManager
+	History       EventHistory
+}
+
+func NewHandler(cm *pkg.ConfigManager, history EventHistory) *Handler {
+	return &Handler{
+		ConfigManager: cm,
+		History:       history,
+	}
+}
+
+// entry 是 Atom 与 RSS 共用的条目内容
+type entry struct {
+	ID      string
+	Title   string
+	Summary string
+	Updated time.Time
+}
+
+// entries 根据查询参数 ?client=（ID、名称或 "me"，默认所有真实客户端）与 ?n= 生成条目，按时间倒序
+func (h *Handler) entries(r *http.Request) []entry {
+	cfg := h.ConfigManager.GetConfig().Feed
+	limit := cfg.MaxEntries
+	if n, err := strconv.Atoi(r.URL.Query().Get("n")); err == nil && n > 0 {
+		limit = n
+	}
+	if limit <= 0 {
+		limit = defaultMaxEntries
+	}
+	limit = min(limit, maxEntriesLimit)
+
+	events := Filter(h.History.Recent(0), r.URL.Query().Get("client"), time.Duration(cfg.Debounce)*time.Second)
+	if len(events) > limit {
+		events = events[:limit]
+	}
+
+	res := make([]entry, 0, len(events))
+	for _, ev := range events {
+		res = append(res, entry{
+			// 事件 ID 持久化且单调递增，可直接作为条目的稳定 ID
+			ID:      fmt.Sprintf("urn:naniwosuruno:event:%d", ev.Id),
+			Title:   entryTitle(ev),
+			Summary: entrySummary(ev),
+			Updated: time.UnixMilli(ev.Timestamp).UTC(),
+		})
+	}
+	return res
+}
+
+// Filter 从事件历史中挑出焦点变化与上下线事件，按时间倒序返回。
+// client 为空时包含所有真实客户端（不含合成的 "me"）；debounce 大于 0 时，
+// 停留时间不足 debounce 就被同一客户端下一次焦点变化或离线取代的焦点变化会被过滤。
+func Filter(events []*naniwosurunov1.WindowEvent, client string, debounce time.Duration) []*naniwosurunov1.WindowEvent {
+	// 补报的事件按 ID 排在其发生时间之后，先按时间戳排序
+	events = slices.Clone(events)
+	slices.SortStableFunc(events, func(a, b *naniwosurunov1.WindowEvent) int {
+		return cmp.Compare(a.Timestamp, b.Timestamp)
+	})
+
+	var res []*naniwosurunov1.WindowEvent
+	// 倒序遍历，记录每个客户端下一次焦点变化或离线的时间，用于计算停留时长；
+	// 空闲、手动状态等其他事件不结束当前焦点
+	next := make(map[string]int64)
+	for i := len(events) - 1; i >= 0; i-- {
+		ev := events[i]
+		if client == "" && ev.ClientId == service.PrimaryClientName {
+			continue
+		}
+		if client != "" && ev.ClientId != client && ev.Client != client {
+			continue
+		}
+
+		switch {
+		case ev.Type == service.EventTypeFocus:
+			nextTimestamp, hasNext := next[ev.ClientId]
+			next[ev.ClientId] = ev.Timestamp
+			if debounce > 0 && hasNext && time.Duration(nextTimestamp-ev.Timestamp)*time.Millisecond < debounce {
+				continue
+			}
+		case ev.Type == service.EventTypePresence && ev.Status == service.StatusOffline:
+			next[ev.ClientId] = ev.Timestamp
+		case ev.Type == service.EventTypePresence && ev.Status == service.StatusOnline:
+		default:
+			continue
+		}
+		res = append(res, ev)
+	}
+	return res
+}
+
+func entryTitle(ev *naniwosurunov1.WindowEvent) string {
+	name := ev.Client
+	if ev.ClientId == service.PrimaryClientName && ev.Source != "" {
+		name = ev.Source
+	}
+	if ev.Type == service.EventTypeFocus {
+		return fmt.Sprintf("%s: %s", name, ev.Title)
+	}
+	return fmt.Sprintf("%s is %s", name, ev.Status)
+}
+
+func entrySummary(ev *naniwosurunov1.WindowEvent) string {
+	if ev.Type == service.EventTypeFocus {
+		return fmt.Sprintf("Switched to %s on %s", ev.Title, ev.Os)
+	}
+	return fmt.Sprintf("%s went %s", ev.Client, ev.Status)
+}
+
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	if !common.AuthorizeViewer(h.ConfigManager, r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func (h *Handler) title() string {
+	if t := h.ConfigManager.GetConfig().Feed.Title; t != "" {
+		return t
+	}
+	return defaultTitle
+}
+
+// selfURL 返回当前请求的绝对地址，用作订阅源的链接
+func selfURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// --- Atom ---
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// atomAuthor 是 RFC 4287 要求的作者信息，条目没有单独的作者时继承订阅源的作者
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+type atomEntry struct {
+	Title   string `xml:"title"`
+	ID      string `xml:"id"`
+	Updated string `xml:"updated"`
+	Summary string `xml:"summary"`
+}
+
+func (h *Handler) HandleAtom(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+
+	entries := h.entries(r)
+	feed := atomFeed{
+		Title:   h.title(),
+		ID:      "urn:naniwosuruno:feed:" + r.URL.Query().Get("client"),
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: h.title()},
+		Link:    atomLink{Href: selfURL(r), Rel: "self"},
+	}
+	if len(entries) > 0 {
+		feed.Updated = entries[0].Updated.Format(time.RFC3339)
+	}
+	for _, e := range entries {
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   e.Title,
+			ID:      e.ID,
+			Updated: e.Updated.Format(time.RFC3339),
+			Summary: e.Summary,
+		})
+	}
+
+	writeXML(w, "application/atom+xml; charset=utf-8", feed)
+}
+
+// --- RSS 2.0 ---
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+func (h *Handler) HandleRSS(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       h.title(),
+			Link:        selfURL(r),
+			Description: "Focus changes and online/offline transitions",
+		},
+	}
+	for _, e := range h.entries(r) {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       e.Title,
+			GUID:        rssGUID{Value: e.ID},
+			PubDate:     e.Updated.Format(time.RFC1123Z),
+			Description: e.Summary,
+		})
+	}
+
+	writeXML(w, "application/rss+xml; charset=utf-8", feed)
+}
+
+func writeXML(w http.ResponseWriter, contentType string, v interface{}) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,129 @@
+package feed
+
+import (
+	"encoding/xml"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+type fakeHistory []*naniwosurunov1.WindowEvent
+
+func (f fakeHistory) Recent(n int) []*naniwosurunov1.WindowEvent { return f }
+
+func event(id uint64, sec int64, typ, client, title, status string) *naniwosurunov1.WindowEvent {
+	return &naniwosurunov1.WindowEvent{Id: id, Timestamp: sec * 1000, Type: typ, Client: client, ClientId: client, Title: title, Status: status, Os: "linux"}
+}
+
+func testEvents() fakeHistory {
+	return fakeHistory{
+		event(1, 0, "presence", "laptop", "", "online"),
+		event(2, 10, "focus", "laptop", "GoLand", "online"), // 仅停留 2 秒
+		event(3, 12, "focus", "laptop", "Slack", "online"),
+		event(4, 100, "focus", "laptop", "Firefox", "online"),
+		event(5, 100, "focus", "me", "Firefox", "online"),
+		event(6, 200, "presence", "laptop", "Firefox", "idle"),
+		event(7, 300, "presence", "desktop", "", "online"),
+		event(8, 400, "presence", "laptop", "Firefox", "offline"),
+	}
+}
+
+func ids(events []*naniwosurunov1.WindowEvent) []uint64 {
+	var res []uint64
+	for _, ev := range events {
+		res = append(res, ev.Id)
+	}
+	return res
+}
+
+func TestFilter(t *testing.T) {
+	// 1. 不去抖：排除 "me" 与空闲事件，按时间倒序
+	got := ids(Filter(testEvents(), "", 0))
+	want := []uint64{8, 7, 4, 3, 2, 1}
+	if len(got) != len(want) {
+		t.Fatalf("Filter() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Filter() = %v, want %v", got, want)
+		}
+	}
+
+	// 2. 去抖 5 秒：停留 2 秒的 GoLand 被过滤
+	got = ids(Filter(testEvents(), "laptop", 5*time.Second))
+	if len(got) != 4 || got[0] != 8 || got[1] != 4 || got[2] != 3 || got[3] != 1 {
+		t.Fatalf("Filter(debounce) = %v, want [8 4 3 1]", got)
+	}
+
+	// 3. 空闲、手动状态与补报事件不影响停留时长：GoLand 停留 10 分钟，2 秒后的空闲事件不会使其被过滤
+	interleaved := fakeHistory{
+		event(1, 0, "focus", "laptop", "GoLand", "online"),
+		event(2, 2, "presence", "laptop", "GoLand", "idle"),
+		event(3, 600, "focus", "laptop", "Slack", "online"),
+		event(4, 601, "focus", "laptop", "Terminal", "online"), // 补报的事件，实际发生在 GoLand 之前
+		event(5, 700, "presence", "laptop", "", "offline"),
+	}
+	interleaved[3].Timestamp = -60 * 1000
+	interleaved[3].Backfilled = true
+	got = ids(Filter(interleaved, "laptop", 5*time.Second))
+	if len(got) != 4 || got[0] != 5 || got[1] != 3 || got[2] != 1 || got[3] != 4 {
+		t.Fatalf("Filter(interleaved) = %v, want [5 3 1 4]", got)
+	}
+
+	// 4. 指定 "me"
+	got = ids(Filter(testEvents(), "me", 0))
+	if len(got) != 1 || got[0] != 5 {
+		t.Fatalf("Filter(me) = %v, want [5]", got)
+	}
+}
+
+func TestHandleAtomAndRSS(t *testing.T) {
+	cm, err := pkg.NewConfigManagerWithLoader(&pkg.JSONConfigLoader{DataDir: t.TempDir(), FileName: "config.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(cm, testEvents())
+
+	// 1. Atom：条目 ID 由事件 ID 生成，多次请求保持稳定
+	rec := httptest.NewRecorder()
+	h.HandleAtom(rec, httptest.NewRequest("GET", "/feed.atom?client=laptop&n=2", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/atom+xml") {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	var atom atomFeed
+	if err := xml.Unmarshal(rec.Body.Bytes(), &atom); err != nil {
+		t.Fatal(err)
+	}
+	if len(atom.Entries) != 2 || atom.Entries[0].ID != "urn:naniwosuruno:event:8" || atom.Entries[1].Title != "laptop: Firefox" {
+		t.Fatalf("unexpected atom entries: %+v", atom.Entries)
+	}
+	if atom.Author.Name != "Naniwosuruno" {
+		t.Fatalf("author = %q", atom.Author.Name)
+	}
+	if atom.Updated != time.Unix(400, 0).UTC().Format(time.RFC3339) {
+		t.Fatalf("updated = %q", atom.Updated)
+	}
+
+	// 2. RSS
+	rec = httptest.NewRecorder()
+	h.HandleRSS(rec, httptest.NewRequest("GET", "/feed.rss", nil))
+	var rss rssFeed
+	if err := xml.Unmarshal(rec.Body.Bytes(), &rss); err != nil {
+		t.Fatal(err)
+	}
+	if len(rss.Channel.Items) != 6 || rss.Channel.Items[0].Title != "laptop is offline" || rss.Channel.Items[0].GUID.IsPermaLink {
+		t.Fatalf("unexpected rss items: %+v", rss.Channel.Items)
+	}
+
+	// 3. 设置 ViewerToken 后未携带 Token 的请求被拒绝
+	cm.GetConfig().ViewerToken = "secret"
+	rec = httptest.NewRecorder()
+	h.HandleRSS(rec, httptest.NewRequest("GET", "/feed.rss", nil))
+	if rec.Code != 401 {
+		t.Fatalf("status = %d, want 401", rec.Code)
+	}
+}
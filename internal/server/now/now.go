@@ -0,0 +1,136 @@
+package now
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/internal/server/common"
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+// ClientLookup 按客户端 ID、名称或 "me" 查询当前状态，由 WindowService 实现
+type ClientLookup interface {
+	LookupClient(key string) (*naniwosurunov1.WindowEvent, bool)
+}
+
+// Handler 为脚本、终端提示符与聊天机器人提供当前活动，直接读取 WindowService 中的状态
+type Handler struct {
+	ConfigManager *pkg.ConfigManager
+	Clients       ClientLookup
+}
+
+func NewHandler(cm *pkg.ConfigManager, clients ClientLookup) *Handler {
+	return &Handler{
+		ConfigManager: cm,
+		Clients:       clients,
+	}
+}
+
+// HandleText 处理 /now，以 text/plain 返回如 "GoLand (linux, laptop)" 的一行文字
+func (h *Handler) HandleText(w http.ResponseWriter, r *http.Request) {
+	_, text, ok := h.resolve(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	_, _ = w.Write([]byte(text + "\n"))
+}
+
+// HandleJSON 处理 /now.json，返回事件信封中的字段以及格式化后的 text
+func (h *Handler) HandleJSON(w http.ResponseWriter, r *http.Request) {
+	ev, text, ok := h.resolve(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := service.MarshalEvent(ev)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	var fields map[string]interface{}
+	_ = json.Unmarshal(data, &fields)
+	fields["text"] = text
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	_ = json.NewEncoder(w).Encode(fields)
+}
+
+// resolve 完成鉴权并查找 ?client=（默认 "me"）的状态，失败时已写出错误响应
+func (h *Handler) resolve(w http.ResponseWriter, r *http.Request) (*naniwosurunov1.WindowEvent, string, bool) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, "", false
+	}
+	if !common.AuthorizeViewer(h.ConfigManager, r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, "", false
+	}
+
+	key := r.URL.Query().Get("client")
+	if key == "" {
+		key = service.PrimaryClientName
+	}
+	ev, ok := h.Clients.LookupClient(key)
+	if !ok {
+		http.Error(w, "Client not found", http.StatusNotFound)
+		return nil, "", false
+	}
+	return ev, Format(ev, r.URL.Query().Get("format")), true
+}
+
+// Format 将状态格式化为一行文字。format 为空时使用默认格式，
+// 否则替换其中的 {title}、{os}、{client}、{device}、{status}、{custom} 占位符。
+// 设置了手动状态时，默认格式将其放在窗口标题之前；标题不公开时以手动状态代替。
+func Format(ev *naniwosurunov1.WindowEvent, format string) string {
+	custom := service.CustomStatusText(ev.CustomStatus)
+	device := ev.Client
+	if ev.ClientId == service.PrimaryClientName {
+		device = ev.Source
+	}
+
+	if format != "" {
+		return strings.NewReplacer(
+			"{title}", ev.Title,
+			"{os}", ev.Os,
+			"{client}", ev.Client,
+			"{device}", device,
+			"{status}", ev.Status,
+			"{custom}", custom,
+		).Replace(format)
+	}
+
+	switch ev.Status {
+	case service.StatusOffline, service.StatusPaused, service.StatusBusy:
+		if custom != "" {
+			return custom
+		}
+		return ev.Status
+	}
+	title := ev.Title
+	switch {
+	case custom != "" && title != "":
+		title = custom + " · " + title
+	case custom != "":
+		title = custom
+	}
+	var details []string
+	for _, v := range []string{ev.Os, device} {
+		if v != "" {
+			details = append(details, v)
+		}
+	}
+	if ev.Status == service.StatusIdle {
+		details = append(details, "idle")
+	}
+	if len(details) == 0 {
+		return title
+	}
+	return title + " (" + strings.Join(details, ", ") + ")"
+}
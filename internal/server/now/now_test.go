@@ -0,0 +1,38 @@
+package now
+
+import (
+	"testing"
+
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+)
+
+func TestFormat(t *testing.T) {
+	me := &naniwosurunov1.WindowEvent{Title: "GoLand", Os: "linux", Client: "me", ClientId: "me", Source: "laptop", Status: "online"}
+	idle := &naniwosurunov1.WindowEvent{Title: "Firefox", Os: "windows", Client: "desktop", ClientId: "d1", Status: "idle"}
+	offline := &naniwosurunov1.WindowEvent{Title: "Steam", Client: "desktop", ClientId: "d1", Status: "offline"}
+	lunch := &naniwosurunov1.CustomStatus{Text: "Lunch", Emoji: "🍜"}
+	custom := &naniwosurunov1.WindowEvent{Title: "GoLand", Os: "linux", Client: "laptop", ClientId: "l1", Status: "online", CustomStatus: lunch}
+	away := &naniwosurunov1.WindowEvent{Title: "Steam", Client: "desktop", ClientId: "d1", Status: "offline", CustomStatus: lunch}
+	untitled := &naniwosurunov1.WindowEvent{Os: "linux", Client: "laptop", ClientId: "l1", Status: "online", CustomStatus: lunch}
+
+	cases := []struct {
+		ev     *naniwosurunov1.WindowEvent
+		format string
+		want   string
+	}{
+		{me, "", "GoLand (linux, laptop)"},
+		{idle, "", "Firefox (windows, desktop, idle)"},
+		{offline, "", "offline"},
+		{me, "💻 {title} @ {device} [{status}]", "💻 GoLand @ laptop [online]"},
+		{custom, "", "🍜 Lunch · GoLand (linux, laptop)"},
+		{away, "", "🍜 Lunch"},
+		{untitled, "", "🍜 Lunch (linux, laptop)"},
+		{custom, "{custom} | {title}", "🍜 Lunch | GoLand"},
+		{me, "[{custom}]", "[]"},
+	}
+	for _, c := range cases {
+		if got := Format(c.ev, c.format); got != c.want {
+			t.Errorf("Format(%q, %q) = %q, want %q", c.ev.Title, c.format, got, c.want)
+		}
+	}
+}
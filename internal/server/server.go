@@ -1,34 +1,66 @@
 package server
 
 import (
+	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 
+	"connectrpc.com/connect"
 	"github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1/naniwosurunov1connect"
+	"github.com/nhirsama/Naniwosuruno/internal/calendar"
+	"github.com/nhirsama/Naniwosuruno/internal/federation"
+	"github.com/nhirsama/Naniwosuruno/internal/history"
+	"github.com/nhirsama/Naniwosuruno/internal/mqtt"
+	"github.com/nhirsama/Naniwosuruno/internal/relay"
+	"github.com/nhirsama/Naniwosuruno/internal/server/badge"
+	"github.com/nhirsama/Naniwosuruno/internal/server/common"
+	"github.com/nhirsama/Naniwosuruno/internal/server/feed"
+	"github.com/nhirsama/Naniwosuruno/internal/server/now"
 	"github.com/nhirsama/Naniwosuruno/internal/server/v0"
+	"github.com/nhirsama/Naniwosuruno/internal/server/v1"
+	"github.com/nhirsama/Naniwosuruno/internal/server/web"
 	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/internal/statussync"
+	"github.com/nhirsama/Naniwosuruno/internal/webhook"
 	"github.com/nhirsama/Naniwosuruno/pkg"
 	"github.com/nhirsama/Naniwosuruno/pkg/auth"
 	"github.com/r3labs/sse/v2"
-	"golang.org/x/net/http2"
-	"golang.org/x/net/http2/h2c"
 )
 
+// Options 为服务端的命令行参数
+type Options struct {
+	WebDir       string // 前端资源目录，留空时使用内嵌资源
+	OverlayToken string // 访问 /overlay 所需的 key，留空表示不校验
+	RelayTo      string // 上游实例地址，设置后将本机事件转发到上游
+}
+
+// shutdownTimeout 为关闭时等待进行中请求完成的最长时间
+const shutdownTimeout = 10 * time.Second
+
 type Server struct {
+	options       Options
 	configManager *pkg.ConfigManager
 	authenticator auth.StatefulAuthenticator
 	sseServer     *sse.Server
+	history       *history.FileStore
+	windowSvc     *service.WindowService
+	stoppers      []func() // 后台任务的停止函数，关闭时逆序调用
 }
 
-func Run() {
-	NewServer().Run()
+// Run 启动服务端并阻塞到 ctx 结束，随后优雅关闭
+func Run(ctx context.Context, opts Options) error {
+	return NewServer(opts).Run(ctx)
 }
 
-func NewServer() *Server {
+func NewServer(opts Options) *Server {
 	cm, err := pkg.NewConfigManager()
 	if err != nil {
 		log.Fatalf("初始化配置管理器失败: %v", err)
@@ -37,54 +69,203 @@ func NewServer() *Server {
 	keyProvider := &ConfigKeyProvider{cm: cm}
 
 	return &Server{
+		options:       opts,
 		configManager: cm,
 		authenticator: auth.NewStatefulAuthenticator(keyProvider),
 	}
 }
 
-func (s *Server) Run() {
+func (s *Server) Run(ctx context.Context) error {
 	s.initSSEServer()
-	s.registerRoutes()
+	s.initHistory()
+	s.watchReload(ctx)
+	return s.serve(ctx, s.registerRoutes())
 }
 
 func (s *Server) initSSEServer() {
 	s.sseServer = sse.New()
 	s.sseServer.EventTTL = 24 * time.Hour
 	s.sseServer.BufferSize = 4
-	s.sseServer.CreateStream("focus")
+	// 续传由持久化的事件历史负责，r3labs 自带的内存回放会改写事件 ID，因此关闭
+	s.sseServer.AutoReplay = false
+	s.sseServer.CreateStream(service.EventStream)
+}
+
+func (s *Server) initHistory() {
+	cfg := s.configManager.GetConfig().History
+	store, err := history.NewFileStore(filepath.Join(pkg.DefaultDataDir, "events.jsonl"), cfg.MaxEvents)
+	if err != nil {
+		log.Fatalf("初始化事件历史失败: %v", err)
+	}
+	s.history = store
 }
 
-func (s *Server) registerRoutes() {
+func (s *Server) registerRoutes() http.Handler {
 	mux := http.NewServeMux()
 
 	// 1. Register ConnectRPC Services
 	authSvc := service.NewAuthService(s.authenticator)
-	windowSvc := service.NewWindowService(s.sseServer, s.authenticator)
+	broker := service.NewEventBroker(s.sseServer, s.history, s.configManager.GetConfig().History.MaxReplay)
+	windowSvc := service.NewWindowService(broker, s.authenticator, s.configManager)
+	s.windowSvc = windowSvc
+	if err := windowSvc.LoadControls(filepath.Join(pkg.DefaultDataDir, "controls.json")); err != nil {
+		log.Fatalf("读取客户端控制失败: %v", err)
+	}
+	s.onStop(s.authenticator.Close)
+	s.onStop(windowSvc.Close)
+
+	dispatcher, err := webhook.NewDispatcher(s.configManager, webhook.DefaultDir)
+	if err != nil {
+		log.Fatalf("初始化回调失败: %v", err)
+	}
+	dispatcher.Start(broker)
+	s.onStop(dispatcher.Stop)
+
+	if cfg := s.configManager.GetConfig().MQTT; cfg.Broker != "" {
+		publisher := mqtt.NewPublisher(cfg)
+		publisher.Start(windowSvc)
+		s.onStop(publisher.Stop)
+	}
+	if syncer := statussync.New(s.configManager.GetConfig().StatusSync); syncer.Enabled() {
+		syncer.Start(windowSvc)
+		s.onStop(syncer.Stop)
+	}
+	if watcher := calendar.New(s.configManager.GetConfig().Calendar); watcher.Enabled() {
+		watcher.Start(windowSvc)
+		s.onStop(watcher.Stop)
+	}
+	if peers := federation.NewManager(s.configManager.GetConfig().Federation, windowSvc); peers.Enabled() {
+		peers.Start()
+		s.onStop(peers.Stop)
+	}
+	if s.options.RelayTo != "" {
+		upstream, err := relay.New(s.options.RelayTo, s.configManager.GetConfig())
+		if err != nil {
+			log.Fatalf("初始化中继失败: %v", err)
+		}
+		upstream.Start(windowSvc)
+		s.onStop(upstream.Stop)
+	}
 
 	authPath, authHandler := naniwosurunov1connect.NewAuthServiceHandler(authSvc)
 	mux.Handle(authPath, authHandler)
 
-	winPath, winHandler := naniwosurunov1connect.NewWindowServiceHandler(windowSvc)
+	// 快照与事件订阅与 /events 等接口一样受 ViewerToken 保护
+	winPath, winHandler := naniwosurunov1connect.NewWindowServiceHandler(windowSvc, connect.WithInterceptors(
+		common.ViewerInterceptor(s.configManager,
+			naniwosurunov1connect.WindowServiceGetSnapshotProcedure,
+			naniwosurunov1connect.WindowServiceSubscribeEventsProcedure,
+		),
+	))
 	mux.Handle(winPath, winHandler)
 
 	// 2. Legacy V0 API
-	v0Handler := v0.NewHandler(s.configManager, s.sseServer)
+	v0Handler := v0.NewHandler(s.configManager, s.sseServer, windowSvc, windowSvc)
 	mux.HandleFunc("/api/v0/update", v0Handler.HandleUpdate)
 	mux.HandleFunc("/events", v0Handler.HandleEvents)
 
 	// Support existing frontend SSE path
 	mux.HandleFunc("/api/v1/events", v0Handler.HandleEvents)
 
+	v1Handler := v1.NewHandler(s.configManager, windowSvc)
+	mux.HandleFunc("/api/v1/snapshot", v1Handler.HandleSnapshot)
+	// 部分代理会缓冲 text/event-stream，提供 WebSocket 作为替代
+	mux.HandleFunc("/api/v1/ws", v1Handler.HandleWebSocket)
+
+	// 状态徽章，便于嵌入 README 或论坛签名
+	mux.Handle("/badge/", badge.NewHandler(s.configManager, windowSvc))
+
+	// 供 curl、终端提示符与聊天机器人使用的当前活动
+	nowHandler := now.NewHandler(s.configManager, windowSvc)
+	mux.HandleFunc("/now", nowHandler.HandleText)
+	mux.HandleFunc("/now.json", nowHandler.HandleJSON)
+
+	// 由事件历史生成的活动订阅源
+	feedHandler := feed.NewHandler(s.configManager, s.history)
+	mux.HandleFunc("/feed.atom", feedHandler.HandleAtom)
+	mux.HandleFunc("/feed.rss", feedHandler.HandleRSS)
+
 	// 3. Static Files
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, "index.html")
+	webHandler := web.NewHandler(s.configManager, s.options.WebDir, s.options.OverlayToken)
+	mux.HandleFunc("/theme.json", webHandler.HandleTheme)
+	// 供 OBS 浏览器源使用的叠加层
+	mux.HandleFunc("/overlay", webHandler.HandleOverlay)
+	mux.Handle("/", webHandler)
+
+	return mux
+}
+
+// onStop 登记一个在关闭时调用的停止函数
+// watchReload 在收到 SIGHUP 时重新加载配置文件。客户端策略、回调等每次使用时读取配置的设置立即生效，
+// 客户端通过心跳与 Presence 确认中变化的设置版本重新获取；MQTT、联邦等启动时读取的配置仍需重启
+func (s *Server) watchReload(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				if err := s.configManager.Reload(); err != nil {
+					log.Printf("重新加载配置失败，继续使用原配置: %v", err)
+					continue
+				}
+				log.Printf("已重新加载配置")
+			}
+		}
+	}()
+}
+
+func (s *Server) onStop(stop func()) {
+	s.stoppers = append(s.stoppers, stop)
+}
+
+// stop 逆序停止所有后台任务并关闭事件历史
+func (s *Server) stop() {
+	for i := len(s.stoppers) - 1; i >= 0; i-- {
+		s.stoppers[i]()
+	}
+	if err := s.history.Close(); err != nil {
+		log.Printf("关闭事件历史失败: %v", err)
+	}
+}
+
+// serve 监听直到 ctx 结束，然后依次结束事件长连接、等待其余请求完成、停止后台任务
+func (s *Server) serve(ctx context.Context, handler http.Handler) error {
+	// 同时支持明文 HTTP/2 (h2c)，与 h2c.NewHandler 不同，这些连接由 http.Server 管理，Shutdown 时可以正常排空
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	srv := &http.Server{Addr: ":9975", Handler: handler, Protocols: &protocols}
+
+	// SSE、RPC 事件流与 Presence 都是长连接，Shutdown 开始时主动结束，否则排空会一直等到超时
+	srv.RegisterOnShutdown(func() {
+		s.windowSvc.Close()
+		s.sseServer.Close()
 	})
 
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
 	fmt.Println("服务端启动于 :9975")
-	// Use h2c to support HTTP/2 without TLS (Cleartext)
-	if err := http.ListenAndServe(":9975", h2c.NewHandler(mux, &http2.Server{})); err != nil {
-		log.Fatal("服务启动失败", err)
+
+	select {
+	case err := <-errc:
+		s.stop()
+		return fmt.Errorf("服务启动失败: %w", err)
+	case <-ctx.Done():
 	}
+
+	log.Println("正在关闭服务端...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	s.stop()
+	log.Println("服务端已关闭")
+	return err
 }
 
 // --- KeyProvider Implementation ---
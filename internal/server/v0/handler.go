@@ -8,15 +8,25 @@ import (
 	"github.com/r3labs/sse/v2"
 )
 
+// 所有 v0 客户端共用同一个静态 Token，因此在状态机中被视为同一个客户端
+const (
+	legacyClientID   = "legacy"
+	legacyClientName = "Legacy Client"
+)
+
 type Handler struct {
 	ConfigManager *pkg.ConfigManager
 	SSEServer     *sse.Server
+	Reporter      common.UpdateReporter
+	Resumer       common.EventResumer
 }
 
-func NewHandler(cm *pkg.ConfigManager, sse *sse.Server) *Handler {
+func NewHandler(cm *pkg.ConfigManager, sse *sse.Server, reporter common.UpdateReporter, resumer common.EventResumer) *Handler {
 	return &Handler{
 		ConfigManager: cm,
 		SSEServer:     sse,
+		Reporter:      reporter,
+		Resumer:       resumer,
 	}
 }
 
@@ -25,11 +35,15 @@ func (h *Handler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	common.ProcessUpdate(h.SSEServer, w, r, "Legacy Client")
+	common.ProcessUpdate(h.Reporter, w, r, legacyClientID, legacyClientName)
 }
 
 func (h *Handler) HandleEvents(w http.ResponseWriter, r *http.Request) {
-	common.ServeSSE(h.SSEServer, w, r)
+	if r.Method != http.MethodOptions && !common.AuthorizeViewer(h.ConfigManager, r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	common.ServeSSE(h.SSEServer, h.Resumer, w, r)
 }
 
 func (h *Handler) validateToken(r *http.Request) bool {
@@ -0,0 +1,43 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/nhirsama/Naniwosuruno/internal/server/common"
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+type Handler struct {
+	ConfigManager *pkg.ConfigManager
+	WindowService *service.WindowService
+}
+
+func NewHandler(cm *pkg.ConfigManager, ws *service.WindowService) *Handler {
+	return &Handler{
+		ConfigManager: cm,
+		WindowService: ws,
+	}
+}
+
+// HandleSnapshot 以 JSON 形式返回所有客户端的当前状态以及合成的 "me" 条目，便于浏览器直接 GET
+func (h *Handler) HandleSnapshot(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !common.AuthorizeViewer(h.ConfigManager, r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	data, err := service.MarshalSnapshot(h.WindowService.Snapshot())
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	_, _ = w.Write(data)
+}
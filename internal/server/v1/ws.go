@@ -0,0 +1,140 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/internal/server/common"
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+)
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+)
+
+// 与 SSE 一致，允许任意来源的页面订阅；访问控制由 AuthorizeViewer 负责
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsClientMessage 是浏览器发往服务端的控制消息
+type wsClientMessage struct {
+	Type    string   `json:"type"`    // 目前仅支持 "subscribe"
+	Clients []string `json:"clients"` // 客户端 ID 或名称，包含 "me" 可订阅合成条目；为空表示订阅全部
+}
+
+// clientFilter 记录当前连接订阅的客户端，nil 表示全部
+type clientFilter map[string]bool
+
+func (f clientFilter) match(ev *naniwosurunov1.WindowEvent) bool {
+	return f == nil || f[ev.ClientId] || f[ev.Client]
+}
+
+// HandleWebSocket 为 SSE 被代理缓冲的环境提供同样的事件信封。
+// 连接建立后先收到快照（或通过 ?since_id= 续传），之后可随时发送 subscribe 消息调整订阅的客户端。
+func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !common.AuthorizeViewer(h.ConfigManager, r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var sinceID uint64
+	if v := r.URL.Query().Get("since_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			http.Error(w, "since_id must be a number", http.StatusBadRequest)
+			return
+		}
+		sinceID = id
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade 已经向客户端写出了错误响应
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	var filter atomic.Pointer[clientFilter]
+	go h.readWebSocket(conn, &filter, cancel)
+	go pingWebSocket(ctx, conn)
+
+	err = h.WindowService.StreamEvents(ctx, sinceID, func(ev *naniwosurunov1.WindowEvent) error {
+		if f := filter.Load(); f != nil && !f.match(ev) {
+			return nil
+		}
+		data, err := service.MarshalEvent(ev)
+		if err != nil {
+			return err
+		}
+		_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		return conn.WriteMessage(websocket.TextMessage, data)
+	})
+	if err != nil && !errors.Is(err, service.ErrClosed) {
+		log.Printf("WebSocket 订阅结束: %v", err)
+	}
+	_ = conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(wsWriteWait))
+}
+
+// readWebSocket 处理订阅消息与 pong，连接断开或超时未收到 pong 时结束订阅
+func (h *Handler) readWebSocket(conn *websocket.Conn, filter *atomic.Pointer[clientFilter], cancel context.CancelFunc) {
+	defer cancel()
+
+	conn.SetReadLimit(4096)
+	_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var msg wsClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Type != "subscribe" {
+			continue
+		}
+		if len(msg.Clients) == 0 {
+			filter.Store(nil)
+			continue
+		}
+		f := make(clientFilter, len(msg.Clients))
+		for _, c := range msg.Clients {
+			f[c] = true
+		}
+		filter.Store(&f)
+	}
+}
+
+// pingWebSocket 定期发送 ping，WriteControl 可与 WriteMessage 并发调用
+func pingWebSocket(ctx context.Context, conn *websocket.Conn) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+	"github.com/r3labs/sse/v2"
+)
+
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	cm, err := pkg.NewConfigManagerWithLoader(&pkg.JSONConfigLoader{DataDir: t.TempDir(), FileName: "config.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := service.NewEventBroker(sse.New(), nil, 0)
+	return NewHandler(cm, service.NewWindowService(broker, nil, cm))
+}
+
+func readEvent(t *testing.T, conn *websocket.Conn) map[string]interface{} {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	var ev map[string]interface{}
+	if err := json.Unmarshal(data, &ev); err != nil {
+		t.Fatalf("invalid event %s: %v", data, err)
+	}
+	return ev
+}
+
+func TestWebSocketSubscribe(t *testing.T) {
+	h := newTestHandler(t)
+	h.WindowService.ReportLegacyWindow("laptop", "Laptop", "GoLand", "linux")
+
+	srv := httptest.NewServer(http.HandlerFunc(h.HandleWebSocket))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// 1. 连接建立后先收到快照：一个客户端条目加一个 "me" 条目
+	for i := 0; i < 2; i++ {
+		if ev := readEvent(t, conn); ev["type"] != service.EventTypeSnapshot {
+			t.Fatalf("expected snapshot event, got %v", ev)
+		}
+	}
+
+	// 2. 只订阅 "me" 后，单个客户端的事件被过滤
+	if err := conn.WriteJSON(wsClientMessage{Type: "subscribe", Clients: []string{"me"}}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	h.WindowService.ReportLegacyWindow("laptop", "Laptop", "Firefox", "linux")
+
+	ev := readEvent(t, conn)
+	if ev["client_id"] != "me" || ev["title"] != "Firefox" || ev["type"] != service.EventTypeFocus {
+		t.Errorf("unexpected event %v", ev)
+	}
+}
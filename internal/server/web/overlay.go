@@ -0,0 +1,82 @@
+package web
+
+import (
+	"crypto/subtle"
+	"embed"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+//go:embed templates
+var templates embed.FS
+
+var overlayTemplate = template.Must(template.ParseFS(templates, "templates/overlay.html"))
+
+// overlayPositions 将 position 参数映射为 flex 布局的对齐方式 (justify-content, align-items)
+var overlayPositions = map[string][2]string{
+	"top-left":     {"flex-start", "flex-start"},
+	"top":          {"center", "flex-start"},
+	"top-right":    {"flex-end", "flex-start"},
+	"center":       {"center", "center"},
+	"bottom-left":  {"flex-start", "flex-end"},
+	"bottom":       {"center", "flex-end"},
+	"bottom-right": {"flex-end", "flex-end"},
+}
+
+// overlayParams 为渲染叠加层页面所需的参数，CSS 与 JS 中的值由 html/template 按上下文转义
+type overlayParams struct {
+	Client         string
+	ViewerToken    string
+	Background     string
+	Font           string
+	Color          string
+	Size           int
+	JustifyContent string
+	AlignItems     string
+	ShowIcon       bool
+}
+
+// HandleOverlay 根据查询参数生成供 OBS 浏览器源使用的叠加层页面：
+// client、bg（默认透明）、font、color、size、position、icon。
+// 配置了 --overlay-token 时需要携带 ?key=，防止地址被猜到。
+func (h *Handler) HandleOverlay(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	if h.OverlayToken != "" && subtle.ConstantTimeCompare([]byte(q.Get("key")), []byte(h.OverlayToken)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	params := overlayParams{
+		Client:      queryOr(q.Get("client"), "me"),
+		ViewerToken: q.Get("token"),
+		Background:  queryOr(q.Get("bg"), "transparent"),
+		Font:        queryOr(q.Get("font"), "Inter"),
+		Color:       queryOr(q.Get("color"), "#fff"),
+		Size:        32,
+		ShowIcon:    q.Get("icon") == "1" || q.Get("icon") == "true",
+	}
+	if size, err := strconv.Atoi(q.Get("size")); err == nil && size >= 8 && size <= 200 {
+		params.Size = size
+	}
+	pos, ok := overlayPositions[q.Get("position")]
+	if !ok {
+		pos = overlayPositions["bottom-left"]
+	}
+	params.JustifyContent, params.AlignItems = pos[0], pos[1]
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := overlayTemplate.Execute(w, params); err != nil {
+		log.Printf("渲染叠加层失败: %v", err)
+	}
+}
+
+func queryOr(v, fallback string) string {
+	if v == "" {
+		return fallback
+	}
+	return v
+}
@@ -0,0 +1,205 @@
+(function () {
+    const titleElement = document.getElementById('windowTitle');
+    const statusDot = document.getElementById('statusDot');
+    const statusText = document.getElementById('statusText');
+    const detailsElement = document.getElementById('details');
+    const teamElement = document.getElementById('team');
+    let currentTitle = "";
+    let currentOS = "";
+    let isConnected = false;
+
+    // 默认展示的字段，可由 /theme.json 的 fields 覆盖
+    let fields = ['os'];
+
+    // 配置了 ViewerToken 时，页面通过 ?token= 访问，事件流沿用同一个 Token
+    const token = new URLSearchParams(location.search).get('token');
+    const withToken = (url) => token ? url + (url.includes('?') ? '&' : '?') + 'token=' + encodeURIComponent(token) : url;
+
+    function applyTheme(theme) {
+        const colors = theme.colors || {};
+        const root = document.documentElement.style;
+        if (colors.background_from) root.setProperty('--background-from', colors.background_from);
+        if (colors.background_to) root.setProperty('--background-to', colors.background_to);
+        if (colors.accent_from) root.setProperty('--accent-from', colors.accent_from);
+        if (colors.accent_to) root.setProperty('--accent-to', colors.accent_to);
+        if (theme.tagline) document.getElementById('tagline').textContent = theme.tagline;
+        if (Array.isArray(theme.fields)) fields = theme.fields;
+    }
+
+    function updateStatusUI() {
+        if (isConnected) {
+            statusDot.classList.remove('disconnected');
+            statusDot.classList.add('connected');
+            statusText.classList.add('live');
+
+            const showOS = fields.includes('os') && currentOS;
+            const osDisplay = showOS ? (currentOS.charAt(0).toUpperCase() + currentOS.slice(1) + " ") : "";
+            statusText.textContent = osDisplay + "Live";
+        } else {
+            statusDot.classList.remove('connected');
+            statusDot.classList.add('disconnected');
+            statusText.textContent = "Offline";
+            statusText.classList.remove('live');
+        }
+    }
+
+    // 手动设置的状态，如 "🍜 Lunch"，未设置时为空字符串
+    function customText(me) {
+        const custom = me.custom_status;
+        if (!custom || !custom.text) return "";
+        return custom.emoji ? `${custom.emoji} ${custom.text}` : custom.text;
+    }
+
+    function updateDetails(me, custom) {
+        const parts = [];
+        if (custom) parts.push(custom);
+        if (fields.includes('source') && me.source) parts.push(me.source);
+        if (fields.includes('status') && me.status) parts.push(me.status);
+        detailsElement.textContent = parts.join(' · ');
+    }
+
+    function updateContent(me) {
+        if (me.os) {
+            currentOS = me.os;
+            updateStatusUI();
+        }
+        // 标题不公开时手动状态代替标题显示，否则与标题一同显示在详情中
+        const custom = customText(me);
+        let title = me.title;
+        const hidden = me.status === 'paused' || me.status === 'busy' || !title || title.trim() === "";
+        updateDetails(me, hidden ? "" : custom);
+        if (hidden && custom) {
+            title = custom;
+        } else if (me.status === 'paused' || me.status === 'busy') {
+            title = me.status === 'busy' ? "Busy" : "Paused";
+        } else if (!title || title.trim() === "") {
+            title = "Idle";
+        }
+        if (currentTitle === title) return;
+
+        titleElement.classList.add('hidden');
+
+        setTimeout(() => {
+            titleElement.textContent = title;
+            currentTitle = title;
+            titleElement.classList.remove('hidden');
+        }, 300);
+    }
+
+    // 联邦对端的连接状态与对端的 "me" 条目（本地 client_id 为 "<对端>/me"），以对端名称为键
+    const peers = {};
+    const team = {};
+
+    function renderTeam() {
+        const names = Object.keys(peers).sort();
+        teamElement.hidden = names.length === 0;
+        teamElement.replaceChildren(...names.map((name) => {
+            const member = team[name] || {};
+            const reachable = peers[name] === 'online';
+
+            const dot = document.createElement('span');
+            dot.className = 'status-dot';
+            if (!reachable || member.status === 'offline' || !member.status) {
+                dot.classList.add('disconnected');
+            } else {
+                dot.classList.add(member.status === 'online' ? 'connected' : 'idle');
+            }
+
+            const label = document.createElement('span');
+            label.className = 'team-name';
+            label.textContent = name;
+
+            const title = document.createElement('span');
+            title.className = 'team-title';
+            title.textContent = reachable ? (member.title || customText(member) || (member.status === 'paused' || member.status === 'busy' ? member.status : '')) : 'server unreachable';
+
+            const li = document.createElement('li');
+            li.className = 'team-member';
+            li.append(dot, label, title);
+            return li;
+        }));
+    }
+
+    // 返回对端 "me" 条目所属的对端名称，其他事件返回 null
+    function teamMember(ev) {
+        const id = ev.client_id || '';
+        return id.endsWith('/me') ? id.slice(0, -3) : null;
+    }
+
+    function connect() {
+        const source = new EventSource(withToken('/api/v1/events?stream=focus'));
+
+        source.onopen = function () {
+            isConnected = true;
+            updateStatusUI();
+        };
+
+        // 事件以 SSE event 名称区分类型 (presence / focus)，这里只展示服务端选出的 "me" 条目。
+        // 断线重连时浏览器会自动携带 Last-Event-ID，服务端据此续传或重新发送快照。
+        let lastEventId = 0;
+
+        function handleEvent(event) {
+            let parsed;
+            try {
+                parsed = JSON.parse(event.data);
+            } catch (e) {
+                return;
+            }
+            // 续传与实时推送之间可能有重复事件，按 ID 去重（protojson 将 uint64 编码为字符串）
+            const id = Number(parsed.id) || 0;
+            if (id <= lastEventId) {
+                return;
+            }
+            lastEventId = id;
+            if (parsed.type === 'peer') {
+                peers[parsed.client_id] = parsed.status;
+                renderTeam();
+                return;
+            }
+            const member = teamMember(parsed);
+            if (member) {
+                team[member] = parsed;
+                renderTeam();
+                return;
+            }
+            if (parsed.client_id !== 'me') {
+                return;
+            }
+            updateContent(parsed);
+        }
+
+        source.addEventListener('snapshot', function (event) {
+            let snapshot;
+            try {
+                snapshot = JSON.parse(event.data);
+            } catch (e) {
+                return;
+            }
+            lastEventId = Number(snapshot.last_event_id) || 0;
+            if (snapshot.me) {
+                updateContent(snapshot.me);
+            }
+            (snapshot.peers || []).forEach((peer) => { peers[peer.client_id] = peer.status; });
+            (snapshot.clients || []).forEach((client) => {
+                const member = teamMember(client);
+                if (member) team[member] = client;
+            });
+            renderTeam();
+        });
+        source.addEventListener('presence', handleEvent);
+        source.addEventListener('focus', handleEvent);
+        source.addEventListener('peer', handleEvent);
+
+        source.onerror = function () {
+            isConnected = false;
+            updateStatusUI();
+        };
+    }
+
+    // 主题加载失败时使用默认样式，不影响事件流
+    fetch('/theme.json')
+        .then((res) => res.ok ? res.json() : {})
+        .then(applyTheme)
+        .catch(() => {})
+        .finally(connect);
+})();
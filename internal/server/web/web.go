@@ -0,0 +1,91 @@
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+//go:embed static
+var embedded embed.FS
+
+// Handler 提供前端页面与静态资源：默认使用编译进二进制的资源，指定目录时从该目录加载以便自定义主题
+type Handler struct {
+	ConfigManager *pkg.ConfigManager
+	OverlayToken  string // 访问 /overlay 所需的 key，留空表示不校验
+	files         fs.FS
+	embedded      bool
+	etags         sync.Map // 内嵌资源不会变化，缓存其 ETag
+}
+
+// NewHandler 创建前端资源处理器，webDir 为空时使用内嵌资源
+func NewHandler(cm *pkg.ConfigManager, webDir, overlayToken string) *Handler {
+	h := &Handler{ConfigManager: cm, OverlayToken: overlayToken}
+	if webDir != "" {
+		h.files = os.DirFS(webDir)
+	} else {
+		h.files, _ = fs.Sub(embedded, "static")
+		h.embedded = true
+	}
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+	if name == "" {
+		name = "index.html"
+	}
+
+	data, err := fs.ReadFile(h.files, name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	// 设置 ETag 后 ServeContent 会自动处理 If-None-Match 并返回 304
+	w.Header().Set("ETag", h.etag(name, data))
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+}
+
+func (h *Handler) etag(name string, data []byte) string {
+	if h.embedded {
+		if v, ok := h.etags.Load(name); ok {
+			return v.(string)
+		}
+	}
+	sum := sha256.Sum256(data)
+	tag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	if h.embedded {
+		h.etags.Store(name, tag)
+	}
+	return tag
+}
+
+// HandleTheme 以 JSON 返回配置中的主题（颜色、标语、展示字段），前端据此覆盖默认样式
+func (h *Handler) HandleTheme(w http.ResponseWriter, r *http.Request) {
+	data, err := json.Marshal(h.ConfigManager.GetConfig().Theme)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	_, _ = w.Write(data)
+}
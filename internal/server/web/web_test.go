@@ -0,0 +1,82 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedAssetsWithETag(t *testing.T) {
+	h := NewHandler(nil, "", "")
+
+	// 1. 根路径返回内嵌的 index.html，且不引用任何 CDN
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET / = %d", rec.Code)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "cdn.") || strings.Contains(body, "googleapis") {
+		t.Error("index.html should not reference external CDNs")
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("missing ETag")
+	}
+
+	// 2. 携带相同的 If-None-Match 时返回 304
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("conditional GET = %d, want 304", rec.Code)
+	}
+
+	// 3. 不存在的资源返回 404
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing.js", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /missing.js = %d, want 404", rec.Code)
+	}
+}
+
+func TestWebDirOverride(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("custom theme"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	NewHandler(nil, dir, "").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Body.String() != "custom theme" {
+		t.Errorf("web-dir override not used, got %q", rec.Body.String())
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	h := NewHandler(nil, "", "s3cret")
+
+	// 1. 配置了 overlay token 时缺少 key 返回 401
+	rec := httptest.NewRecorder()
+	h.HandleOverlay(rec, httptest.NewRequest(http.MethodGet, "/overlay", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("overlay without key = %d, want 401", rec.Code)
+	}
+
+	// 2. 查询参数按上下文转义，无法注入脚本或样式
+	rec = httptest.NewRecorder()
+	h.HandleOverlay(rec, httptest.NewRequest(http.MethodGet, "/overlay?key=s3cret&position=top-right&client=%3C/script%3E&bg=red;}body{", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("overlay = %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if strings.Contains(body, "</script>\";") || strings.Contains(body, "red;}body{") {
+		t.Error("query parameters should be escaped")
+	}
+	if !strings.Contains(body, "justify-content: flex-end") {
+		t.Error("position not applied")
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// SetCalendarStatus 设置日程中正在进行的忙碌事件，nil 表示没有。
+// 期间 "me" 与所有在线客户端显示为忙碌并隐藏窗口标题，以日程文字作为手动状态；用户自己设置的手动状态优先
+func (s *WindowService) SetCalendarStatus(status *naniwosurunov1.CustomStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if proto.Equal(s.calendar, status) {
+		return
+	}
+	s.calendar = status
+	if status == nil {
+		log.Println("Calendar: no busy event")
+	} else {
+		log.Printf("Calendar: %s", status.Text)
+	}
+	s.refreshLocked(time.Now())
+}
+
+// applyCalendarLocked 将当前的日程状态同步到客户端，返回是否发生了变化，调用方需持有锁
+func (s *WindowService) applyCalendarLocked(state *ClientState, now time.Time) bool {
+	busy := s.calendarStatus(now)
+	if proto.Equal(state.Calendar, busy) {
+		return false
+	}
+	state.Calendar = busy
+	return true
+}
+
+// calendarStatus 返回当前生效的日程状态，日程停止更新后按其到期时间失效，调用方需持有锁
+func (s *WindowService) calendarStatus(now time.Time) *naniwosurunov1.CustomStatus {
+	if s.calendar == nil || (s.calendar.Until > 0 && now.UnixMilli() >= s.calendar.Until) {
+		return nil
+	}
+	return s.calendar
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/r3labs/sse/v2"
+)
+
+func TestCalendarStatus(t *testing.T) {
+	s := NewWindowService(NewEventBroker(sse.New(), nil, 0), staticSession{}, nil)
+	report := connect.NewRequest(&naniwosurunov1.ReportWindowRequest{Title: "GoLand", Os: "linux"})
+	report.Header().Set("Authorization", "Bearer token")
+	if _, err := s.ReportWindow(context.Background(), report); err != nil {
+		t.Fatal(err)
+	}
+	meeting := &naniwosurunov1.CustomStatus{Text: "In a meeting until 15:30", Emoji: "📅", Until: time.Now().Add(time.Hour).UnixMilli()}
+
+	// 1. 忙碌事件期间 "me" 与客户端的条目都显示为忙碌，以日程文字代替窗口标题
+	s.SetCalendarStatus(meeting)
+	for _, key := range []string{PrimaryClientName, "laptop"} {
+		if ev, _ := s.LookupClient(key); ev.Status != StatusBusy || ev.Title != "" || ev.CustomStatus.GetText() != meeting.Text {
+			t.Errorf("%s during meeting = %q (%s) %v", key, ev.Title, ev.Status, ev.CustomStatus)
+		}
+	}
+
+	// 2. 期间的上报同样不公开窗口标题
+	events, cancel := s.broker.Subscribe(8)
+	defer cancel()
+	report = connect.NewRequest(&naniwosurunov1.ReportWindowRequest{Title: "Slack", Os: "linux"})
+	report.Header().Set("Authorization", "Bearer token")
+	if _, err := s.ReportWindow(context.Background(), report); err != nil {
+		t.Fatal(err)
+	}
+	if ev := <-events; ev.ClientId != "laptop-id" || ev.Status != StatusBusy || ev.Title != "" {
+		t.Errorf("focus event during meeting = %q (%s) from %s", ev.Title, ev.Status, ev.ClientId)
+	}
+
+	// 3. 用户自己设置的手动状态优先于日程文字
+	set := connect.NewRequest(&naniwosurunov1.SetCustomStatusRequest{Status: &naniwosurunov1.CustomStatus{Text: "Presenting"}})
+	set.Header().Set("Authorization", "Bearer token")
+	if _, err := s.SetCustomStatus(context.Background(), set); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{PrimaryClientName, "laptop"} {
+		if ev, _ := s.LookupClient(key); ev.Status != StatusBusy || ev.CustomStatus.GetText() != "Presenting" {
+			t.Errorf("%s with custom status = %s %v", key, ev.Status, ev.CustomStatus)
+		}
+	}
+
+	// 4. 事件结束后恢复
+	s.SetCalendarStatus(nil)
+	for _, key := range []string{PrimaryClientName, "laptop"} {
+		if ev, _ := s.LookupClient(key); ev.Status != StatusOnline || ev.Title != "Slack" {
+			t.Errorf("%s after meeting = %q (%s)", key, ev.Title, ev.Status)
+		}
+	}
+}
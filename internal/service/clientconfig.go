@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"connectrpc.com/connect"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultPollInterval      = 5 * time.Second
+	defaultHeartbeatInterval = 120 * time.Second
+	// missedHeartbeats 为判定客户端离线前允许错过的心跳次数
+	missedHeartbeats = 3
+)
+
+// heartbeatInterval 返回客户端在轮询模式下的心跳间隔，未配置时使用默认值
+func heartbeatInterval(cfg pkg.ClientPolicyConfig) time.Duration {
+	if cfg.HeartbeatInterval <= 0 {
+		return defaultHeartbeatInterval
+	}
+	return time.Duration(cfg.HeartbeatInterval) * time.Second
+}
+
+// clientSettings 根据配置生成下发给客户端的设置，revision 为设置内容的摘要，内容不变时保持不变
+func clientSettings(cfg *pkg.AppConfig) *naniwosurunov1.GetClientConfigResponse {
+	policy := cfg.ClientPolicy
+	poll := defaultPollInterval
+	if policy.PollInterval > 0 {
+		poll = time.Duration(policy.PollInterval) * time.Second
+	}
+	settings := &naniwosurunov1.GetClientConfigResponse{
+		PollInterval:      int64(poll / time.Second),
+		HeartbeatInterval: int64(heartbeatInterval(policy) / time.Second),
+		Hide:              policy.Privacy.Hide,
+		Redact:            policy.Privacy.Redact,
+		Placeholder:       policy.Privacy.Placeholder,
+		Fields:            policy.Fields,
+	}
+	data, _ := proto.MarshalOptions{Deterministic: true}.Marshal(settings)
+	sum := sha256.Sum256(data)
+	settings.Revision = hex.EncodeToString(sum[:8])
+	return settings
+}
+
+// appConfig 返回当前配置，未提供 ConfigManager 时（如测试）使用零值配置
+func (s *WindowService) appConfig() *pkg.AppConfig {
+	if s.configManager == nil {
+		return &pkg.AppConfig{}
+	}
+	return s.configManager.GetConfig()
+}
+
+// clientTimeout 为判定客户端离线的心跳超时，随下发的心跳间隔变化，避免两者不一致
+func (s *WindowService) clientTimeout() time.Duration {
+	return missedHeartbeats * heartbeatInterval(s.appConfig().ClientPolicy)
+}
+
+// configRevision 返回当前客户端设置的版本，随心跳与 Presence 确认告知客户端
+func (s *WindowService) configRevision() string {
+	return clientSettings(s.appConfig()).Revision
+}
+
+// GetClientConfig 返回服务端推荐的客户端设置
+func (s *WindowService) GetClientConfig(ctx context.Context, req *connect.Request[naniwosurunov1.GetClientConfigRequest]) (*connect.Response[naniwosurunov1.GetClientConfigResponse], error) {
+	if _, err := s.authenticate(req.Header()); err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(clientSettings(s.appConfig())), nil
+}
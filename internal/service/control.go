@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+)
+
+const (
+	// StatusPaused 表示客户端的上报被管理员暂停，窗口标题不会公开
+	StatusPaused = "paused"
+	// StatusBusy 表示客户端被管理员强制显示为忙碌，窗口标题不会公开
+	StatusBusy = "busy"
+)
+
+// control 返回客户端当前生效的控制模式，未设置或已到期时返回空字符串
+func (c *ClientState) control(now time.Time) string {
+	if c.Control == "" || (!c.ControlUntil.IsZero() && !now.Before(c.ControlUntil)) {
+		return ""
+	}
+	return c.Control
+}
+
+// savedControl 是持久化的控制，服务端重启后仍然生效
+type savedControl struct {
+	Mode  string `json:"mode"`
+	Until int64  `json:"until,omitempty"` // 到期时间 (Unix 毫秒)，0 表示直到恢复
+	Self  bool   `json:"self,omitempty"`  // 由客户端以自己的会话令牌设置（如本机暂停），否则为管理员设置
+}
+
+// active 判断控制在 now 时是否仍然生效
+func (c savedControl) active(now time.Time) bool {
+	return c.Until == 0 || now.Before(c.until())
+}
+
+func (c savedControl) until() time.Time {
+	if c.Until == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(c.Until)
+}
+
+// LoadControls 从 path 读取持久化的控制，之后的每次变化都写回该文件。文件不存在时从空开始，已到期的控制被丢弃
+func (s *WindowService) LoadControls(path string) error {
+	controls := make(map[string]savedControl)
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(data, &controls); err != nil {
+			return fmt.Errorf("解析 %s 失败: %w", path, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.controlsPath = path
+	now := time.Now()
+	for id, saved := range controls {
+		if !saved.active(now) {
+			continue
+		}
+		s.controls[id] = saved
+		if state, ok := s.clients[id]; ok {
+			state.Control, state.ControlUntil = saved.Mode, saved.until()
+		}
+	}
+	s.refreshLocked(now)
+	return nil
+}
+
+// saveControlsLocked 将生效中的控制写入持久化文件，调用方需持有锁
+func (s *WindowService) saveControlsLocked() {
+	now := time.Now()
+	for id, saved := range s.controls {
+		if !saved.active(now) {
+			delete(s.controls, id)
+		}
+	}
+	if s.controlsPath == "" {
+		return
+	}
+	data, err := json.MarshalIndent(s.controls, "", "  ")
+	if err == nil {
+		tmp := s.controlsPath + ".tmp"
+		if err = os.WriteFile(tmp, data, 0o600); err == nil {
+			err = os.Rename(tmp, s.controlsPath)
+		}
+	}
+	if err != nil {
+		log.Printf("保存客户端控制失败: %v", err)
+	}
+}
+
+// controlTargetID 将尚未连接过的控制目标解析为客户端 ID：配置中名称或 ID 匹配的客户端优先，否则视为客户端 ID
+func (s *WindowService) controlTargetID(target string) string {
+	for _, c := range s.appConfig().Clients {
+		if c.ID == target || c.Name == target {
+			return c.ID
+		}
+	}
+	return target
+}
+
+// controlMessage 返回随心跳与 Presence 确认下发给客户端的控制，未设置时返回 nil
+func (c *ClientState) controlMessage(now time.Time) *naniwosurunov1.ClientControl {
+	mode := c.control(now)
+	if mode == "" {
+		return nil
+	}
+	msg := &naniwosurunov1.ClientControl{Mode: mode}
+	if !c.ControlUntil.IsZero() {
+		msg.Until = c.ControlUntil.UnixMilli()
+	}
+	return msg
+}
+
+// visibleTitle 返回可以公开的窗口标题，暂停或忙碌时隐藏
+func (c *ClientState) visibleTitle() string {
+	if c.Status == StatusPaused || c.Status == StatusBusy {
+		return ""
+	}
+	return c.LastTitle
+}
+
+// controlWatch 返回客户端当前的控制以及在控制变化时关闭的通道，供 Presence 流主动推送
+func (s *WindowService) controlWatch(clientID string) (*naniwosurunov1.ClientControl, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.clients[clientID]
+	if !ok {
+		return nil, nil
+	}
+	if state.controlChanged == nil {
+		state.controlChanged = make(chan struct{})
+	}
+	return state.controlMessage(time.Now()), state.controlChanged
+}
+
+// ControlClient 设置或清除客户端的控制模式，暂停与忙碌期间服务端以对应状态代替窗口标题发布。
+// 持有服务端 AdminToken 时可以控制任意客户端，客户端的会话令牌只能控制自身，且不能改变或清除管理员设置的控制。
+// 控制会被持久化；目标客户端尚未连接时控制在其首次出现时生效
+func (s *WindowService) ControlClient(ctx context.Context, req *connect.Request[naniwosurunov1.ControlClientRequest]) (*connect.Response[naniwosurunov1.ControlClientResponse], error) {
+	target := req.Msg.Client
+	self := false
+	if err := s.authorizeAdmin(req.Header()); err != nil {
+		// 客户端可以用自己的会话令牌控制自身，例如在本机暂停上报
+		session, authErr := s.authenticate(req.Header())
+		if authErr != nil {
+			return nil, err
+		}
+		if target != "" && target != session.ClientID && target != session.Name {
+			return nil, connect.NewError(connect.CodePermissionDenied, errors.New("clients can only control themselves"))
+		}
+		target = session.ClientID
+		self = true
+	}
+
+	control := req.Msg.Control
+	if control == nil {
+		control = &naniwosurunov1.ClientControl{}
+	}
+	switch control.Mode {
+	case "", StatusPaused, StatusBusy:
+	default:
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("mode must be empty, \"paused\" or \"busy\""))
+	}
+	var until time.Time
+	if control.Until > 0 {
+		until = time.UnixMilli(control.Until)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if saved, ok := s.controls[target]; self && ok && !saved.Self && saved.active(time.Now()) {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, errors.New("control was set by an administrator"))
+	}
+	var targets []*ClientState
+	for _, state := range s.clients {
+		if target == "" || state.ID == target || state.Name == target {
+			// 中继转发来的客户端无法收到控制，暂停后也不会停止上报
+			if state.RelayedBy != "" {
+				if target != "" {
+					return nil, connect.NewError(connect.CodeFailedPrecondition, errors.New("client is relayed, control it on the downstream server"))
+				}
+				continue
+			}
+			targets = append(targets, state)
+		}
+	}
+	res := &naniwosurunov1.ControlClientResponse{}
+	if len(targets) == 0 {
+		if target == "" {
+			return nil, connect.NewError(connect.CodeNotFound, errors.New("no such client"))
+		}
+		id := s.controlTargetID(target)
+		if control.Mode == "" {
+			delete(s.controls, id)
+			log.Printf("Client %s resumed", id)
+		} else {
+			s.controls[id] = savedControl{Mode: control.Mode, Until: control.Until, Self: self}
+			log.Printf("Client %s %s, applied when it connects", id, control.Mode)
+		}
+		s.saveControlsLocked()
+		res.Clients = append(res.Clients, target)
+		return connect.NewResponse(res), nil
+	}
+
+	if target == "" && control.Mode == "" {
+		clear(s.controls)
+	}
+	for _, state := range targets {
+		state.Control = control.Mode
+		state.ControlUntil = until
+		if control.Mode == "" {
+			delete(s.controls, state.ID)
+		} else {
+			s.controls[state.ID] = savedControl{Mode: control.Mode, Until: control.Until, Self: self}
+		}
+		if state.controlChanged != nil {
+			close(state.controlChanged)
+			state.controlChanged = nil
+		}
+		switch {
+		case control.Mode == "":
+			log.Printf("Client %s resumed", state.Name)
+		case until.IsZero():
+			log.Printf("Client %s %s", state.Name, control.Mode)
+		default:
+			log.Printf("Client %s %s until %s", state.Name, control.Mode, until.Format(time.RFC3339))
+		}
+		res.Clients = append(res.Clients, state.Name)
+	}
+	s.saveControlsLocked()
+	s.refreshLocked(time.Now())
+	return connect.NewResponse(res), nil
+}
+
+// authorizeAdmin 校验管理操作的凭据：服务端配置中的 AdminToken。
+// 不使用 v0 客户端同样持有的静态 Token，未配置 AdminToken 时只允许客户端控制自身
+func (s *WindowService) authorizeAdmin(header http.Header) error {
+	token := strings.TrimPrefix(header.Get("Authorization"), "Bearer ")
+	expected := s.appConfig().AdminToken
+	if expected == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		return connect.NewError(connect.CodePermissionDenied, errors.New("admin token required"))
+	}
+	return nil
+}
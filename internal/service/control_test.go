@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+	"github.com/r3labs/sse/v2"
+)
+
+func TestControlClient(t *testing.T) {
+	cm, err := pkg.NewConfigManagerWithLoader(&pkg.JSONConfigLoader{DataDir: t.TempDir(), FileName: "config.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm.GetConfig().AdminToken = "admin-token"
+	s := NewWindowService(NewEventBroker(sse.New(), nil, 0), staticSession{}, cm)
+	report := func(title string) {
+		t.Helper()
+		req := connect.NewRequest(&naniwosurunov1.ReportWindowRequest{Title: title, Os: "linux"})
+		req.Header().Set("Authorization", "Bearer token")
+		if _, err := s.ReportWindow(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	control := func(token, mode string, until time.Time) error {
+		t.Helper()
+		msg := &naniwosurunov1.ClientControl{Mode: mode}
+		if !until.IsZero() {
+			msg.Until = until.UnixMilli()
+		}
+		req := connect.NewRequest(&naniwosurunov1.ControlClientRequest{Client: "laptop", Control: msg})
+		req.Header().Set("Authorization", "Bearer "+token)
+		_, err := s.ControlClient(context.Background(), req)
+		return err
+	}
+	report("GoLand")
+
+	// 1. 客户端的会话令牌只能控制自身，控制其他客户端需要 AdminToken
+	other := connect.NewRequest(&naniwosurunov1.ControlClientRequest{Client: "desktop", Control: &naniwosurunov1.ClientControl{Mode: StatusPaused}})
+	other.Header().Set("Authorization", "Bearer token")
+	if _, err := s.ControlClient(context.Background(), other); connect.CodeOf(err) != connect.CodePermissionDenied {
+		t.Fatalf("control other client with session token = %v, want permission denied", err)
+	}
+	if err := control("token", "", time.Time{}); err != nil {
+		t.Fatalf("control self with session token: %v", err)
+	}
+
+	// 2. v0 客户端持有的静态 Token 不能用于管理操作
+	if err := s.authorizeAdmin(http.Header{"Authorization": {"Bearer " + cm.GetConfig().Token}}); connect.CodeOf(err) != connect.CodePermissionDenied {
+		t.Fatalf("static token accepted as admin: %v", err)
+	}
+
+	// 3. 暂停后客户端与 "me" 都显示为 paused，不再公开标题，新的上报被忽略
+	admin := cm.GetConfig().AdminToken
+	if err := control(admin, StatusPaused, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	report("Slack")
+	for _, key := range []string{"laptop", PrimaryClientName} {
+		if ev, _ := s.LookupClient(key); ev.Status != StatusPaused || ev.Title != "" {
+			t.Errorf("%s = %q (%s), want paused without title", key, ev.Title, ev.Status)
+		}
+	}
+
+	// 4. 客户端不能用自己的会话令牌清除管理员设置的控制
+	if err := control("token", "", time.Time{}); connect.CodeOf(err) != connect.CodeFailedPrecondition {
+		t.Errorf("self resume of admin control = %v, want failed precondition", err)
+	}
+
+	// 5. 心跳响应携带控制，客户端据此停止上报
+	beat := connect.NewRequest(&naniwosurunov1.HeartbeatRequest{Epoch: "first", Count: 1})
+	beat.Header().Set("Authorization", "Bearer token")
+	res, err := s.Heartbeat(context.Background(), beat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Msg.Control.GetMode() != StatusPaused {
+		t.Errorf("heartbeat control = %v, want paused", res.Msg.Control)
+	}
+
+	// 6. 控制到期后恢复为在线，恢复后的上报正常发布
+	if err := control(admin, StatusBusy, time.Now().Add(50*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if ev, _ := s.LookupClient("laptop"); ev.Status != StatusBusy {
+		t.Fatalf("status = %s, want busy", ev.Status)
+	}
+	time.Sleep(100 * time.Millisecond)
+	report("Firefox")
+	if ev, _ := s.LookupClient("laptop"); ev.Status != StatusOnline || ev.Title != "Firefox" {
+		t.Errorf("after expiry = %q (%s), want Firefox online", ev.Title, ev.Status)
+	}
+}
+
+func TestControlPersisted(t *testing.T) {
+	cm, err := pkg.NewConfigManagerWithLoader(&pkg.JSONConfigLoader{DataDir: t.TempDir(), FileName: "config.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm.GetConfig().AdminToken = "admin-token"
+	// 尚未连接的客户端按配置中的名称解析为 ID
+	cm.GetConfig().Clients = []pkg.ClientConfig{{ID: "laptop-id", Name: "laptop"}}
+	path := filepath.Join(t.TempDir(), "controls.json")
+	newService := func() *WindowService {
+		t.Helper()
+		s := NewWindowService(NewEventBroker(sse.New(), nil, 0), staticSession{}, cm)
+		if err := s.LoadControls(path); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	control := func(s *WindowService, client, mode string) error {
+		t.Helper()
+		req := connect.NewRequest(&naniwosurunov1.ControlClientRequest{Client: client, Control: &naniwosurunov1.ClientControl{Mode: mode}})
+		req.Header().Set("Authorization", "Bearer "+cm.GetConfig().AdminToken)
+		_, err := s.ControlClient(context.Background(), req)
+		return err
+	}
+
+	// 1. 可以暂停服务端启动后尚未出现过的客户端
+	s := newService()
+	s.ReportLegacyWindow("desktop-id", "desktop", "Steam", "windows")
+	if err := control(s, "laptop", StatusPaused); err != nil {
+		t.Fatalf("pause unseen client: %v", err)
+	}
+	if err := control(s, "desktop", StatusBusy); err != nil {
+		t.Fatal(err)
+	}
+
+	// 2. 重启后控制仍然生效，客户端首次出现时即被暂停
+	s = newService()
+	s.ReportLegacyWindow("laptop-id", "laptop", "GoLand", "linux")
+	s.ReportLegacyWindow("desktop-id", "desktop", "Steam", "windows")
+	if ev, _ := s.LookupClient("laptop"); ev.Status != StatusPaused || ev.Title != "" {
+		t.Errorf("laptop after restart = %q (%s), want paused", ev.Title, ev.Status)
+	}
+	if ev, _ := s.LookupClient("desktop"); ev.Status != StatusBusy {
+		t.Errorf("desktop after restart = %s, want busy", ev.Status)
+	}
+
+	// 3. 恢复后从持久化文件中移除
+	if err := control(s, "laptop", ""); err != nil {
+		t.Fatal(err)
+	}
+	s = newService()
+	s.ReportLegacyWindow("laptop-id", "laptop", "GoLand", "linux")
+	if ev, _ := s.LookupClient("laptop"); ev.Status != StatusOnline {
+		t.Errorf("laptop after resume and restart = %s, want online", ev.Status)
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"connectrpc.com/connect"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	maxCustomText  = 128 // 手动状态文字的最大长度（字符数）
+	maxCustomEmoji = 16  // 表情的最大长度，组合表情可能由多个码点组成
+)
+
+// customStatus 返回客户端当前生效的手动状态，未设置或已到期时返回 nil
+func (c *ClientState) customStatus(now time.Time) *naniwosurunov1.CustomStatus {
+	if c.Custom == nil || (c.Custom.Until > 0 && now.UnixMilli() >= c.Custom.Until) {
+		return nil
+	}
+	return c.Custom
+}
+
+// expireCustom 清除已到期的手动状态，返回是否发生了清除
+func (c *ClientState) expireCustom(now time.Time) bool {
+	if c.Custom == nil || c.customStatus(now) != nil {
+		return false
+	}
+	log.Printf("Client %s custom status expired", c.Name)
+	c.Custom = nil
+	return true
+}
+
+// primaryCustomLocked 返回 "me" 展示的手动状态：优先使用被选中客户端的状态，否则使用最近设置的一条，调用方需持有锁
+func (s *WindowService) primaryCustomLocked(selected *ClientState, now time.Time) *naniwosurunov1.CustomStatus {
+	if selected != nil {
+		if custom := selected.customStatus(now); custom != nil {
+			return custom
+		}
+	}
+	var latest *ClientState
+	for _, state := range s.clients {
+		if state.customStatus(now) != nil && (latest == nil || state.CustomSetAt.After(latest.CustomSetAt)) {
+			latest = state
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+	return latest.Custom
+}
+
+// setCustomLocked 更新客户端的手动状态，内容变化时发布 presence 事件，调用方需持有锁
+func (s *WindowService) setCustomLocked(state *ClientState, custom *naniwosurunov1.CustomStatus, now time.Time) bool {
+	if proto.Equal(state.Custom, custom) {
+		return false
+	}
+	state.Custom = custom
+	state.CustomSetAt = now
+	s.broker.Publish(state.toEvent(EventTypePresence))
+	return true
+}
+
+// SetCustomStatus 设置或清除客户端自己的手动状态。状态与检测到的窗口一同发布，到期后由超时巡检自动清除
+func (s *WindowService) SetCustomStatus(ctx context.Context, req *connect.Request[naniwosurunov1.SetCustomStatusRequest]) (*connect.Response[naniwosurunov1.SetCustomStatusResponse], error) {
+	session, err := s.authenticate(req.Header())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var custom *naniwosurunov1.CustomStatus
+	if msg := req.Msg.Status; msg != nil && strings.TrimSpace(msg.Text) != "" {
+		custom = &naniwosurunov1.CustomStatus{
+			Text:  strings.TrimSpace(msg.Text),
+			Emoji: strings.TrimSpace(msg.Emoji),
+			Until: msg.Until,
+		}
+		switch {
+		case utf8.RuneCountInString(custom.Text) > maxCustomText:
+			return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("status text is too long"))
+		case utf8.RuneCountInString(custom.Emoji) > maxCustomEmoji:
+			return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("emoji is too long"))
+		case custom.Until < 0 || (custom.Until > 0 && custom.Until <= now.UnixMilli()):
+			return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("until must be in the future"))
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.getOrCreateStateLocked(session)
+	if s.setCustomLocked(state, custom, now) {
+		switch {
+		case custom == nil:
+			log.Printf("Client %s cleared custom status", state.Name)
+		case custom.Until == 0:
+			log.Printf("Client %s set custom status: %s %s", state.Name, custom.Emoji, custom.Text)
+		default:
+			log.Printf("Client %s set custom status: %s %s until %s", state.Name, custom.Emoji, custom.Text,
+				time.UnixMilli(custom.Until).Format(time.RFC3339))
+		}
+		s.recomputePrimaryLocked(now)
+	}
+	return connect.NewResponse(&naniwosurunov1.SetCustomStatusResponse{}), nil
+}
+
+// CustomStatusText 将手动状态格式化为 "🍜 Lunch"，未设置时返回空字符串
+func CustomStatusText(custom *naniwosurunov1.CustomStatus) string {
+	if custom == nil || custom.Text == "" {
+		return ""
+	}
+	if custom.Emoji == "" {
+		return custom.Text
+	}
+	return custom.Emoji + " " + custom.Text
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/r3labs/sse/v2"
+)
+
+func TestSetCustomStatus(t *testing.T) {
+	s := NewWindowService(NewEventBroker(sse.New(), nil, 0), staticSession{}, nil)
+	set := func(status *naniwosurunov1.CustomStatus) error {
+		t.Helper()
+		req := connect.NewRequest(&naniwosurunov1.SetCustomStatusRequest{Status: status})
+		req.Header().Set("Authorization", "Bearer token")
+		_, err := s.SetCustomStatus(context.Background(), req)
+		return err
+	}
+	report := connect.NewRequest(&naniwosurunov1.ReportWindowRequest{Title: "GoLand", Os: "linux"})
+	report.Header().Set("Authorization", "Bearer token")
+	if _, err := s.ReportWindow(context.Background(), report); err != nil {
+		t.Fatal(err)
+	}
+
+	// 1. 未登录时拒绝，已过期的到期时间视为无效参数
+	anonymous := connect.NewRequest(&naniwosurunov1.SetCustomStatusRequest{Status: &naniwosurunov1.CustomStatus{Text: "Lunch"}})
+	if _, err := s.SetCustomStatus(context.Background(), anonymous); connect.CodeOf(err) != connect.CodeUnauthenticated {
+		t.Errorf("set without token = %v, want unauthenticated", err)
+	}
+	if err := set(&naniwosurunov1.CustomStatus{Text: "Lunch", Until: time.Now().Add(-time.Minute).UnixMilli()}); connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Errorf("set with past until = %v, want invalid argument", err)
+	}
+
+	// 2. 手动状态与检测到的窗口一同出现在客户端与 "me" 的条目中
+	if err := set(&naniwosurunov1.CustomStatus{Text: " Lunch ", Emoji: "🍜", Until: time.Now().Add(50 * time.Millisecond).UnixMilli()}); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"laptop", PrimaryClientName} {
+		ev, _ := s.LookupClient(key)
+		if ev.Title != "GoLand" || CustomStatusText(ev.CustomStatus) != "🍜 Lunch" {
+			t.Errorf("%s = %q with %v, want GoLand with 🍜 Lunch", key, ev.Title, ev.CustomStatus)
+		}
+	}
+
+	// 3. 到期后由巡检清除，"me" 随之更新
+	time.Sleep(100 * time.Millisecond)
+	s.mu.Lock()
+	s.refreshLocked(time.Now())
+	s.mu.Unlock()
+	for _, key := range []string{"laptop", PrimaryClientName} {
+		if ev, _ := s.LookupClient(key); ev.CustomStatus != nil {
+			t.Errorf("%s custom status after expiry = %v, want none", key, ev.CustomStatus)
+		}
+	}
+
+	// 4. 文字为空表示清除
+	if err := set(&naniwosurunov1.CustomStatus{Text: "Focus"}); err != nil {
+		t.Fatal(err)
+	}
+	if ev, _ := s.LookupClient(PrimaryClientName); ev.CustomStatus.GetText() != "Focus" || ev.CustomStatus.GetUntil() != 0 {
+		t.Errorf("me custom status = %v, want Focus without expiry", ev.CustomStatus)
+	}
+	if err := set(nil); err != nil {
+		t.Fatal(err)
+	}
+	if ev, _ := s.LookupClient(PrimaryClientName); ev.CustomStatus != nil {
+		t.Errorf("me custom status after clear = %v, want none", ev.CustomStatus)
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/internal/history"
+	"github.com/r3labs/sse/v2"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	// EventSchemaVersion 是当前事件信封的结构版本
+	EventSchemaVersion = 1
+
+	// EventStream 是 SSE 服务端中承载所有窗口事件的流名称
+	EventStream = "focus"
+
+	EventTypePresence = "presence" // 上线、离线、空闲等状态变化
+	EventTypeFocus    = "focus"    // 焦点窗口变化
+	EventTypeSnapshot = "snapshot" // 订阅开始或断档过大时发送的全量状态
+	EventTypePeer     = "peer"     // 联邦对端服务器的连接状态
+
+	defaultMaxReplay = 500
+)
+
+// eventMarshaler 统一事件的 JSON 格式：使用 proto 字段名并输出零值字段，保证前端总能读到 title 等字段
+var eventMarshaler = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+
+// MarshalEvent 将事件信封序列化为 JSON
+func MarshalEvent(ev *naniwosurunov1.WindowEvent) ([]byte, error) {
+	return eventMarshaler.Marshal(ev)
+}
+
+// MarshalSnapshot 以与事件相同的 JSON 格式序列化快照
+func MarshalSnapshot(snap *naniwosurunov1.GetSnapshotResponse) ([]byte, error) {
+	return eventMarshaler.Marshal(snap)
+}
+
+// EventBroker 为事件补全信封字段（版本、ID、时间戳），写入历史，并分发到 SSE 流与进程内订阅者。
+// 发布后的事件会被多个订阅者共享，任何一方都不应再修改它。
+type EventBroker struct {
+	sseServer   *sse.Server
+	history     history.Store
+	maxReplay   int
+	lastID      uint64
+	subscribers map[chan *naniwosurunov1.WindowEvent]struct{}
+	mu          sync.Mutex
+}
+
+// NewEventBroker 创建事件分发器，事件 ID 从历史中的最后一个 ID 继续递增；store 为 nil 时不支持续传
+func NewEventBroker(sse *sse.Server, store history.Store, maxReplay int) *EventBroker {
+	if maxReplay <= 0 {
+		maxReplay = defaultMaxReplay
+	}
+	b := &EventBroker{
+		sseServer:   sse,
+		history:     store,
+		maxReplay:   maxReplay,
+		subscribers: make(map[chan *naniwosurunov1.WindowEvent]struct{}),
+	}
+	if store != nil {
+		b.lastID = store.LastID()
+	}
+	return b
+}
+
+// Publish 补全事件信封，以事件类型作为 SSE 的 event 名称、事件 ID 作为 SSE 的 id 发布
+func (b *EventBroker) Publish(ev *naniwosurunov1.WindowEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.appendLocked(ev)
+
+	payload, err := MarshalEvent(ev)
+	if err != nil {
+		log.Printf("序列化事件失败: %v", err)
+		return
+	}
+	b.sseServer.TryPublish(EventStream, &sse.Event{
+		ID:    []byte(strconv.FormatUint(ev.Id, 10)),
+		Event: []byte(ev.Type),
+		Data:  payload,
+	})
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			// 消费过慢的订阅者直接断开，由其携带最后的事件 ID 重连续传
+			log.Printf("事件订阅者消费过慢，已断开")
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Record 补全事件信封并只写入历史，不推送到 SSE 与订阅者，用于客户端补报的过往事件
+func (b *EventBroker) Record(ev *naniwosurunov1.WindowEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.appendLocked(ev)
+}
+
+// appendLocked 分配事件 ID、补全信封字段并写入历史，调用方需持有锁
+func (b *EventBroker) appendLocked(ev *naniwosurunov1.WindowEvent) {
+	b.lastID++
+	ev.Id = b.lastID
+	ev.SchemaVersion = EventSchemaVersion
+	if ev.Timestamp == 0 {
+		ev.Timestamp = time.Now().UnixMilli()
+	}
+
+	if b.history != nil {
+		if err := b.history.Append(ev); err != nil {
+			log.Printf("写入事件历史失败: %v", err)
+		}
+	}
+}
+
+// Subscribe 注册一个进程内订阅者，返回的 channel 在订阅者过慢或取消时被关闭
+func (b *EventBroker) Subscribe(buffer int) (<-chan *naniwosurunov1.WindowEvent, func()) {
+	ch := make(chan *naniwosurunov1.WindowEvent, buffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel
+}
+
+// LastID 返回最近一次发布的事件 ID
+func (b *EventBroker) LastID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastID
+}
+
+// Since 从历史中取出 ID 大于 id 的事件；断档或需要重放的事件过多时 ok 为 false
+func (b *EventBroker) Since(id uint64) ([]*naniwosurunov1.WindowEvent, bool) {
+	if b.history == nil {
+		return nil, false
+	}
+	events, ok := b.history.Since(id)
+	if !ok || len(events) > b.maxReplay {
+		return nil, false
+	}
+	return events, true
+}
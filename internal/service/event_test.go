@@ -0,0 +1,45 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/r3labs/sse/v2"
+)
+
+func TestEventBrokerEnvelope(t *testing.T) {
+	broker := NewEventBroker(sse.New(), nil, 0)
+
+	first := &naniwosurunov1.WindowEvent{Title: "a \"quoted\"   title", Type: EventTypeFocus}
+	second := &naniwosurunov1.WindowEvent{Type: EventTypePresence, Status: StatusOffline}
+	broker.Publish(first)
+	broker.Publish(second)
+
+	if first.SchemaVersion != EventSchemaVersion || first.Timestamp == 0 {
+		t.Errorf("envelope not filled: %+v", first)
+	}
+	if second.Id <= first.Id {
+		t.Errorf("event ids not monotonic: %d then %d", first.Id, second.Id)
+	}
+
+	// 序列化结果必须是合法 JSON，并且包含零值字段
+	data, err := MarshalEvent(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("invalid JSON %s: %v", data, err)
+	}
+	for _, field := range []string{"title", "client_id", "schema_version", "type"} {
+		if _, ok := decoded[field]; !ok {
+			t.Errorf("field %q missing in %s", field, data)
+		}
+	}
+
+	data, _ = MarshalEvent(first)
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded["title"] != first.Title {
+		t.Errorf("title not round-tripped: %s", data)
+	}
+}
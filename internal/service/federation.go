@@ -0,0 +1,114 @@
+package service
+
+import (
+	"log"
+	"strings"
+
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// RemoteSeparator 分隔联邦对端的名称与其客户端，如 "alice/me"、"alice/laptop"
+const RemoteSeparator = "/"
+
+// IsRemote 判断事件是否来自联邦对端。对端自己联邦来的客户端不会被再次转发，避免互相订阅时形成环路
+func IsRemote(ev *naniwosurunov1.WindowEvent) bool {
+	return strings.Contains(ev.ClientId, RemoteSeparator) || ev.Type == EventTypePeer
+}
+
+// remoteEvent 将对端的事件转换到本地命名空间：对端的 "me" 显示为对端名称，其余客户端显示为 "<对端>/<客户端>"
+func remoteEvent(peer string, ev *naniwosurunov1.WindowEvent) *naniwosurunov1.WindowEvent {
+	res := proto.Clone(ev).(*naniwosurunov1.WindowEvent)
+	res.Id = 0
+	res.SchemaVersion = 0
+	res.ClientId = peer + RemoteSeparator + ev.ClientId
+	if ev.ClientId == PrimaryClientName {
+		res.Client = peer
+	} else {
+		res.Client = peer + RemoteSeparator + ev.Client
+	}
+	if res.Type != EventTypeFocus {
+		res.Type = EventTypePresence
+	}
+	return res
+}
+
+// ReplaceRemote 用对端的全量快照替换其所有客户端，对端已不存在的客户端以离线状态发布后移除
+func (s *WindowService) ReplaceRemote(peer string, snap *naniwosurunov1.GetSnapshotResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, ev := range append(snap.Clients, snap.Me) {
+		if ev == nil || IsRemote(ev) {
+			continue
+		}
+		seen[s.applyRemoteLocked(peer, ev)] = true
+	}
+
+	prefix := peer + RemoteSeparator
+	for id, ev := range s.remote {
+		if !strings.HasPrefix(id, prefix) || seen[id] {
+			continue
+		}
+		delete(s.remote, id)
+		gone := proto.Clone(ev).(*naniwosurunov1.WindowEvent)
+		gone.Status = StatusOffline
+		gone.Type = EventTypePresence
+		gone.Timestamp = 0
+		s.broker.Publish(gone)
+	}
+}
+
+// ApplyRemote 合并对端推送的一条实时事件
+func (s *WindowService) ApplyRemote(peer string, ev *naniwosurunov1.WindowEvent) {
+	if IsRemote(ev) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.applyRemoteLocked(peer, ev)
+}
+
+// applyRemoteLocked 保存对端客户端的最新状态，内容变化时重新发布，返回带命名空间的客户端 ID，调用方需持有锁
+func (s *WindowService) applyRemoteLocked(peer string, ev *naniwosurunov1.WindowEvent) string {
+	next := remoteEvent(peer, ev)
+	prev, ok := s.remote[next.ClientId]
+	if ok && prev.Title == next.Title && prev.Status == next.Status && prev.Os == next.Os && prev.Source == next.Source &&
+		proto.Equal(prev.CustomStatus, next.CustomStatus) {
+		return next.ClientId
+	}
+	if !ok || prev.Title != next.Title || prev.Source != next.Source {
+		next.Type = EventTypeFocus
+	} else {
+		next.Type = EventTypePresence
+	}
+
+	s.remote[next.ClientId] = next
+	s.broker.Publish(proto.Clone(next).(*naniwosurunov1.WindowEvent))
+	return next.ClientId
+}
+
+// SetPeerHealth 更新联邦对端的连接状态，状态变化时以 peer 类型的事件发布
+func (s *WindowService) SetPeerHealth(peer string, up bool) {
+	status := StatusOffline
+	if up {
+		status = StatusOnline
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if prev, ok := s.peers[peer]; ok && prev.Status == status {
+		return
+	}
+	log.Printf("联邦对端 %s: %s", peer, status)
+	ev := &naniwosurunov1.WindowEvent{
+		Client:   peer,
+		ClientId: peer,
+		Status:   status,
+		Type:     EventTypePeer,
+	}
+	s.peers[peer] = ev
+	s.broker.Publish(proto.Clone(ev).(*naniwosurunov1.WindowEvent))
+}
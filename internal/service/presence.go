@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"connectrpc.com/connect"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/pkg/auth"
+)
+
+// PresenceKeepaliveTimeout 为 Presence 流上两次消息之间的最长间隔，超时视为客户端已失联
+const PresenceKeepaliveTimeout = 45 * time.Second
+
+// Presence 维持客户端的长连接：每收到一条消息（焦点变化或保活）回复一次确认，
+// 流关闭或超过 keepaliveTimeout 未收到消息时立即将客户端标记为离线，而不必等待心跳超时。
+func (s *WindowService) Presence(ctx context.Context, stream *connect.BidiStream[naniwosurunov1.PresenceUpdate, naniwosurunov1.PresenceAck]) error {
+	session, err := s.authenticate(stream.RequestHeader())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s.openPresence(session)
+	defer s.closePresence(session)
+
+	updates := make(chan *naniwosurunov1.PresenceUpdate)
+	closed := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := stream.Receive()
+			if err != nil {
+				closed <- err
+				return
+			}
+			select {
+			case updates <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// 每次确认都携带最新的设置版本与控制，服务端收到 SIGHUP 重新加载配置后长连接上的客户端也能及时感知；
+	// 控制变化时不等客户端的下一条消息，立即发送一次确认
+	var controlChanged <-chan struct{}
+	ack := func() *naniwosurunov1.PresenceAck {
+		msg := &naniwosurunov1.PresenceAck{KeepaliveTimeout: int64(s.keepaliveTimeout / time.Second), ConfigRevision: s.configRevision()}
+		msg.Control, controlChanged = s.controlWatch(session.ClientID)
+		return msg
+	}
+	if err := stream.Send(ack()); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(s.keepaliveTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.done:
+			return nil
+		case <-closed:
+			// 客户端正常关闭 (io.EOF) 或连接中断，都意味着它不再在线
+			return nil
+		case <-controlChanged:
+			if err := stream.Send(ack()); err != nil {
+				return err
+			}
+		case <-timer.C:
+			log.Printf("Client %s presence keepalive timeout", session.Name)
+			return connect.NewError(connect.CodeDeadlineExceeded, errors.New("presence keepalive timeout"))
+		case msg := <-updates:
+			timer.Reset(s.keepaliveTimeout)
+			if w := msg.Window; w != nil {
+				s.applyReport(session, w.Title, w.Os, w.ObservedAt)
+			} else {
+				s.mu.Lock()
+				s.touchLocked(s.getOrCreateStateLocked(session), "Presence")
+				s.mu.Unlock()
+			}
+			if err := stream.Send(ack()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// openPresence 记录一条新打开的 Presence 流，客户端随即视为在线，原先经由中继上报的状态由 touchLocked 清除
+func (s *WindowService) openPresence(session auth.SessionInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.getOrCreateStateLocked(session)
+	state.Streams++
+	s.touchLocked(state, "Presence")
+}
+
+// closePresence 在客户端的最后一条 Presence 流关闭时将其标记为离线
+func (s *WindowService) closePresence(session auth.SessionInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.getOrCreateStateLocked(session)
+	state.Streams--
+	if state.Streams > 0 || !state.IsOnline {
+		return
+	}
+	state.IsOnline = false
+	log.Printf("Client %s offline (presence stream closed)", state.Name)
+	s.refreshLocked(time.Now())
+}
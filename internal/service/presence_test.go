@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1/naniwosurunov1connect"
+	"github.com/nhirsama/Naniwosuruno/pkg/auth"
+	"github.com/r3labs/sse/v2"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// staticSession 将任意令牌视为同一个客户端的会话
+type staticSession struct {
+	auth.StatefulAuthenticator
+}
+
+func (staticSession) ValidateSession(token string) (auth.SessionInfo, bool) {
+	return auth.SessionInfo{ClientID: "laptop-id", Name: "laptop"}, token != ""
+}
+
+func TestPresenceKeepaliveTimeout(t *testing.T) {
+	s := NewWindowService(NewEventBroker(sse.New(), nil, 0), staticSession{}, nil)
+	s.keepaliveTimeout = 200 * time.Millisecond
+	_, handler := naniwosurunov1connect.NewWindowServiceHandler(s)
+	srv := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	defer srv.Close()
+
+	h2cClient := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := naniwosurunov1connect.NewWindowServiceClient(h2cClient, srv.URL).Presence(ctx)
+	stream.RequestHeader().Set("Authorization", "Bearer token")
+
+	// 1. 打开流并发送一次焦点变化后客户端在线
+	if err := stream.Send(&naniwosurunov1.PresenceUpdate{Window: &naniwosurunov1.ReportWindowRequest{Title: "GoLand", Os: "linux"}}); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if _, err := stream.Receive(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ev, _ := s.LookupClient("laptop"); ev.Status != StatusOnline || ev.Title != "GoLand" {
+		t.Fatalf("client = %+v, want online on GoLand", ev)
+	}
+
+	// 2. 之后不再保活，服务端在超时后结束流并将客户端标记为离线
+	if _, err := stream.Receive(); err == nil {
+		t.Fatal("stream not closed after missed keepalives")
+	}
+	if ev, _ := s.LookupClient("laptop"); ev.Status != StatusOffline {
+		t.Errorf("client status = %s, want offline", ev.Status)
+	}
+}
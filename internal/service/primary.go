@@ -0,0 +1,76 @@
+package service
+
+import (
+	"time"
+
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+const (
+	// PrimaryClientName 是合成的权威活动条目在快照与事件流中使用的客户端名称
+	PrimaryClientName = pkg.PrimaryClientName
+
+	PrimaryStrategyRecent   = "recent"
+	PrimaryStrategyPriority = "priority"
+
+	defaultIdleAfter = 10 * time.Minute
+)
+
+// idleAfter 返回客户端被视为空闲的阈值，未配置时使用默认值
+func idleAfter(cfg pkg.PrimaryConfig) time.Duration {
+	if cfg.IdleAfter <= 0 {
+		return defaultIdleAfter
+	}
+	return time.Duration(cfg.IdleAfter) * time.Second
+}
+
+// resolvePrimary 在所有在线客户端中选出唯一的权威活动，没有在线客户端时返回 nil
+func resolvePrimary(clients map[string]*ClientState, cfg pkg.PrimaryConfig, now time.Time) *ClientState {
+	idle := idleAfter(cfg)
+
+	if cfg.Strategy == PrimaryStrategyPriority {
+		for _, key := range cfg.Priority {
+			for _, state := range clients {
+				if (state.ID == key || state.Name == key) && state.status(now, idle) == StatusOnline {
+					return state
+				}
+			}
+		}
+	}
+
+	// recent 策略（同时作为 priority 策略的兜底）：优先选择最近活跃的非空闲客户端，
+	// 全部空闲时退而选择最近活跃的在线客户端，再退而选择被暂停或设为忙碌的客户端
+	var best, bestIdle, bestControlled *ClientState
+	for _, state := range clients {
+		switch state.status(now, idle) {
+		case StatusOnline:
+			if best == nil || moreRecent(state, best) {
+				best = state
+			}
+		case StatusIdle:
+			if bestIdle == nil || moreRecent(state, bestIdle) {
+				bestIdle = state
+			}
+		case StatusPaused, StatusBusy:
+			if bestControlled == nil || moreRecent(state, bestControlled) {
+				bestControlled = state
+			}
+		}
+	}
+	switch {
+	case best != nil:
+		return best
+	case bestIdle != nil:
+		return bestIdle
+	}
+	// 其余在线客户端都被暂停或设为忙碌时，"me" 随之显示为暂停或忙碌，而不是离线
+	return bestControlled
+}
+
+// moreRecent 比较两个客户端的活跃时间，时间相同时按 ID 排序以保证结果稳定
+func moreRecent(a, b *ClientState) bool {
+	if !a.LastActive.Equal(b.LastActive) {
+		return a.LastActive.After(b.LastActive)
+	}
+	return a.ID < b.ID
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+func TestResolvePrimary(t *testing.T) {
+	now := time.Now()
+	clients := map[string]*ClientState{
+		"laptop":  {ID: "laptop", Name: "Laptop", IsOnline: true, LastActive: now.Add(-2 * time.Minute)},
+		"desktop": {ID: "desktop", Name: "Desktop", IsOnline: true, LastActive: now.Add(-30 * time.Second)},
+		"phone":   {ID: "phone", Name: "Phone", IsOnline: false, LastActive: now},
+		"tablet":  {ID: "tablet", Name: "Tablet", IsOnline: true, LastActive: now.Add(-time.Hour)},
+	}
+
+	// 1. recent 策略选择最近活跃的在线客户端，忽略离线客户端
+	if got := resolvePrimary(clients, pkg.PrimaryConfig{}, now); got == nil || got.ID != "desktop" {
+		t.Errorf("recent: got %v, want desktop", got)
+	}
+
+	// 2. priority 策略按配置顺序选择，支持名称匹配；离线客户端被跳过
+	cfg := pkg.PrimaryConfig{Strategy: PrimaryStrategyPriority, Priority: []string{"phone", "Laptop"}}
+	if got := resolvePrimary(clients, cfg, now); got == nil || got.ID != "laptop" {
+		t.Errorf("priority: got %v, want laptop", got)
+	}
+
+	// 3. 优先级列表中的客户端空闲时回退到 recent 策略
+	cfg = pkg.PrimaryConfig{Strategy: PrimaryStrategyPriority, Priority: []string{"tablet"}}
+	if got := resolvePrimary(clients, cfg, now); got == nil || got.ID != "desktop" {
+		t.Errorf("priority fallback: got %v, want desktop", got)
+	}
+
+	// 4. 所有在线客户端都空闲时，选择最近活跃的空闲客户端
+	cfg = pkg.PrimaryConfig{IdleAfter: 10}
+	if got := resolvePrimary(clients, cfg, now); got == nil || got.ID != "desktop" {
+		t.Errorf("all idle: got %v, want desktop", got)
+	}
+
+	// 5. 没有在线客户端
+	offline := map[string]*ClientState{"phone": clients["phone"]}
+	if got := resolvePrimary(offline, pkg.PrimaryConfig{}, now); got != nil {
+		t.Errorf("offline: got %v, want nil", got)
+	}
+}
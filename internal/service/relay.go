@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"connectrpc.com/connect"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/pkg/auth"
+)
+
+// RelayEvents 接收中继（NAT 后的服务端）转发来的事件。中继以普通客户端的身份认证，
+// 其下游客户端保留原有 ID 并作为本机客户端参与 "me" 的选择，在线与空闲状态以下游为准。
+// 只有配置中标记为 relay 的客户端可以转发事件。
+func (s *WindowService) RelayEvents(ctx context.Context, req *connect.Request[naniwosurunov1.RelayEventsRequest]) (*connect.Response[naniwosurunov1.RelayEventsResponse], error) {
+	session, err := s.authenticate(req.Header())
+	if err != nil {
+		return nil, err
+	}
+	if !s.isRelay(session.ClientID) {
+		return nil, connect.NewError(connect.CodePermissionDenied, errors.New("client is not configured as a relay"))
+	}
+
+	last := s.applyRelayed(session, req.Msg.Events, req.Msg.Resync)
+	return connect.NewResponse(&naniwosurunov1.RelayEventsResponse{LastId: last}), nil
+}
+
+// applyRelayed 按顺序合并中继的一批事件并返回该中继已接收的最后一个事件 ID。
+// 响应丢失后中继会重发同一批事件，ID 不大于游标的事件视为重复直接跳过；resync 时以快照为准重置游标，
+// 快照中不存在的客户端标记为离线。空批次仅刷新该中继所有客户端的心跳。
+func (s *WindowService) applyRelayed(session auth.SessionInfo, events []*naniwosurunov1.WindowEvent, resync bool) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	cursor := s.relays[session.ClientID]
+	if resync {
+		cursor = 0
+		log.Printf("中继 %s 重新同步，共 %d 个客户端", session.Name, len(events))
+	}
+
+	seen := make(map[string]bool)
+	for _, ev := range events {
+		if ev.ClientId == PrimaryClientName || IsRemote(ev) || (!resync && ev.Id <= cursor) {
+			continue
+		}
+		if owner, owned := s.ownerLocked(ev.ClientId, session.ClientID); owned {
+			log.Printf("忽略中继 %s 转发的客户端 %s：该客户端正由 %s 上报", session.Name, ev.ClientId, owner)
+			cursor = max(cursor, ev.Id)
+			continue
+		}
+		s.applyRelayedLocked(session.ClientID, ev, now)
+		seen[ev.ClientId] = true
+		cursor = max(cursor, ev.Id)
+	}
+	s.relays[session.ClientID] = cursor
+
+	for id, state := range s.clients {
+		if state.RelayedBy != session.ClientID {
+			continue
+		}
+		if resync && !seen[id] {
+			state.IsOnline = false
+		}
+		state.LastHeartbeat = now
+	}
+	s.refreshLocked(now)
+	return cursor
+}
+
+// isRelay 判断客户端是否在配置中被标记为中继
+func (s *WindowService) isRelay(clientID string) bool {
+	for _, c := range s.appConfig().Clients {
+		if c.ID == clientID {
+			return c.Relay
+		}
+	}
+	return false
+}
+
+// ownerLocked 判断下游客户端 id 是否属于中继 relay 以外的会话：中继自身、在线的直连客户端以及
+// 另一个中继正在转发的在线客户端都不能被覆盖，离线的客户端可以被接管。返回占用者的描述，调用方需持有锁
+func (s *WindowService) ownerLocked(id, relay string) (string, bool) {
+	if id == relay {
+		return "relay itself", true
+	}
+	state, exists := s.clients[id]
+	if !exists || !state.IsOnline || state.RelayedBy == relay {
+		return "", false
+	}
+	if state.RelayedBy != "" {
+		return "relay " + state.RelayedBy, true
+	}
+	return "direct session", true
+}
+
+// applyRelayedLocked 将一条下游事件写入对应的客户端状态，焦点变化时发布 focus 事件，其余变化交由 refreshLocked 发布，调用方需持有锁
+func (s *WindowService) applyRelayedLocked(relay string, ev *naniwosurunov1.WindowEvent, now time.Time) {
+	state, exists := s.clients[ev.ClientId]
+	if !exists {
+		state = &ClientState{ID: ev.ClientId, Status: StatusOffline}
+		s.clients[ev.ClientId] = state
+	}
+
+	titleChanged := state.LastTitle != ev.Title
+	state.RelayedBy = relay
+	state.RelayedStatus = ev.Status
+	state.Name = ev.Client
+	state.OS = ev.Os
+	state.LastTitle = ev.Title
+	state.IsOnline = ev.Status != StatusOffline
+	s.setCustomLocked(state, ev.CustomStatus, now)
+
+	if ev.Type != EventTypeFocus && !(titleChanged && state.IsOnline) {
+		if state.LastActive.IsZero() {
+			state.LastActive = now
+		}
+		return
+	}
+	// 缓存期间产生的事件保留其原始时间，"me" 的选择因此与下游一致
+	state.LastActive = now
+	if ev.Timestamp > 0 {
+		state.LastActive = time.UnixMilli(ev.Timestamp)
+	}
+	s.applyCalendarLocked(state, now)
+	state.Status = state.status(now, idleAfter(s.primaryConfig()))
+	s.broker.Publish(state.toEvent(EventTypeFocus))
+}
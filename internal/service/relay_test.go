@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"connectrpc.com/connect"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+	"github.com/nhirsama/Naniwosuruno/pkg/auth"
+	"github.com/r3labs/sse/v2"
+)
+
+// tokenSession 将令牌本身作为客户端 ID，用于模拟多个会话
+type tokenSession struct {
+	auth.StatefulAuthenticator
+}
+
+func (tokenSession) ValidateSession(token string) (auth.SessionInfo, bool) {
+	return auth.SessionInfo{ClientID: token, Name: token}, token != ""
+}
+
+func TestRelayOwnership(t *testing.T) {
+	cm, err := pkg.NewConfigManagerWithLoader(&pkg.JSONConfigLoader{DataDir: t.TempDir(), FileName: "config.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm.GetConfig().Clients = []pkg.ClientConfig{{ID: "home", Relay: true}, {ID: "office", Relay: true}, {ID: "laptop"}}
+	s := NewWindowService(NewEventBroker(sse.New(), nil, 0), tokenSession{}, cm)
+	relay := func(token string, id uint64, client, title string) error {
+		t.Helper()
+		req := connect.NewRequest(&naniwosurunov1.RelayEventsRequest{Events: []*naniwosurunov1.WindowEvent{{
+			Id: id, Type: EventTypeFocus, ClientId: client, Client: client, Title: title, Status: StatusOnline,
+		}}})
+		req.Header().Set("Authorization", "Bearer "+token)
+		_, err := s.RelayEvents(context.Background(), req)
+		return err
+	}
+
+	// 1. 未标记为 relay 的客户端不能转发事件
+	if err := relay("laptop", 1, "desktop", "Steam"); connect.CodeOf(err) != connect.CodePermissionDenied {
+		t.Fatalf("relay from non-relay client = %v, want permission denied", err)
+	}
+
+	// 2. 中继不能覆盖在线的直连客户端，也不能覆盖另一个中继的在线客户端
+	s.ReportLegacyWindow("laptop", "laptop", "GoLand", "linux")
+	if err := relay("home", 1, "laptop", "Spoofed"); err != nil {
+		t.Fatal(err)
+	}
+	if err := relay("home", 2, "desktop", "Steam"); err != nil {
+		t.Fatal(err)
+	}
+	if err := relay("office", 1, "desktop", "Excel"); err != nil {
+		t.Fatal(err)
+	}
+	if ev, _ := s.LookupClient("laptop"); ev.Title != "GoLand" {
+		t.Errorf("laptop = %q, want GoLand", ev.Title)
+	}
+	if ev, _ := s.LookupClient("desktop"); ev.Title != "Steam" {
+		t.Errorf("desktop = %q, want Steam from home", ev.Title)
+	}
+
+	// 3. 设备直接上报后不再采用中继上报的状态
+	s.mu.Lock()
+	s.clients["desktop"].RelayedStatus = StatusIdle
+	s.mu.Unlock()
+	s.ReportLegacyWindow("desktop", "desktop", "Terminal", "linux")
+	s.mu.Lock()
+	desktop := s.clients["desktop"]
+	relayedBy, status := desktop.RelayedBy, desktop.Status
+	s.mu.Unlock()
+	if relayedBy != "" || status != StatusOnline {
+		t.Errorf("desktop after direct report = relayed by %q, %s; want direct and online", relayedBy, status)
+	}
+}
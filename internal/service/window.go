@@ -2,143 +2,596 @@ package service
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"log"
+	"net/http"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"connectrpc.com/connect"
 	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/pkg"
 	"github.com/nhirsama/Naniwosuruno/pkg/auth"
-	"github.com/r3labs/sse/v2"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	StatusOnline  = "online"
+	StatusOffline = "offline"
+	StatusIdle    = "idle"
+
+	// maxPastEpochs 为每个客户端记住的已结束纪元数量
+	maxPastEpochs = 8
 )
 
 type ClientState struct {
+	ID            string
 	LastHeartbeat time.Time
-	LastCount     uint32
+	LastActive    time.Time // 最近一次焦点切换的时间，用于空闲判定与 "me" 的选择
+	LastCount     uint32    // 当前纪元内最近一次心跳的计数
+	Epoch         string    // 客户端进程的纪元，变化表示客户端已重启
+	PastEpochs    []string  // 已结束的纪元，来自这些纪元的心跳视为重放
 	IsOnline      bool
 	Name          string
 	OS            string
 	LastTitle     string
+	Status        string                       // 最近一次发布的状态
+	LastObserved  int64                        // 最近一次上报的观测时间 (Unix 毫秒)，早于它的补报视为重发
+	Streams       int                          // 打开的 Presence 流数量
+	RelayedBy     string                       // 经由中继上报时为中继的客户端 ID
+	RelayedStatus string                       // 中继上报的状态，空闲由下游推导
+	Control       string                       // 管理员设置的控制模式 (StatusPaused、StatusBusy)，见 control.go
+	ControlUntil  time.Time                    // 控制到期的时间，零值表示直到恢复
+	Custom        *naniwosurunov1.CustomStatus // 手动设置的状态，见 custom.go
+	CustomSetAt   time.Time                    // 手动状态的设置时间，用于 "me" 的选择
+	Calendar      *naniwosurunov1.CustomStatus // 日程中正在进行的忙碌事件，期间显示为忙碌，见 calendar.go
+
+	controlChanged chan struct{} // 控制变化时关闭，用于通知该客户端的 Presence 流
+}
+
+// status 根据在线标记与最近活跃时间推导客户端当前的状态
+func (c *ClientState) status(now time.Time, idle time.Duration) string {
+	if !c.IsOnline {
+		return StatusOffline
+	}
+	if mode := c.control(now); mode != "" {
+		return mode
+	}
+	if c.Calendar != nil {
+		return StatusBusy
+	}
+	if c.RelayedBy != "" {
+		return c.RelayedStatus
+	}
+	if now.Sub(c.LastActive) > idle {
+		return StatusIdle
+	}
+	return StatusOnline
+}
+
+// direct 在设备直接向本机上报后清除中继信息，之后的状态不再采用中继上报的值，返回是否曾经由中继上报
+func (c *ClientState) direct() bool {
+	if c.RelayedBy == "" {
+		return false
+	}
+	c.RelayedBy, c.RelayedStatus = "", ""
+	return true
+}
+
+// acceptHeartbeat 按纪元检查心跳计数：新纪元表示客户端重启，计数从头开始；
+// 同一纪元内不大于已记录计数的心跳以及来自已结束纪元的心跳都视为重放
+func (c *ClientState) acceptHeartbeat(epoch string, count uint32) bool {
+	if epoch != c.Epoch {
+		if slices.Contains(c.PastEpochs, epoch) {
+			return false
+		}
+		if c.Epoch != "" {
+			log.Printf("Client %s restarted (epoch %s -> %s)", c.Name, c.Epoch, epoch)
+			c.PastEpochs = append(c.PastEpochs, c.Epoch)
+			if len(c.PastEpochs) > maxPastEpochs {
+				c.PastEpochs = c.PastEpochs[len(c.PastEpochs)-maxPastEpochs:]
+			}
+		}
+		c.Epoch = epoch
+		c.LastCount = 0
+	}
+	return count > c.LastCount || c.LastCount == 0
+}
+
+func (c *ClientState) toEvent(eventType string) *naniwosurunov1.WindowEvent {
+	// 没有手动状态时，日程中的忙碌事件作为手动状态显示
+	custom := c.customStatus(time.Now())
+	if custom == nil && c.Status == StatusBusy {
+		custom = c.Calendar
+	}
+	return &naniwosurunov1.WindowEvent{
+		Title:    c.visibleTitle(),
+		Os:       c.OS,
+		Client:   c.Name,
+		ClientId: c.ID,
+		Status:   c.Status,
+		Type:     eventType,
+
+		CustomStatus: custom,
+	}
 }
 
 type WindowService struct {
-	sseServer     *sse.Server
-	authenticator auth.StatefulAuthenticator
-	clients       map[string]*ClientState
-	mu            sync.Mutex
+	broker           *EventBroker
+	authenticator    auth.StatefulAuthenticator
+	configManager    *pkg.ConfigManager
+	clients          map[string]*ClientState
+	primary          *naniwosurunov1.WindowEvent            // 最近一次发布的 "me" 条目
+	remote           map[string]*naniwosurunov1.WindowEvent // 联邦对端的客户端，键为带命名空间的客户端 ID
+	peers            map[string]*naniwosurunov1.WindowEvent // 联邦对端的连接状态
+	relays           map[string]uint64                      // 各中继已接收的最后一个下游事件 ID
+	calendar         *naniwosurunov1.CustomStatus           // 日程中正在进行的忙碌事件，见 calendar.go
+	controls         map[string]savedControl                // 各客户端生效中的控制，键为客户端 ID，见 control.go
+	controlsPath     string                                 // 控制的持久化文件，为空时只保存在内存中
+	keepaliveTimeout time.Duration                          // Presence 流的保活超时，测试中可调小
+	done             chan struct{}                          // Close 时关闭，结束超时巡检与所有事件流
+	closeOnce        sync.Once
+	mu               sync.Mutex
 }
 
-func NewWindowService(sse *sse.Server, auth auth.StatefulAuthenticator) *WindowService {
+func NewWindowService(broker *EventBroker, auth auth.StatefulAuthenticator, cm *pkg.ConfigManager) *WindowService {
 	s := &WindowService{
-		sseServer:     sse,
-		authenticator: auth,
-		clients:       make(map[string]*ClientState),
+		broker:           broker,
+		authenticator:    auth,
+		configManager:    cm,
+		clients:          make(map[string]*ClientState),
+		primary:          newPrimaryEvent(),
+		remote:           make(map[string]*naniwosurunov1.WindowEvent),
+		peers:            make(map[string]*naniwosurunov1.WindowEvent),
+		relays:           make(map[string]uint64),
+		controls:         make(map[string]savedControl),
+		keepaliveTimeout: PresenceKeepaliveTimeout,
+		done:             make(chan struct{}),
 	}
 	go s.startTimeoutChecker()
 	return s
 }
 
+// Close 停止超时巡检，并结束所有 StreamEvents 与 Presence 流，使服务端关闭时不必等待长连接
+func (s *WindowService) Close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+func (s *WindowService) primaryConfig() pkg.PrimaryConfig {
+	return s.appConfig().Primary
+}
+
 func (s *WindowService) startTimeoutChecker() {
 	ticker := time.NewTicker(30 * time.Second)
-	for range ticker.C {
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+		timeout := s.clientTimeout()
 		s.mu.Lock()
 		now := time.Now()
 		for _, state := range s.clients {
-			if state.IsOnline && now.Sub(state.LastHeartbeat) > 360*time.Second {
+			if state.IsOnline && now.Sub(state.LastHeartbeat) > timeout {
 				state.IsOnline = false
 				log.Printf("Client %s offline (timeout)", state.Name)
-				s.publishEvent(state, "offline")
 			}
 		}
+		// 空闲状态由时间推导，因此每次巡检都需要重新计算
+		s.refreshLocked(now)
 		s.mu.Unlock()
 	}
 }
 
-// publishEvent 核心修复：手动构造 Map 确保 JSON 字段完整，防止前端解析 title.trim() 报错
-func (s *WindowService) publishEvent(state *ClientState, status string) {
-	payload, _ := json.Marshal(map[string]interface{}{
-		"title":  state.LastTitle,
-		"os":     state.OS,
-		"client": state.Name,
-		"status": status,
-	})
-	s.sseServer.TryPublish("focus", &sse.Event{Data: payload})
+// refreshLocked 重新推导所有客户端的状态，发布发生变化的条目并重新选出 "me"，调用方需持有锁
+func (s *WindowService) refreshLocked(now time.Time) {
+	idle := idleAfter(s.primaryConfig())
+	for _, state := range s.clients {
+		expired := state.expireCustom(now)
+		calendar := s.applyCalendarLocked(state, now)
+		if status := state.status(now, idle); status != state.Status || expired || calendar {
+			state.Status = status
+			s.broker.Publish(state.toEvent(EventTypePresence))
+		}
+	}
+	s.recomputePrimaryLocked(now)
 }
 
-func (s *WindowService) ReportWindow(ctx context.Context, req *connect.Request[naniwosurunov1.ReportWindowRequest]) (*connect.Response[naniwosurunov1.ReportWindowResponse], error) {
-	token := req.Header().Get("Authorization")
+// recomputePrimaryLocked 在客户端状态变化后重新选出权威活动，结果变化时以 "me" 的名义发布，调用方需持有锁
+func (s *WindowService) recomputePrimaryLocked(now time.Time) {
+	next := newPrimaryEvent()
+	state := resolvePrimary(s.clients, s.primaryConfig(), now)
+	next.CustomStatus = s.primaryCustomLocked(state, now)
+	if state != nil {
+		next.Title = state.visibleTitle()
+		next.Os = state.OS
+		next.Status = state.Status
+		next.Source = state.Name
+	} else {
+		// 全部离线时保留最后一次的活动内容，仅将状态标记为离线
+		next.Title = s.primary.Title
+		next.Os = s.primary.Os
+		next.Source = s.primary.Source
+	}
+	// 日程中的忙碌事件覆盖 "me" 的可见状态
+	if busy := s.calendarStatus(now); busy != nil {
+		next.Status = StatusBusy
+		next.Title = ""
+		if next.CustomStatus == nil {
+			next.CustomStatus = busy
+		}
+	}
+
+	if next.Title != s.primary.Title || next.Source != s.primary.Source {
+		next.Type = EventTypeFocus
+	} else if next.Status != s.primary.Status || next.Os != s.primary.Os || !proto.Equal(next.CustomStatus, s.primary.CustomStatus) {
+		next.Type = EventTypePresence
+	} else {
+		return
+	}
+	s.primary = next
+	s.broker.Publish(proto.Clone(next).(*naniwosurunov1.WindowEvent))
+}
+
+func newPrimaryEvent() *naniwosurunov1.WindowEvent {
+	return &naniwosurunov1.WindowEvent{
+		Client:   PrimaryClientName,
+		ClientId: PrimaryClientName,
+		Status:   StatusOffline,
+		Type:     EventTypePresence,
+	}
+}
+
+// getOrCreateStateLocked 返回会话对应的客户端状态，不存在时创建，调用方需持有锁
+func (s *WindowService) getOrCreateStateLocked(session auth.SessionInfo) *ClientState {
+	state, exists := s.clients[session.ClientID]
+	if !exists {
+		state = &ClientState{ID: session.ClientID, Name: session.Name, Status: StatusOffline}
+		// 服务端重启前或客户端连接前设置的控制在首次出现时生效
+		if saved, ok := s.controls[session.ClientID]; ok {
+			state.Control, state.ControlUntil = saved.Mode, saved.until()
+		}
+		s.clients[session.ClientID] = state
+	}
+	return state
+}
+
+// authenticate 从 Authorization (Bearer) 或 token 请求头中取出会话令牌并校验
+func (s *WindowService) authenticate(header http.Header) (auth.SessionInfo, error) {
+	token := header.Get("Authorization")
 	if strings.HasPrefix(token, "Bearer ") {
 		token = strings.TrimPrefix(token, "Bearer ")
 	}
 	if token == "" {
-		token = req.Header().Get("token")
+		token = header.Get("token")
 	}
 
 	session, ok := s.authenticator.ValidateSession(token)
 	if !ok {
-		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("invalid or expired token"))
+		return auth.SessionInfo{}, connect.NewError(connect.CodeUnauthenticated, errors.New("invalid or expired token"))
 	}
+	return session, nil
+}
 
+func (s *WindowService) ReportWindow(ctx context.Context, req *connect.Request[naniwosurunov1.ReportWindowRequest]) (*connect.Response[naniwosurunov1.ReportWindowResponse], error) {
+	session, err := s.authenticate(req.Header())
+	if err != nil {
+		return nil, err
+	}
+
+	s.applyReport(session, req.Msg.Title, req.Msg.Os, req.Msg.ObservedAt)
+	return connect.NewResponse(&naniwosurunov1.ReportWindowResponse{}), nil
+}
+
+// ReportLegacyWindow 接收来自 API v0 (Static Token) 的窗口更新，使其与 v1 客户端共享同一套状态与事件格式
+func (s *WindowService) ReportLegacyWindow(clientID, name, title, os string) {
+	s.applyReport(auth.SessionInfo{ClientID: clientID, Name: name}, title, os, 0)
+}
+
+// applyReport 记录一次焦点变化并发布 focus 事件；observedAt 为客户端的观测时间，为 0 或晚于当前时间时使用当前时间
+func (s *WindowService) applyReport(session auth.SessionInfo, title, os string, observedAt int64) {
 	s.mu.Lock()
-	state, exists := s.clients[session.ClientID]
-	if !exists {
-		state = &ClientState{Name: session.Name, OS: req.Msg.Os}
-		s.clients[session.ClientID] = state
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	at := now
+	if observedAt > 0 && observedAt < now.UnixMilli() {
+		at = time.UnixMilli(observedAt)
+	}
+
+	state := s.getOrCreateStateLocked(session)
+	state.direct()
+	if state.control(now) != "" {
+		// 暂停或忙碌期间不记录窗口标题，只视为一次保活
+		s.touchLocked(state, "ReportWindow")
+		return
 	}
-	state.LastHeartbeat = time.Now()
-	state.OS = req.Msg.Os
-	state.LastTitle = req.Msg.Title
+	state.LastHeartbeat = now
+	state.LastActive = at
+	state.LastObserved = max(state.LastObserved, at.UnixMilli())
+	state.OS = os
+	state.LastTitle = title
 	state.IsOnline = true
-	s.mu.Unlock()
+	// 统一作为 online 状态发布，确保 title 字段原样发送；日程中的忙碌事件期间为 busy，标题不公开
+	state.Status = StatusOnline
+	if s.applyCalendarLocked(state, now); state.Calendar != nil {
+		state.Status = StatusBusy
+	}
+	ev := state.toEvent(EventTypeFocus)
+	ev.Timestamp = at.UnixMilli()
+	s.broker.Publish(ev)
+	// 观测时间较早时客户端可能已经空闲，由 refreshLocked 修正状态
+	s.refreshLocked(now)
+}
 
-	// 统一作为 online 状态发布，确保 title 字段原样发送
-	s.publishEvent(state, "online")
+// ReportWindows 接收客户端离线期间缓存的窗口变化，按观测时间写入事件历史。
+// 这些变化已经过时，不更新客户端的当前状态，也不作为实时事件推送；客户端随后会单独上报当前窗口。
+func (s *WindowService) ReportWindows(ctx context.Context, req *connect.Request[naniwosurunov1.ReportWindowsRequest]) (*connect.Response[naniwosurunov1.ReportWindowsResponse], error) {
+	session, err := s.authenticate(req.Header())
+	if err != nil {
+		return nil, err
+	}
 
-	return connect.NewResponse(&naniwosurunov1.ReportWindowResponse{}), nil
+	accepted := s.applyBackfill(session, req.Msg.Windows)
+	return connect.NewResponse(&naniwosurunov1.ReportWindowsResponse{Accepted: accepted}), nil
 }
 
-func (s *WindowService) Heartbeat(ctx context.Context, req *connect.Request[naniwosurunov1.HeartbeatRequest]) (*connect.Response[naniwosurunov1.HeartbeatResponse], error) {
-	token := req.Header().Get("Authorization")
-	if strings.HasPrefix(token, "Bearer ") {
-		token = strings.TrimPrefix(token, "Bearer ")
+// applyBackfill 将补报的窗口变化写入历史，观测时间不晚于已记录时间的条目（响应丢失后的重发）被跳过。
+// 客户端时钟略快时观测时间可能晚于当前时间，与 applyReport 一样按当前时间记录，而不是丢弃
+func (s *WindowService) applyBackfill(session auth.SessionInfo, windows []*naniwosurunov1.ReportWindowRequest) uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	state := s.getOrCreateStateLocked(session)
+	state.LastHeartbeat = now
+
+	var accepted uint32
+	for _, w := range windows {
+		// 重发的判断使用客户端的原始观测时间，使同一批条目重发时仍能被识别
+		if w.ObservedAt <= state.LastObserved {
+			continue
+		}
+		state.LastObserved = w.ObservedAt
+		s.broker.Record(&naniwosurunov1.WindowEvent{
+			Title:      w.Title,
+			Os:         w.Os,
+			Client:     state.Name,
+			ClientId:   state.ID,
+			Status:     StatusOnline,
+			Type:       EventTypeFocus,
+			Timestamp:  min(w.ObservedAt, now.UnixMilli()),
+			Backfilled: true,
+		})
+		accepted++
 	}
-	if token == "" {
-		token = req.Header().Get("token")
+	if accepted > 0 {
+		log.Printf("Client %s backfilled %d window changes", state.Name, accepted)
 	}
+	return accepted
+}
 
-	session, ok := s.authenticator.ValidateSession(token)
-	if !ok {
-		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("invalid or expired token"))
+func (s *WindowService) Heartbeat(ctx context.Context, req *connect.Request[naniwosurunov1.HeartbeatRequest]) (*connect.Response[naniwosurunov1.HeartbeatResponse], error) {
+	session, err := s.authenticate(req.Header())
+	if err != nil {
+		return nil, err
 	}
 
 	s.mu.Lock()
-	state, exists := s.clients[session.ClientID]
-	if !exists {
-		state = &ClientState{Name: session.Name}
-		s.clients[session.ClientID] = state
+	state := s.getOrCreateStateLocked(session)
+
+	res := &naniwosurunov1.HeartbeatResponse{Count: state.LastCount, ConfigRevision: s.configRevision()}
+	if state.acceptHeartbeat(req.Msg.Epoch, req.Msg.Count) {
+		state.LastCount = req.Msg.Count
+		res.Count = req.Msg.Count
+		s.touchLocked(state, "Heartbeat")
 	}
+	res.Control = state.controlMessage(time.Now())
+	s.mu.Unlock()
 
-	if req.Msg.Count <= state.LastCount && state.LastCount != 0 {
-		s.mu.Unlock()
-		return connect.NewResponse(&naniwosurunov1.HeartbeatResponse{Count: state.LastCount}), nil
+	return connect.NewResponse(res), nil
+}
+
+// Goodbye 由正常退出的客户端调用，立即将其标记为离线而不必等待超时
+func (s *WindowService) Goodbye(ctx context.Context, req *connect.Request[naniwosurunov1.GoodbyeRequest]) (*connect.Response[naniwosurunov1.GoodbyeResponse], error) {
+	session, err := s.authenticate(req.Header())
+	if err != nil {
+		return nil, err
 	}
 
-	state.LastCount = req.Msg.Count
-	state.LastHeartbeat = time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.getOrCreateStateLocked(session)
+	if req.Msg.Epoch != "" && state.Epoch != "" && req.Msg.Epoch != state.Epoch {
+		return connect.NewResponse(&naniwosurunov1.GoodbyeResponse{}), nil
+	}
+	if state.IsOnline {
+		state.IsOnline = false
+		log.Printf("Client %s offline (goodbye)", state.Name)
+		s.refreshLocked(time.Now())
+	}
+	return connect.NewResponse(&naniwosurunov1.GoodbyeResponse{}), nil
+}
+
+// touchLocked 记录客户端仍然存活，离线的客户端重新上线，调用方需持有锁。
+// 只用于客户端直接发起的请求，原先经由中继上报的客户端由此转为直接连接
+func (s *WindowService) touchLocked(state *ClientState, via string) {
+	now := time.Now()
+	state.LastHeartbeat = now
+	relayed := state.direct()
 
 	if !state.IsOnline {
 		state.IsOnline = true
-		log.Printf("Client %s online via Heartbeat", session.Name)
-		s.publishEvent(state, "online")
+		// 重新上线视为一次活动，避免刚上线就被判定为空闲
+		state.LastActive = now
+		log.Printf("Client %s online via %s", state.Name, via)
+		s.refreshLocked(now)
+	} else if relayed {
+		s.refreshLocked(now)
 	}
-	s.mu.Unlock()
-
-	return connect.NewResponse(&naniwosurunov1.HeartbeatResponse{Count: req.Msg.Count}), nil
 }
 
+// ErrSubscriberTooSlow 表示订阅者消费过慢被断开，应携带最后收到的事件 ID 重新订阅
+var ErrSubscriberTooSlow = errors.New("subscriber too slow, resubscribe with since_id")
+
+// ErrClosed 表示服务已经关闭，订阅者不应再重新订阅
+var ErrClosed = errors.New("window service closed")
+
+// SubscribeEvents 以 RPC 流的形式推送事件：先发送快照或从 since_id 续传，再持续推送实时事件
 func (s *WindowService) SubscribeEvents(ctx context.Context, req *connect.Request[naniwosurunov1.SubscribeEventsRequest], stream *connect.ServerStream[naniwosurunov1.WindowEvent]) error {
-	return connect.NewError(connect.CodeUnimplemented, errors.New("RPC streaming not yet implemented"))
+	if req.Msg.StreamId != "" && req.Msg.StreamId != EventStream {
+		return connect.NewError(connect.CodeInvalidArgument, errors.New("unknown stream"))
+	}
+
+	err := s.StreamEvents(ctx, req.Msg.SinceId, stream.Send)
+	switch {
+	case errors.Is(err, ErrSubscriberTooSlow):
+		return connect.NewError(connect.CodeResourceExhausted, err)
+	case errors.Is(err, ErrClosed):
+		return connect.NewError(connect.CodeUnavailable, err)
+	}
+	return err
+}
+
+// StreamEvents 先发送快照（sinceID 为 0 或断档过大时）或续传的历史事件，再持续推送实时事件，
+// 直到 ctx 结束（返回 nil）、服务关闭（返回 ErrClosed）或 send 返回错误。RPC 流与 WebSocket 共用这一逻辑。
+func (s *WindowService) StreamEvents(ctx context.Context, sinceID uint64, send func(*naniwosurunov1.WindowEvent) error) error {
+	// 先订阅再计算续传内容，保证两者之间不会丢事件；重复的事件按 ID 过滤
+	live, cancel := s.broker.Subscribe(64)
+	defer cancel()
+
+	var backlog []*naniwosurunov1.WindowEvent
+	var snap *naniwosurunov1.GetSnapshotResponse
+	if sinceID > 0 {
+		backlog, snap = s.ResumeFrom(sinceID)
+	} else {
+		snap = s.Snapshot()
+	}
+	if snap != nil {
+		backlog = SnapshotEvents(snap)
+	}
+
+	var lastSent uint64
+	for _, ev := range backlog {
+		if err := send(ev); err != nil {
+			return err
+		}
+		lastSent = max(lastSent, ev.Id)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.done:
+			return ErrClosed
+		case ev, ok := <-live:
+			if !ok {
+				return ErrSubscriberTooSlow
+			}
+			if ev.Id <= lastSent {
+				continue
+			}
+			if err := send(ev); err != nil {
+				return err
+			}
+			lastSent = ev.Id
+		}
+	}
+}
+
+func (s *WindowService) GetSnapshot(ctx context.Context, req *connect.Request[naniwosurunov1.GetSnapshotRequest]) (*connect.Response[naniwosurunov1.GetSnapshotResponse], error) {
+	return connect.NewResponse(s.Snapshot()), nil
+}
+
+// Snapshot 返回所有已知客户端的当前状态（按名称排序）以及合成的 "me" 条目
+func (s *WindowService) Snapshot() *naniwosurunov1.GetSnapshotResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshotLocked()
+}
+
+// LookupClient 按客户端 ID 或名称查找当前状态，key 为 "me" 时返回合成的权威活动
+func (s *WindowService) LookupClient(key string) (*naniwosurunov1.WindowEvent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key == PrimaryClientName {
+		return proto.Clone(s.primary).(*naniwosurunov1.WindowEvent), true
+	}
+	if state, ok := s.clients[key]; ok {
+		return state.toEvent(EventTypePresence), true
+	}
+	for _, state := range s.clients {
+		if state.Name == key {
+			return state.toEvent(EventTypePresence), true
+		}
+	}
+	for _, ev := range s.remote {
+		if ev.ClientId == key || ev.Client == key {
+			return proto.Clone(ev).(*naniwosurunov1.WindowEvent), true
+		}
+	}
+	return nil, false
+}
+
+// ResumeFrom 返回 ID 大于 lastID 的历史事件；断档过大或无法续传时改为返回当前快照
+func (s *WindowService) ResumeFrom(lastID uint64) ([]*naniwosurunov1.WindowEvent, *naniwosurunov1.GetSnapshotResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if events, ok := s.broker.Since(lastID); ok {
+		return events, nil
+	}
+	return nil, s.snapshotLocked()
+}
+
+// snapshotLocked 生成当前状态快照，调用方需持有锁；所有事件都在持锁时发布，因此快照与 LastEventId 一致
+func (s *WindowService) snapshotLocked() *naniwosurunov1.GetSnapshotResponse {
+	res := &naniwosurunov1.GetSnapshotResponse{
+		Clients:     make([]*naniwosurunov1.WindowEvent, 0, len(s.clients)),
+		Me:          proto.Clone(s.primary).(*naniwosurunov1.WindowEvent),
+		LastEventId: s.broker.LastID(),
+	}
+	for _, state := range s.clients {
+		res.Clients = append(res.Clients, state.toEvent(EventTypePresence))
+	}
+	for _, ev := range s.remote {
+		res.Clients = append(res.Clients, proto.Clone(ev).(*naniwosurunov1.WindowEvent))
+	}
+	sort.Slice(res.Clients, func(i, j int) bool {
+		return res.Clients[i].Client < res.Clients[j].Client
+	})
+	for _, ev := range s.peers {
+		res.Peers = append(res.Peers, proto.Clone(ev).(*naniwosurunov1.WindowEvent))
+	}
+	sort.Slice(res.Peers, func(i, j int) bool {
+		return res.Peers[i].Client < res.Peers[j].Client
+	})
+	return res
+}
+
+// SnapshotEvents 将快照展开为 snapshot 类型的事件，供只能传输 WindowEvent 的事件流使用；
+// 联邦对端的连接状态保留 peer 类型
+func SnapshotEvents(snap *naniwosurunov1.GetSnapshotResponse) []*naniwosurunov1.WindowEvent {
+	events := make([]*naniwosurunov1.WindowEvent, 0, len(snap.Clients)+len(snap.Peers)+1)
+	for _, ev := range append(snap.Clients, snap.Me) {
+		ev = proto.Clone(ev).(*naniwosurunov1.WindowEvent)
+		ev.Type = EventTypeSnapshot
+		ev.Id = snap.LastEventId
+		ev.SchemaVersion = EventSchemaVersion
+		events = append(events, ev)
+	}
+	for _, ev := range snap.Peers {
+		ev = proto.Clone(ev).(*naniwosurunov1.WindowEvent)
+		ev.Id = snap.LastEventId
+		ev.SchemaVersion = EventSchemaVersion
+		events = append(events, ev)
+	}
+	return events
 }
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/internal/history"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+	"github.com/nhirsama/Naniwosuruno/pkg/auth"
+	"github.com/r3labs/sse/v2"
+)
+
+func TestBackfillRecordsHistoryOnly(t *testing.T) {
+	store, err := history.NewFileStore(filepath.Join(t.TempDir(), "events.jsonl"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	broker := NewEventBroker(sse.New(), store, 0)
+	s := NewWindowService(broker, nil, nil)
+	session := auth.SessionInfo{ClientID: "laptop-id", Name: "laptop"}
+
+	now := time.Now()
+	s.applyReport(session, "GoLand", "linux", now.Add(-5*time.Minute).UnixMilli())
+	live, cancel := broker.Subscribe(16)
+	defer cancel()
+
+	windows := []*naniwosurunov1.ReportWindowRequest{
+		{Title: "Firefox", Os: "linux", ObservedAt: now.Add(-3 * time.Minute).UnixMilli()},
+		{Title: "Slack", Os: "linux", ObservedAt: now.Add(-2 * time.Minute).UnixMilli()},
+	}
+
+	// 1. 补报的变化写入历史，带有观测时间与 backfilled 标记
+	if got := s.applyBackfill(session, windows); got != 2 {
+		t.Fatalf("accepted = %d, want 2", got)
+	}
+	events, _ := broker.Since(0)
+	if len(events) < 2 {
+		t.Fatalf("history has %d events", len(events))
+	}
+	for i, ev := range events[len(events)-2:] {
+		if !ev.Backfilled || ev.Title != windows[i].Title || ev.Timestamp != windows[i].ObservedAt {
+			t.Errorf("history[%d] = %+v, want backfilled %q", i, ev, windows[i].Title)
+		}
+	}
+
+	// 2. 不推送给实时订阅者，也不改变当前状态
+	select {
+	case ev := <-live:
+		t.Errorf("backfill broadcast live: %+v", ev)
+	default:
+	}
+	if me, _ := s.LookupClient("me"); me.Title != "GoLand" {
+		t.Errorf("me = %q, want GoLand", me.Title)
+	}
+
+	// 3. 响应丢失后的重发被忽略
+	if got := s.applyBackfill(session, windows); got != 0 {
+		t.Errorf("resend accepted = %d, want 0", got)
+	}
+
+	// 4. 客户端时钟略快时观测时间晚于当前时间的条目按当前时间记录，重发同样被忽略
+	skewed := []*naniwosurunov1.ReportWindowRequest{
+		{Title: "Terminal", Os: "linux", ObservedAt: time.Now().Add(2 * time.Second).UnixMilli()},
+		{Title: "Zed", Os: "linux", ObservedAt: time.Now().Add(3 * time.Second).UnixMilli()},
+	}
+	if got := s.applyBackfill(session, skewed); got != 2 {
+		t.Fatalf("skewed accepted = %d, want 2", got)
+	}
+	events, _ = broker.Since(0)
+	if last := events[len(events)-1]; last.Title != "Zed" || last.Timestamp > time.Now().UnixMilli() {
+		t.Errorf("skewed history = %q at %d, want Zed clamped to now", last.Title, last.Timestamp)
+	}
+	if got := s.applyBackfill(session, skewed); got != 0 {
+		t.Errorf("skewed resend accepted = %d, want 0", got)
+	}
+}
+
+func TestHeartbeatEpochs(t *testing.T) {
+	s := NewWindowService(NewEventBroker(sse.New(), nil, 0), staticSession{}, nil)
+	beat := func(epoch string, count uint32) uint32 {
+		t.Helper()
+		req := connect.NewRequest(&naniwosurunov1.HeartbeatRequest{Epoch: epoch, Count: count})
+		req.Header().Set("Authorization", "Bearer token")
+		res, err := s.Heartbeat(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Msg.Count
+	}
+
+	// 1. 同一纪元内计数不增长的心跳被丢弃
+	beat("first", 1)
+	beat("first", 5)
+	if got := beat("first", 3); got != 5 {
+		t.Errorf("replayed heartbeat accepted, count = %d", got)
+	}
+
+	// 2. 客户端重启后新纪元从 1 重新计数，不受旧计数影响
+	if got := beat("second", 1); got != 1 {
+		t.Errorf("restarted client heartbeat ignored, count = %d", got)
+	}
+
+	// 3. 来自已结束纪元的心跳视为重放
+	if got := beat("first", 6); got != 1 {
+		t.Errorf("heartbeat from retired epoch accepted, count = %d", got)
+	}
+	if got := beat("second", 2); got != 2 {
+		t.Errorf("count = %d, want 2", got)
+	}
+}
+
+func TestGoodbyeAndClose(t *testing.T) {
+	s := NewWindowService(NewEventBroker(sse.New(), nil, 0), staticSession{}, nil)
+	heartbeat := func(epoch string) {
+		t.Helper()
+		beat := connect.NewRequest(&naniwosurunov1.HeartbeatRequest{Epoch: epoch, Count: 1})
+		beat.Header().Set("Authorization", "Bearer token")
+		if _, err := s.Heartbeat(context.Background(), beat); err != nil {
+			t.Fatal(err)
+		}
+	}
+	goodbye := func(epoch string) {
+		t.Helper()
+		req := connect.NewRequest(&naniwosurunov1.GoodbyeRequest{Epoch: epoch})
+		req.Header().Set("Authorization", "Bearer token")
+		if _, err := s.Goodbye(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 1. 已结束纪元的下线通知不影响重启后的客户端
+	heartbeat("first")
+	heartbeat("second")
+	goodbye("first")
+	if ev, _ := s.LookupClient("laptop"); ev.Status != StatusOnline {
+		t.Fatalf("status after stale goodbye = %q, want online", ev.Status)
+	}
+
+	// 2. 当前纪元的下线通知立即发布离线状态，无需等待超时
+	goodbye("second")
+	if ev, _ := s.LookupClient("laptop"); ev.Status != StatusOffline {
+		t.Fatalf("status after goodbye = %q, want offline", ev.Status)
+	}
+
+	// 3. Close 结束所有进行中的事件流
+	done := make(chan error, 1)
+	go func() {
+		done <- s.StreamEvents(context.Background(), 0, func(*naniwosurunov1.WindowEvent) error { return nil })
+	}()
+	s.Close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("StreamEvents after Close = %v, want ErrClosed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("StreamEvents still running after Close")
+	}
+}
+
+func TestClientConfig(t *testing.T) {
+	loader := &pkg.JSONConfigLoader{DataDir: t.TempDir(), FileName: "config.json"}
+	cm, err := pkg.NewConfigManagerWithLoader(loader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewWindowService(NewEventBroker(sse.New(), nil, 0), staticSession{}, cm)
+	get := func() *naniwosurunov1.GetClientConfigResponse {
+		t.Helper()
+		req := connect.NewRequest(&naniwosurunov1.GetClientConfigRequest{})
+		req.Header().Set("Authorization", "Bearer token")
+		res, err := s.GetClientConfig(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Msg
+	}
+
+	// 1. 未配置时下发默认值，离线超时为 3 个心跳间隔
+	defaults := get()
+	if defaults.PollInterval != 5 || defaults.HeartbeatInterval != 120 {
+		t.Errorf("defaults = %+v", defaults)
+	}
+	if got := s.clientTimeout(); got != 360*time.Second {
+		t.Errorf("clientTimeout = %s, want 6m", got)
+	}
+
+	// 2. 修改配置后版本变化，心跳响应携带新版本，超时随心跳间隔调整
+	cfg := cm.GetConfig()
+	cfg.ClientPolicy = pkg.ClientPolicyConfig{HeartbeatInterval: 30, Fields: []string{"title"}, Privacy: pkg.PrivacyConfig{Hide: []string{"Slack"}}}
+	updated := get()
+	if updated.Revision == defaults.Revision {
+		t.Fatal("revision unchanged after config change")
+	}
+	if updated.HeartbeatInterval != 30 || len(updated.Hide) != 1 || len(updated.Fields) != 1 {
+		t.Errorf("updated = %+v", updated)
+	}
+	if got := s.clientTimeout(); got != 90*time.Second {
+		t.Errorf("clientTimeout = %s, want 90s", got)
+	}
+	beat := connect.NewRequest(&naniwosurunov1.HeartbeatRequest{Epoch: "first", Count: 1})
+	beat.Header().Set("Authorization", "Bearer token")
+	res, err := s.Heartbeat(context.Background(), beat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Msg.ConfigRevision != updated.Revision {
+		t.Errorf("heartbeat revision = %q, want %q", res.Msg.ConfigRevision, updated.Revision)
+	}
+
+	// 3. 修改磁盘上的配置文件并重新加载（服务端收到 SIGHUP 时），版本随之变化
+	edited := *cm.GetConfig()
+	edited.ClientPolicy.PollInterval = 10
+	if err := loader.Save(&edited); err != nil {
+		t.Fatal(err)
+	}
+	if err := cm.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := get()
+	if reloaded.Revision == updated.Revision || reloaded.PollInterval != 10 {
+		t.Errorf("reloaded = %+v", reloaded)
+	}
+	if got := s.configRevision(); got != reloaded.Revision {
+		t.Errorf("configRevision = %q, want %q", got, reloaded.Revision)
+	}
+}
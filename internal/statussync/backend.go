@@ -0,0 +1,144 @@
+package statussync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+const defaultSlackBaseURL = "https://slack.com/api"
+
+// Status 是要同步到聊天软件的状态，Text 与 Emoji 均为空表示清除状态
+type Status struct {
+	Text       string
+	Emoji      string
+	SlackEmoji string
+	Presence   string // 跟随客户端的在线状态
+}
+
+// Backend 是一个聊天软件的状态接口
+type Backend interface {
+	Name() string
+	SetStatus(ctx context.Context, st Status) error
+}
+
+// --- Slack ---
+
+// Slack 通过 users.profile.set 设置状态文字与表情
+type Slack struct {
+	client  *http.Client
+	baseURL string
+	token   string
+}
+
+func NewSlack(cfg pkg.SlackConfig, client *http.Client) *Slack {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultSlackBaseURL
+	}
+	return &Slack{client: client, baseURL: strings.TrimSuffix(baseURL, "/"), token: cfg.Token}
+}
+
+func (s *Slack) Name() string { return "slack" }
+
+func (s *Slack) SetStatus(ctx context.Context, st Status) error {
+	text, emoji := st.Text, st.SlackEmoji
+	if emoji == "" && st.Emoji != "" {
+		text = strings.TrimSpace(st.Emoji + " " + text)
+	}
+
+	body := map[string]interface{}{
+		"profile": map[string]interface{}{
+			"status_text":       text,
+			"status_emoji":      emoji,
+			"status_expiration": 0,
+		},
+	}
+	var res struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := doJSON(ctx, s.client, http.MethodPost, s.baseURL+"/users.profile.set", s.token, body, &res); err != nil {
+		return err
+	}
+	if !res.OK {
+		return fmt.Errorf("slack: %s", res.Error)
+	}
+	return nil
+}
+
+// --- Matrix ---
+
+// Matrix 通过 /presence/{userId}/status 设置在线状态与 status_msg
+type Matrix struct {
+	client      *http.Client
+	homeserver  string
+	userID      string
+	accessToken string
+}
+
+func NewMatrix(cfg pkg.MatrixConfig, client *http.Client) *Matrix {
+	return &Matrix{
+		client:      client,
+		homeserver:  strings.TrimSuffix(cfg.Homeserver, "/"),
+		userID:      cfg.UserID,
+		accessToken: cfg.AccessToken,
+	}
+}
+
+func (m *Matrix) Name() string { return "matrix" }
+
+func (m *Matrix) SetStatus(ctx context.Context, st Status) error {
+	// Matrix 的 presence 只有 online、unavailable、offline 三种
+	presence := "online"
+	switch st.Presence {
+	case service.StatusIdle, service.StatusPaused, service.StatusBusy:
+		presence = "unavailable"
+	case service.StatusOffline:
+		presence = "offline"
+	}
+
+	body := map[string]interface{}{
+		"presence":   presence,
+		"status_msg": strings.TrimSpace(st.Emoji + " " + st.Text),
+	}
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/presence/%s/status", m.homeserver, url.PathEscape(m.userID))
+	return doJSON(ctx, m.client, http.MethodPut, endpoint, m.accessToken, body, nil)
+}
+
+// doJSON 以 Bearer Token 发送 JSON 请求，非 2xx 响应视为错误，out 非 nil 时解析响应体
+func doJSON(ctx context.Context, client *http.Client, method, endpoint, token string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s %s", method, endpoint, resp.Status, bytes.TrimSpace(respBody))
+	}
+	if out != nil {
+		return json.Unmarshal(respBody, out)
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package statussync
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+const (
+	defaultMinInterval = time.Minute
+	requestTimeout     = 10 * time.Second
+	// minBackoff 为事件订阅意外结束后重新订阅前的等待时间
+	minBackoff = time.Second
+)
+
+// EventStreamer 先发送全量快照再持续推送实时事件，由 WindowService 实现
+type EventStreamer interface {
+	StreamEvents(ctx context.Context, sinceID uint64, send func(*naniwosurunov1.WindowEvent) error) error
+}
+
+type rule struct {
+	pkg.StatusRule
+	apps []*regexp.Regexp
+}
+
+// Syncer 跟随一个客户端的活动，按规则计算状态并限速同步到各个后端
+type Syncer struct {
+	client  string
+	rules   []rule
+	workers []*worker
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New 根据配置创建 Syncer，只启用配置了凭据的后端
+func New(cfg pkg.StatusSyncConfig) *Syncer {
+	s := &Syncer{client: cfg.Client}
+	if s.client == "" {
+		s.client = service.PrimaryClientName
+	}
+
+	categories := make(map[string][]*regexp.Regexp)
+	for name, exprs := range cfg.Categories {
+		categories[name] = compileApps("分类 "+name, exprs)
+	}
+	for _, r := range cfg.Rules {
+		compiled := rule{StatusRule: r, apps: compileApps("规则 "+r.Name, r.Apps)}
+		if r.Category != "" {
+			apps, ok := categories[r.Category]
+			if !ok {
+				log.Printf("状态同步规则 %q 引用了未定义的分类 %q", r.Name, r.Category)
+			}
+			compiled.apps = append(compiled.apps, apps...)
+		}
+		s.rules = append(s.rules, compiled)
+	}
+
+	interval := time.Duration(cfg.MinInterval) * time.Second
+	if interval <= 0 {
+		interval = defaultMinInterval
+	}
+	httpClient := &http.Client{Timeout: requestTimeout}
+	if cfg.Slack.Token != "" {
+		s.workers = append(s.workers, newWorker(NewSlack(cfg.Slack, httpClient), interval))
+	}
+	if cfg.Matrix.AccessToken != "" {
+		s.workers = append(s.workers, newWorker(NewMatrix(cfg.Matrix, httpClient), interval))
+	}
+	return s
+}
+
+// Enabled 判断是否至少配置了一个后端
+func (s *Syncer) Enabled() bool {
+	return len(s.workers) > 0
+}
+
+// Start 订阅事件并启动各后端的同步协程
+func (s *Syncer) Start(events EventStreamer) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, w := range s.workers {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			w.run(ctx)
+		}()
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.follow(ctx, events)
+	}()
+}
+
+// Stop 停止同步，已发送的状态保持不变
+func (s *Syncer) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Syncer) follow(ctx context.Context, events EventStreamer) {
+	var lastID uint64
+	for {
+		err := events.StreamEvents(ctx, lastID, func(ev *naniwosurunov1.WindowEvent) error {
+			lastID = ev.Id
+			if ev.ClientId == s.client || ev.Client == s.client {
+				st := s.Resolve(ev)
+				for _, w := range s.workers {
+					w.update(st)
+				}
+			}
+			return nil
+		})
+		if ctx.Err() != nil || errors.Is(err, service.ErrClosed) {
+			return
+		}
+		if !errors.Is(err, service.ErrSubscriberTooSlow) {
+			log.Printf("状态同步的事件订阅结束: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(minBackoff):
+		}
+	}
+}
+
+// Resolve 返回第一条命中规则对应的状态，都不命中时返回空状态（清除）。手动设置的状态优先于规则
+func (s *Syncer) Resolve(ev *naniwosurunov1.WindowEvent) Status {
+	if custom := ev.CustomStatus; custom != nil && custom.Text != "" {
+		return Status{Text: custom.Text, Emoji: custom.Emoji, Presence: ev.Status}
+	}
+	for _, r := range s.rules {
+		if r.Status != "" && r.Status != ev.Status {
+			continue
+		}
+		if r.Status == "" && ev.Status == service.StatusOffline {
+			continue
+		}
+		if (len(r.Apps) > 0 || r.Category != "") && !matchAny(r.apps, ev.Title) {
+			continue
+		}
+		return Status{Text: r.Text, Emoji: r.Emoji, SlackEmoji: r.SlackEmoji, Presence: ev.Status}
+	}
+	return Status{Presence: ev.Status}
+}
+
+// compileApps 编译匹配窗口标题的正则，无效的正则记录日志后跳过
+func compileApps(owner string, exprs []string) []*regexp.Regexp {
+	var res []*regexp.Regexp
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			log.Printf("状态同步%s的正则无效 %q: %v", owner, expr, err)
+			continue
+		}
+		res = append(res, re)
+	}
+	return res
+}
+
+func matchAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// worker 负责一个后端：只发送最新的状态，两次发送之间至少间隔 interval，失败时在间隔后重试
+type worker struct {
+	backend  Backend
+	interval time.Duration
+
+	latest Status
+	mu     sync.Mutex
+	wake   chan struct{}
+}
+
+func newWorker(backend Backend, interval time.Duration) *worker {
+	return &worker{backend: backend, interval: interval, wake: make(chan struct{}, 1)}
+}
+
+func (w *worker) update(st Status) {
+	w.mu.Lock()
+	w.latest = st
+	w.mu.Unlock()
+	w.signal()
+}
+
+func (w *worker) signal() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *worker) run(ctx context.Context) {
+	var sent *Status
+	var next time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.wake:
+		}
+
+		// 限速：等待到允许下一次发送的时刻，期间到达的更新会覆盖 latest
+		if wait := time.Until(next); wait > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+
+		w.mu.Lock()
+		st := w.latest
+		w.mu.Unlock()
+		if sent != nil && *sent == st {
+			continue
+		}
+
+		next = time.Now().Add(w.interval)
+		if err := w.backend.SetStatus(ctx, st); err != nil {
+			log.Printf("同步 %s 状态失败: %v", w.backend.Name(), err)
+			w.signal()
+			continue
+		}
+		log.Printf("已同步 %s 状态: %s %s", w.backend.Name(), st.Emoji, st.Text)
+		sent = &st
+	}
+}
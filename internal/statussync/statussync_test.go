@@ -0,0 +1,213 @@
+package statussync
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+	"github.com/r3labs/sse/v2"
+)
+
+// recorder 记录模拟服务收到的请求
+type recorder struct {
+	mu       sync.Mutex
+	paths    []string
+	bodies   []map[string]interface{}
+	authzs   []string
+	response string
+}
+
+func (rc *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	rc.mu.Lock()
+	rc.paths = append(rc.paths, r.Method+" "+r.URL.EscapedPath())
+	rc.bodies = append(rc.bodies, body)
+	rc.authzs = append(rc.authzs, r.Header.Get("Authorization"))
+	rc.mu.Unlock()
+	_, _ = w.Write([]byte(rc.response))
+}
+
+func (rc *recorder) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.bodies)
+}
+
+func (rc *recorder) last() map[string]interface{} {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.bodies[len(rc.bodies)-1]
+}
+
+func waitCount(t *testing.T, rc *recorder, n int) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for rc.count() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d requests, got %d", n, rc.count())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+var testRules = []pkg.StatusRule{
+	{Name: "gaming", Apps: []string{"(?i)steam"}, Text: "Playing", Emoji: "🎮", SlackEmoji: ":video_game:"},
+	{Name: "coding", Apps: []string{"GoLand", "Visual Studio Code"}, Text: "Coding", Emoji: "💻"},
+	{Name: "offline", Status: "offline", Text: "Offline", Emoji: "🚫"},
+}
+
+func TestResolve(t *testing.T) {
+	s := New(pkg.StatusSyncConfig{Rules: testRules})
+	cases := []struct {
+		title, status string
+		want          string
+	}{
+		{"Steam", "online", "Playing"},
+		{"main.go - GoLand", "idle", "Coding"},
+		{"GoLand", "offline", "Offline"},
+		{"Firefox", "online", ""},
+	}
+	for _, c := range cases {
+		got := s.Resolve(&naniwosurunov1.WindowEvent{Title: c.title, Status: c.status})
+		if got.Text != c.want || got.Presence != c.status {
+			t.Errorf("Resolve(%q, %q) = %+v, want text %q", c.title, c.status, got, c.want)
+		}
+	}
+
+	// 规则可以引用分类，分类中的正则与 Apps 合并匹配；引用未定义分类且没有 Apps 的规则不会命中
+	categorized := New(pkg.StatusSyncConfig{
+		Categories: map[string][]string{"meeting": {"^Zoom", "(?i)google meet"}},
+		Rules: []pkg.StatusRule{
+			{Name: "call", Category: "meeting", Apps: []string{"Teams"}, Text: "On a call", Emoji: "📞"},
+			{Name: "typo", Category: "meetings", Text: "Never"},
+		},
+	})
+	for title, want := range map[string]string{"Zoom Meeting": "On a call", "Google Meet - Chrome": "On a call", "Microsoft Teams": "On a call", "Slack": ""} {
+		if got := categorized.Resolve(&naniwosurunov1.WindowEvent{Title: title, Status: "online"}); got.Text != want {
+			t.Errorf("Resolve(%q) with categories = %q, want %q", title, got.Text, want)
+		}
+	}
+
+	// 手动状态优先于规则
+	got := s.Resolve(&naniwosurunov1.WindowEvent{Title: "Steam", Status: "online",
+		CustomStatus: &naniwosurunov1.CustomStatus{Text: "Lunch", Emoji: "🍜"}})
+	if got.Text != "Lunch" || got.Emoji != "🍜" || got.Presence != "online" {
+		t.Errorf("Resolve with custom status = %+v, want Lunch", got)
+	}
+}
+
+func TestSyncSlackAndMatrix(t *testing.T) {
+	slack := &recorder{response: `{"ok":true}`}
+	slackSrv := httptest.NewServer(slack)
+	defer slackSrv.Close()
+	matrix := &recorder{response: `{}`}
+	matrixSrv := httptest.NewServer(matrix)
+	defer matrixSrv.Close()
+
+	cm, err := pkg.NewConfigManagerWithLoader(&pkg.JSONConfigLoader{DataDir: t.TempDir(), FileName: "config.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws := service.NewWindowService(service.NewEventBroker(sse.New(), nil, 0), nil, cm)
+
+	s := New(pkg.StatusSyncConfig{
+		Rules:  testRules,
+		Slack:  pkg.SlackConfig{Token: "xoxp-test", BaseURL: slackSrv.URL},
+		Matrix: pkg.MatrixConfig{Homeserver: matrixSrv.URL, UserID: "@alice:example.org", AccessToken: "syt-test"},
+	})
+	for _, w := range s.workers {
+		w.interval = 200 * time.Millisecond
+	}
+	s.Start(ws)
+	defer s.Stop()
+
+	// 1. 启动时的快照中 "me" 离线，立即同步离线状态
+	waitCount(t, slack, 1)
+	waitCount(t, matrix, 1)
+	if got := matrix.last(); got["presence"] != "offline" || got["status_msg"] != "🚫 Offline" {
+		t.Fatalf("unexpected matrix body: %v", got)
+	}
+	if matrix.paths[0] != "PUT /_matrix/client/v3/presence/@alice:example.org/status" || matrix.authzs[0] != "Bearer syt-test" {
+		t.Fatalf("unexpected matrix request: %s %s", matrix.paths[0], matrix.authzs[0])
+	}
+
+	// 2. 间隔内的多次切换只同步最后一次
+	ws.ReportLegacyWindow("c1", "Laptop", "GoLand", "linux")
+	ws.ReportLegacyWindow("c1", "Laptop", "Steam", "linux")
+	waitCount(t, slack, 2)
+	time.Sleep(300 * time.Millisecond)
+	if n := slack.count(); n != 2 {
+		t.Fatalf("slack requests = %d, want 2", n)
+	}
+	profile := slack.last()["profile"].(map[string]interface{})
+	if profile["status_text"] != "Playing" || profile["status_emoji"] != ":video_game:" {
+		t.Fatalf("unexpected slack profile: %v", profile)
+	}
+	if slack.paths[1] != "POST /users.profile.set" || slack.authzs[1] != "Bearer xoxp-test" {
+		t.Fatalf("unexpected slack request: %s %s", slack.paths[1], slack.authzs[1])
+	}
+
+	// 3. 没有 SlackEmoji 时 Unicode 表情拼接在文字前；不命中规则时清除状态
+	ws.ReportLegacyWindow("c1", "Laptop", "GoLand", "linux")
+	waitCount(t, slack, 3)
+	if got := slack.last()["profile"].(map[string]interface{})["status_text"]; got != "💻 Coding" {
+		t.Fatalf("status_text = %v", got)
+	}
+	ws.ReportLegacyWindow("c1", "Laptop", "Firefox", "linux")
+	waitCount(t, slack, 4)
+	if got := slack.last()["profile"].(map[string]interface{})["status_text"]; got != "" {
+		t.Fatalf("status_text = %v, want cleared", got)
+	}
+	waitCount(t, matrix, 4)
+	if got := matrix.last(); got["presence"] != "online" || strings.TrimSpace(got["status_msg"].(string)) != "" {
+		t.Fatalf("unexpected matrix body: %v", got)
+	}
+}
+
+func TestSlackError(t *testing.T) {
+	srv := httptest.NewServer(&recorder{response: `{"ok":false,"error":"invalid_auth"}`})
+	defer srv.Close()
+
+	err := NewSlack(pkg.SlackConfig{Token: "bad", BaseURL: srv.URL}, http.DefaultClient).SetStatus(t.Context(), Status{Text: "x"})
+	if err == nil || !strings.Contains(err.Error(), "invalid_auth") {
+		t.Fatalf("err = %v, want invalid_auth", err)
+	}
+}
+
+// closedStreamer 模拟已经关闭的 WindowService
+type closedStreamer struct {
+	calls atomic.Int32
+}
+
+func (c *closedStreamer) StreamEvents(context.Context, uint64, func(*naniwosurunov1.WindowEvent) error) error {
+	c.calls.Add(1)
+	return service.ErrClosed
+}
+
+func TestFollowStopsWhenClosed(t *testing.T) {
+	s := New(pkg.StatusSyncConfig{Rules: testRules})
+	events := &closedStreamer{}
+	done := make(chan struct{})
+	go func() {
+		s.follow(context.Background(), events)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("follow still running after the service closed")
+	}
+	if n := events.calls.Load(); n != 1 {
+		t.Errorf("StreamEvents calls = %d, want 1", n)
+	}
+}
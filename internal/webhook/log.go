@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxLogSize 为投递日志的轮转阈值，超过后当前日志被重命名为 .1，只保留一份旧日志
+const maxLogSize = 1 << 20
+
+const (
+	ResultDelivered = "delivered" // 接收方返回 2xx
+	ResultRetry     = "retry"     // 投递失败，等待重试
+	ResultDropped   = "dropped"   // 超过最大尝试次数、队列溢出或回调已从配置中删除
+)
+
+// LogEntry 记录一次投递尝试的结果
+type LogEntry struct {
+	Time       time.Time `json:"time"`
+	DeliveryID string    `json:"delivery_id"`
+	Webhook    string    `json:"webhook"`
+	EventID    uint64    `json:"event_id"`
+	Attempt    int       `json:"attempt"`
+	Result     string    `json:"result"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms,omitempty"`
+}
+
+// DeliveryLog 是以 JSON Lines 追加写入的投递日志
+type DeliveryLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewDeliveryLog(path string) *DeliveryLog {
+	return &DeliveryLog{path: path}
+}
+
+func (l *DeliveryLog) Append(entry LogEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if info, err := os.Stat(l.path); err == nil && info.Size() > maxLogSize {
+		if err := os.Rename(l.path, l.path+".1"); err != nil {
+			return fmt.Errorf("轮转投递日志失败: %w", err)
+		}
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("打开投递日志失败: %w", err)
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// ReadLog 读取最近的至多 n 条投递日志（n <= 0 表示全部），webhook 非空时只返回该回调的记录
+func ReadLog(path string, n int, webhook string) ([]LogEntry, error) {
+	var entries []LogEntry
+	for _, p := range []string{path + ".1", path} {
+		f, err := os.Open(p)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取投递日志失败: %w", err)
+		}
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var entry LogEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				continue
+			}
+			if webhook == "" || entry.Webhook == webhook {
+				entries = append(entries, entry)
+			}
+		}
+		f.Close()
+	}
+
+	if n > 0 && len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+	return entries, nil
+}
@@ -0,0 +1,136 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const defaultMaxQueue = 1000
+
+// Delivery 是一次待投递的回调，签名密钥不随投递持久化，发送时按 Webhook 名称从配置中查找
+type Delivery struct {
+	ID          string          `json:"id"`
+	Webhook     string          `json:"webhook"`
+	EventID     uint64          `json:"event_id"`
+	EventType   string          `json:"event_type"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// Queue 是有界的持久化投递队列，每次变更都会整体写回磁盘，保证重启后未完成的投递继续重试
+type Queue struct {
+	path  string
+	max   int
+	items []*Delivery
+	mu    sync.Mutex
+}
+
+// OpenQueue 打开（或创建）队列文件并加载其中未完成的投递
+func OpenQueue(path string, max int) (*Queue, error) {
+	if max <= 0 {
+		max = defaultMaxQueue
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("无法创建回调目录: %w", err)
+	}
+
+	q := &Queue{path: path, max: max}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取回调队列失败: %w", err)
+	}
+	if err := json.Unmarshal(data, &q.items); err != nil {
+		return nil, fmt.Errorf("解析回调队列失败: %w", err)
+	}
+	return q, nil
+}
+
+// Push 追加一次投递，队列已满时丢弃并返回最旧的投递
+func (q *Queue) Push(d *Delivery) (dropped *Delivery, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.items) >= q.max {
+		dropped = q.items[0]
+		q.items = q.items[1:]
+	}
+	q.items = append(q.items, d)
+	return dropped, q.saveLocked()
+}
+
+// Due 按入队顺序返回到达重试时间的投递副本；同一回调中排在未到期投递之后的投递不返回，
+// 使每个回调的投递按事件顺序进行
+func (q *Queue) Due(now time.Time) []Delivery {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var res []Delivery
+	blocked := make(map[string]bool)
+	for _, d := range q.items {
+		if blocked[d.Webhook] {
+			continue
+		}
+		if d.NextAttempt.After(now) {
+			blocked[d.Webhook] = true
+			continue
+		}
+		res = append(res, *d)
+	}
+	return res
+}
+
+// Update 用新的状态替换队列中同 ID 的投递，投递已被丢弃时忽略
+func (q *Queue) Update(d Delivery) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, item := range q.items {
+		if item.ID == d.ID {
+			q.items[i] = &d
+			return q.saveLocked()
+		}
+	}
+	return nil
+}
+
+// Remove 将投递移出队列
+func (q *Queue) Remove(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, item := range q.items {
+		if item.ID == id {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			return q.saveLocked()
+		}
+	}
+	return nil
+}
+
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+// saveLocked 先写入临时文件再重命名，避免写到一半崩溃导致队列损坏
+func (q *Queue) saveLocked() error {
+	data, err := json.Marshal(q.items)
+	if err != nil {
+		return fmt.Errorf("序列化回调队列失败: %w", err)
+	}
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("写入回调队列失败: %w", err)
+	}
+	return os.Rename(tmp, q.path)
+}
@@ -0,0 +1,394 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+)
+
+const (
+	SignatureHeader = "X-Naniwosuruno-Signature"
+	EventHeader     = "X-Naniwosuruno-Event"
+	DeliveryHeader  = "X-Naniwosuruno-Delivery"
+
+	defaultMaxAttempts = 8
+	defaultBackoffBase = 5 * time.Second
+	defaultBackoffMax  = time.Hour
+	requestTimeout     = 10 * time.Second
+)
+
+// DefaultDir 是回调队列与投递日志的存放目录
+var DefaultDir = filepath.Join(pkg.DefaultDataDir, "webhooks")
+
+// LogPath 返回 dir 下投递日志的路径
+func LogPath(dir string) string {
+	return filepath.Join(dir, "deliveries.jsonl")
+}
+
+// Dispatcher 订阅事件流，把匹配过滤条件的事件写入持久化队列，再由每个回调各自的协程签名投递
+type Dispatcher struct {
+	configManager *pkg.ConfigManager
+	queue         *Queue
+	log           *DeliveryLog
+	client        *http.Client
+
+	backoffBase  time.Duration
+	backoffMax   time.Duration
+	pollInterval time.Duration
+
+	regexps map[string]*regexp.Regexp // 已编译的 App 正则，编译失败时为 nil
+	reMu    sync.Mutex
+
+	busy   map[string]bool // 正在投递的回调，同一回调同时只有一个协程，保证投递顺序
+	busyMu sync.Mutex
+
+	wake chan struct{}
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewDispatcher 在 dir 下打开回调队列与投递日志
+func NewDispatcher(cm *pkg.ConfigManager, dir string) (*Dispatcher, error) {
+	queue, err := OpenQueue(filepath.Join(dir, "queue.json"), cm.GetConfig().Webhooks.MaxQueue)
+	if err != nil {
+		return nil, err
+	}
+	return &Dispatcher{
+		configManager: cm,
+		queue:         queue,
+		log:           NewDeliveryLog(LogPath(dir)),
+		client:        &http.Client{Timeout: requestTimeout},
+		backoffBase:   defaultBackoffBase,
+		backoffMax:    defaultBackoffMax,
+		pollInterval:  time.Second,
+		regexps:       make(map[string]*regexp.Regexp),
+		busy:          make(map[string]bool),
+		wake:          make(chan struct{}, 1),
+		done:          make(chan struct{}),
+	}, nil
+}
+
+// Start 订阅 broker 的事件并启动投递协程
+func (d *Dispatcher) Start(broker *service.EventBroker) {
+	// 在返回前完成订阅，保证 Start 之后发布的事件不会遗漏
+	lastID := broker.LastID()
+	events, cancel := broker.Subscribe(256)
+	d.wg.Add(2)
+	go d.consume(broker, lastID, events, cancel)
+	go d.deliverLoop()
+}
+
+// Stop 停止订阅与投递，未完成的投递保留在队列中，下次启动时继续
+func (d *Dispatcher) Stop() {
+	close(d.done)
+	d.wg.Wait()
+}
+
+// consume 将订阅到的事件写入队列；订阅因过慢被断开时重新订阅，并从历史中补齐 lastID 之后错过的事件
+func (d *Dispatcher) consume(broker *service.EventBroker, lastID uint64, events <-chan *naniwosurunov1.WindowEvent, cancel func()) {
+	defer d.wg.Done()
+	for d.drain(events, &lastID) {
+		log.Printf("回调订阅被断开，从事件 %d 之后续传", lastID)
+		events, cancel = broker.Subscribe(256)
+		lastID = d.catchUp(broker, lastID)
+	}
+	cancel()
+}
+
+// catchUp 处理历史中 ID 大于 lastID 的事件并返回最后处理的 ID，调用方需先完成订阅，重复的事件由 drain 按 ID 过滤
+func (d *Dispatcher) catchUp(broker *service.EventBroker, lastID uint64) uint64 {
+	latest := broker.LastID()
+	if latest <= lastID {
+		return lastID
+	}
+	missed, ok := broker.Since(lastID)
+	if !ok {
+		log.Printf("回调订阅断开期间的 %d 个事件无法从历史补齐，已跳过", latest-lastID)
+		return lastID
+	}
+	for _, ev := range missed {
+		// 补报的事件只写入历史，从不推送给订阅者，续传时同样跳过
+		if !ev.Backfilled {
+			d.Handle(ev)
+		}
+		lastID = ev.Id
+	}
+	return lastID
+}
+
+// drain 处理 events 直到其被关闭（返回 true）或 Dispatcher 停止（返回 false），lastID 记录最后处理的事件
+func (d *Dispatcher) drain(events <-chan *naniwosurunov1.WindowEvent, lastID *uint64) bool {
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return true
+			}
+			if ev.Id <= *lastID {
+				continue
+			}
+			d.Handle(ev)
+			*lastID = ev.Id
+		case <-d.done:
+			return false
+		}
+	}
+}
+
+// Handle 为每个匹配该事件的回调创建一次投递
+func (d *Dispatcher) Handle(ev *naniwosurunov1.WindowEvent) {
+	cfg := d.configManager.GetConfig().Webhooks
+	var payload []byte
+	for _, hook := range cfg.Endpoints {
+		if !d.matches(hook, ev) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			if payload, err = service.MarshalEvent(ev); err != nil {
+				log.Printf("序列化回调事件失败: %v", err)
+				return
+			}
+		}
+
+		now := time.Now()
+		dropped, err := d.queue.Push(&Delivery{
+			ID:          fmt.Sprintf("%d-%s", ev.Id, hook.Name),
+			Webhook:     hook.Name,
+			EventID:     ev.Id,
+			EventType:   ev.Type,
+			Payload:     payload,
+			NextAttempt: now,
+			CreatedAt:   now,
+		})
+		if err != nil {
+			log.Printf("写入回调队列失败: %v", err)
+		}
+		if dropped != nil {
+			log.Printf("回调队列已满，丢弃投递 %s", dropped.ID)
+			d.record(*dropped, ResultDropped, 0, "queue full", 0)
+		}
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) matches(hook pkg.WebhookConfig, ev *naniwosurunov1.WindowEvent) bool {
+	if ev.Type == service.EventTypeSnapshot {
+		return false
+	}
+	if len(hook.Events) > 0 && !slices.Contains(hook.Events, ev.Type) {
+		return false
+	}
+	if len(hook.Clients) > 0 && !slices.Contains(hook.Clients, ev.ClientId) && !slices.Contains(hook.Clients, ev.Client) {
+		return false
+	}
+	if len(hook.Statuses) > 0 && !slices.Contains(hook.Statuses, ev.Status) {
+		return false
+	}
+	if hook.App != "" {
+		re := d.compile(hook.App)
+		if re == nil || !re.MatchString(ev.Title) {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *Dispatcher) compile(expr string) *regexp.Regexp {
+	d.reMu.Lock()
+	defer d.reMu.Unlock()
+
+	if re, ok := d.regexps[expr]; ok {
+		return re
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		log.Printf("回调的 app 正则无效 %q: %v", expr, err)
+	}
+	d.regexps[expr] = re
+	return re
+}
+
+func (d *Dispatcher) deliverLoop() {
+	defer d.wg.Done()
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.deliverDue()
+		select {
+		case <-d.done:
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// deliverDue 按回调分组到期的投递，为每个空闲的回调启动一个投递协程，
+// 缓慢或不可达的回调因此不会拖慢其他回调；协程结束后唤醒投递循环处理期间新到期的投递
+func (d *Dispatcher) deliverDue() {
+	due := make(map[string][]Delivery)
+	var order []string
+	for _, delivery := range d.queue.Due(time.Now()) {
+		if _, ok := due[delivery.Webhook]; !ok {
+			order = append(order, delivery.Webhook)
+		}
+		due[delivery.Webhook] = append(due[delivery.Webhook], delivery)
+	}
+
+	d.busyMu.Lock()
+	defer d.busyMu.Unlock()
+	for _, name := range order {
+		if d.busy[name] {
+			continue
+		}
+		d.busy[name] = true
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			d.deliverEndpoint(due[name])
+
+			d.busyMu.Lock()
+			delete(d.busy, name)
+			d.busyMu.Unlock()
+			select {
+			case d.wake <- struct{}{}:
+			default:
+			}
+		}()
+	}
+}
+
+// deliverEndpoint 按顺序投递同一回调的到期投递，失败的按指数退避安排下一次尝试，
+// 其后的投递等到它送达或被放弃后再发送，避免接收方收到乱序的事件
+func (d *Dispatcher) deliverEndpoint(deliveries []Delivery) {
+	cfg := d.configManager.GetConfig().Webhooks
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	for _, delivery := range deliveries {
+		select {
+		case <-d.done:
+			return
+		default:
+		}
+
+		idx := slices.IndexFunc(cfg.Endpoints, func(h pkg.WebhookConfig) bool { return h.Name == delivery.Webhook })
+		if idx < 0 {
+			d.finish(delivery, ResultDropped, 0, "webhook removed from config", 0)
+			continue
+		}
+
+		delivery.Attempts++
+		start := time.Now()
+		code, err := d.send(cfg.Endpoints[idx], delivery)
+		elapsed := time.Since(start)
+
+		if err == nil {
+			d.finish(delivery, ResultDelivered, code, "", elapsed)
+			continue
+		}
+		if delivery.Attempts >= maxAttempts {
+			d.finish(delivery, ResultDropped, code, err.Error(), elapsed)
+			continue
+		}
+
+		delivery.NextAttempt = time.Now().Add(d.backoff(delivery.Attempts))
+		if err := d.queue.Update(delivery); err != nil {
+			log.Printf("更新回调队列失败: %v", err)
+		}
+		d.record(delivery, ResultRetry, code, err.Error(), elapsed)
+		return
+	}
+}
+
+// backoff 返回第 attempts 次失败后的等待时间：base * 2^(attempts-1)，不超过 backoffMax
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.backoffBase
+	for i := 1; i < attempts && wait < d.backoffMax; i++ {
+		wait *= 2
+	}
+	return min(wait, d.backoffMax)
+}
+
+func (d *Dispatcher) send(hook pkg.WebhookConfig, delivery Delivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Naniwosuruno-Webhook")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	if hook.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(hook.Secret, delivery.Payload))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// finish 将投递移出队列并记录最终结果
+func (d *Dispatcher) finish(delivery Delivery, result string, code int, errMsg string, elapsed time.Duration) {
+	if err := d.queue.Remove(delivery.ID); err != nil {
+		log.Printf("更新回调队列失败: %v", err)
+	}
+	if result == ResultDropped {
+		log.Printf("回调 %s 投递失败，已放弃: %s", delivery.ID, errMsg)
+	}
+	d.record(delivery, result, code, errMsg, elapsed)
+}
+
+func (d *Dispatcher) record(delivery Delivery, result string, code int, errMsg string, elapsed time.Duration) {
+	err := d.log.Append(LogEntry{
+		Time:       time.Now(),
+		DeliveryID: delivery.ID,
+		Webhook:    delivery.Webhook,
+		EventID:    delivery.EventID,
+		Attempt:    delivery.Attempts,
+		Result:     result,
+		StatusCode: code,
+		Error:      errMsg,
+		DurationMs: elapsed.Milliseconds(),
+	})
+	if err != nil {
+		log.Printf("写入投递日志失败: %v", err)
+	}
+}
+
+// Sign 返回请求体的 HMAC-SHA256 签名，格式为 "sha256=<hex>"，接收方应使用常量时间比较
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
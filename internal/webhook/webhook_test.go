@@ -0,0 +1,249 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	naniwosurunov1 "github.com/nhirsama/Naniwosuruno/gen/naniwosuruno/v1"
+	"github.com/nhirsama/Naniwosuruno/internal/history"
+	"github.com/nhirsama/Naniwosuruno/internal/service"
+	"github.com/nhirsama/Naniwosuruno/pkg"
+	"github.com/r3labs/sse/v2"
+)
+
+type receiver struct {
+	mu       sync.Mutex
+	failures int // 剩余需要返回 500 的次数
+	bodies   [][]byte
+	headers  []http.Header
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.failures > 0 {
+		rc.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	rc.bodies = append(rc.bodies, body)
+	rc.headers = append(rc.headers, r.Header.Clone())
+}
+
+func (rc *receiver) received() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.bodies)
+}
+
+func newTestDispatcher(t *testing.T, hooks ...pkg.WebhookConfig) (*Dispatcher, string) {
+	t.Helper()
+	dir := t.TempDir()
+	cm, err := pkg.NewConfigManagerWithLoader(&pkg.JSONConfigLoader{DataDir: dir, FileName: "config.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm.GetConfig().Webhooks.Endpoints = hooks
+
+	d, err := NewDispatcher(cm, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.backoffBase = 10 * time.Millisecond
+	d.pollInterval = 5 * time.Millisecond
+	return d, dir
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDispatcherDeliversWithRetryAndSignature(t *testing.T) {
+	rc := &receiver{failures: 2}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	d, dir := newTestDispatcher(t,
+		pkg.WebhookConfig{Name: "light", URL: srv.URL, Secret: "s3cret", Statuses: []string{"online"}, App: "(?i)goland"},
+	)
+	broker := service.NewEventBroker(sse.New(), nil, 0)
+	d.Start(broker)
+	defer d.Stop()
+
+	// 1. 不匹配过滤条件的事件不会投递
+	broker.Publish(&naniwosurunov1.WindowEvent{Type: "focus", Client: "laptop", ClientId: "c1", Title: "Firefox", Status: "online"})
+	broker.Publish(&naniwosurunov1.WindowEvent{Type: "presence", Client: "laptop", ClientId: "c1", Title: "GoLand", Status: "idle"})
+	// 2. 匹配的事件在两次失败后投递成功
+	broker.Publish(&naniwosurunov1.WindowEvent{Type: "focus", Client: "laptop", ClientId: "c1", Title: "GoLand", Status: "online"})
+
+	waitFor(t, func() bool { return rc.received() == 1 })
+	waitFor(t, func() bool { return d.queue.Len() == 0 })
+
+	rc.mu.Lock()
+	body, header := rc.bodies[0], rc.headers[0]
+	rc.mu.Unlock()
+
+	var ev map[string]interface{}
+	if err := json.Unmarshal(body, &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev["title"] != "GoLand" || header.Get(EventHeader) != "focus" || header.Get(DeliveryHeader) != "3-light" {
+		t.Fatalf("unexpected delivery: %s %v", body, header)
+	}
+	if !hmac.Equal([]byte(header.Get(SignatureHeader)), []byte(Sign("s3cret", body))) {
+		t.Fatalf("signature mismatch: %s", header.Get(SignatureHeader))
+	}
+
+	// 3. 投递日志记录了两次重试与最终成功
+	entries, err := ReadLog(LogPath(dir), 0, "light")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].Result != ResultRetry || entries[0].StatusCode != 500 || entries[2].Result != ResultDelivered || entries[2].Attempt != 3 {
+		t.Fatalf("unexpected log: %+v", entries)
+	}
+}
+
+func TestDispatcherDropsAfterMaxAttempts(t *testing.T) {
+	rc := &receiver{failures: 100}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	d, dir := newTestDispatcher(t, pkg.WebhookConfig{Name: "chat", URL: srv.URL})
+	d.configManager.GetConfig().Webhooks.MaxAttempts = 2
+	d.Handle(&naniwosurunov1.WindowEvent{Id: 1, Type: "presence", Status: "offline"})
+
+	for range 20 {
+		d.deliverDue()
+		time.Sleep(5 * time.Millisecond)
+	}
+	if d.queue.Len() != 0 {
+		t.Fatalf("queue len = %d, want 0", d.queue.Len())
+	}
+	entries, _ := ReadLog(LogPath(dir), 1, "")
+	if len(entries) != 1 || entries[0].Result != ResultDropped || entries[0].Attempt != 2 {
+		t.Fatalf("unexpected log: %+v", entries)
+	}
+}
+
+func TestDispatcherSlowEndpointDoesNotBlockOthers(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	rc := &receiver{}
+	fast := httptest.NewServer(rc)
+	defer fast.Close()
+
+	d, _ := newTestDispatcher(t, pkg.WebhookConfig{Name: "slow", URL: slow.URL}, pkg.WebhookConfig{Name: "fast", URL: fast.URL})
+	broker := service.NewEventBroker(sse.New(), nil, 0)
+	d.Start(broker)
+	defer d.Stop()
+	defer close(release)
+
+	// 慢速回调的投递挂起期间，其他回调的事件照常送达
+	broker.Publish(&naniwosurunov1.WindowEvent{Type: "focus", ClientId: "c1", Title: "GoLand", Status: "online"})
+	broker.Publish(&naniwosurunov1.WindowEvent{Type: "focus", ClientId: "c1", Title: "Firefox", Status: "online"})
+	waitFor(t, func() bool { return rc.received() == 2 })
+}
+
+func TestDispatcherKeepsOrderAfterFailure(t *testing.T) {
+	rc := &receiver{failures: 1}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	d, _ := newTestDispatcher(t, pkg.WebhookConfig{Name: "light", URL: srv.URL})
+	defer d.Stop()
+	d.Handle(&naniwosurunov1.WindowEvent{Id: 1, Type: "presence", Status: "online"})
+	d.Handle(&naniwosurunov1.WindowEvent{Id: 2, Type: "presence", Status: "offline"})
+
+	// 第一次投递失败后，后续投递等待它重试成功，接收方仍按事件顺序收到
+	waitFor(t, func() bool {
+		d.deliverDue()
+		return rc.received() == 2
+	})
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for i, want := range []string{"online", "offline"} {
+		var ev map[string]interface{}
+		if err := json.Unmarshal(rc.bodies[i], &ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev["status"] != want {
+			t.Errorf("delivery %d status = %v, want %s", i, ev["status"], want)
+		}
+	}
+}
+
+func TestDispatcherResumesFromHistory(t *testing.T) {
+	store, err := history.NewFileStore(filepath.Join(t.TempDir(), "events.jsonl"), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	broker := service.NewEventBroker(sse.New(), store, 0)
+	d, _ := newTestDispatcher(t, pkg.WebhookConfig{Name: "light", URL: "http://127.0.0.1:0"})
+
+	// 1. 事件 1 已入队，事件 2 与补报的事件 3 在订阅断开期间发生
+	broker.Publish(&naniwosurunov1.WindowEvent{Type: "presence", ClientId: "c1", Status: "online"})
+	broker.Publish(&naniwosurunov1.WindowEvent{Type: "presence", ClientId: "c1", Status: "offline"})
+	broker.Record(&naniwosurunov1.WindowEvent{Type: "focus", ClientId: "c1", Title: "GoLand", Backfilled: true})
+
+	// 2. 以已断开的订阅启动，重新订阅后从历史补齐事件 2 并继续接收新事件
+	closed := make(chan *naniwosurunov1.WindowEvent)
+	close(closed)
+	d.wg.Add(1)
+	go d.consume(broker, 1, closed, func() {})
+	defer d.Stop()
+	waitFor(t, func() bool { return d.queue.Len() == 1 })
+	broker.Publish(&naniwosurunov1.WindowEvent{Type: "presence", ClientId: "c1", Status: "idle"})
+	waitFor(t, func() bool { return d.queue.Len() == 2 })
+
+	due := d.queue.Due(time.Now())
+	if len(due) != 2 || due[0].ID != "2-light" || due[1].ID != "4-light" {
+		t.Fatalf("due = %+v", due)
+	}
+}
+
+func TestQueuePersistentAndBounded(t *testing.T) {
+	path := t.TempDir() + "/queue.json"
+	q, err := OpenQueue(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		dropped, err := q.Push(&Delivery{ID: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id == "c" && (dropped == nil || dropped.ID != "a") {
+			t.Fatalf("dropped = %v, want a", dropped)
+		}
+	}
+
+	// 重新打开后队列内容保持不变
+	q, err = OpenQueue(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	due := q.Due(time.Now())
+	if len(due) != 2 || due[0].ID != "b" || due[1].ID != "c" {
+		t.Fatalf("due = %+v", due)
+	}
+}
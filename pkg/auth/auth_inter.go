@@ -18,6 +18,8 @@ type StatefulAuthenticator interface {
 	CreateChallenge(clientID string) (string, error)
 	ValidateChallengeAndIssueToken(clientID, signature string) (string, int64, error)
 	ValidateSession(token string) (SessionInfo, bool)
+	// Close 停止后台的过期清理协程
+	Close()
 }
 
 // SessionInfo 存储会话信息
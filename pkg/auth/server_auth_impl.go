@@ -61,6 +61,9 @@ type statefulAuthenticatorImpl struct {
 
 	sessions     map[string]sessionData // 存储已建立的会话信息
 	sessionsLock sync.RWMutex
+
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 type challengeData struct {
@@ -80,6 +83,7 @@ func NewStatefulAuthenticator(kp KeyProvider) StatefulAuthenticator {
 		keyProvider:         kp,
 		challenges:          make(map[string]challengeData),
 		sessions:            make(map[string]sessionData),
+		done:                make(chan struct{}),
 	}
 
 	go sa.cleanupLoop() // 启动异步清理协程，防止内存因过期数据堆积而无限增长
@@ -168,7 +172,12 @@ func (s *statefulAuthenticatorImpl) cleanupLoop() {
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
 
-	for range ticker.C {
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
 		now := time.Now()
 
 		s.challengesLock.Lock()
@@ -189,6 +198,10 @@ func (s *statefulAuthenticatorImpl) cleanupLoop() {
 	}
 }
 
+func (s *statefulAuthenticatorImpl) Close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
 type ClientNameProvider interface {
 	GetClientName(clientID string) (string, error)
 }
@@ -8,23 +8,227 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 )
 
 // AppConfig 存储应用程序的所有配置项，包括 Token、BaseUrl 以及安全认证所需的密钥和客户端列表
 type AppConfig struct {
-	Token      string         `json:"Token"`
-	BaseUrl    string         `json:"BaseUrl"`
-	ClientID   string         `json:"ClientID,omitempty"`   // 客户端用于标识自身身份的 ID
-	PrivateKey string         `json:"PrivateKey,omitempty"` // 客户端用于签名的 Ed25519 私钥 (Base64)
-	Clients    []ClientConfig `json:"Clients,omitempty"`    // 服务端信任的客户端列表 (包含公钥)
+	Token       string           `json:"Token"`
+	BaseUrl     string           `json:"BaseUrl"`
+	ClientID    string           `json:"ClientID,omitempty"`    // 客户端用于标识自身身份的 ID
+	PrivateKey  string           `json:"PrivateKey,omitempty"`  // 客户端用于签名的 Ed25519 私钥 (Base64)
+	Clients     []ClientConfig   `json:"Clients,omitempty"`     // 服务端信任的客户端列表 (包含公钥)
+	ViewerToken string           `json:"ViewerToken,omitempty"` // 访问事件流等只读接口所需的 Token，留空表示公开
+	AdminToken  string           `json:"AdminToken,omitempty"`  // 远程控制客户端等管理操作所需的 Token，留空表示禁用
+	Primary     PrimaryConfig    `json:"Primary,omitzero"`      // 服务端在多个在线客户端之间选出 "me" 的策略
+	History     HistoryConfig    `json:"History,omitzero"`      // 服务端事件历史的持久化与断线续传
+	Theme       ThemeConfig      `json:"Theme,omitzero"`        // 前端页面的主题
+	Badge       BadgeConfig      `json:"Badge,omitzero"`        // /badge 状态徽章的默认样式
+	Feed        FeedConfig       `json:"Feed,omitzero"`         // /feed.atom 与 /feed.rss 活动订阅源
+	Webhooks    WebhooksConfig   `json:"Webhooks,omitzero"`     // 事件触发的外部回调
+	MQTT        MQTTConfig       `json:"MQTT,omitzero"`         // 将客户端状态发布到 MQTT，支持 Home Assistant 自动发现
+	Privacy     PrivacyConfig    `json:"Privacy,omitzero"`      // 客户端上报窗口标题前的隐私过滤
+	Discord     DiscordConfig    `json:"Discord,omitzero"`      // 客户端的 Discord Rich Presence
+	StatusSync  StatusSyncConfig `json:"StatusSync,omitzero"`   // 将当前活动同步为 Slack/Matrix 状态
+	Federation  FederationConfig `json:"Federation,omitzero"`   // 订阅其他 Naniwosuruno 服务端，合并为团队状态面板
+	// ClientPolicy 为服务端下发给所有客户端的推荐设置，客户端认证后获取并即时生效
+	ClientPolicy ClientPolicyConfig `json:"ClientPolicy,omitzero"`
+	// Calendar 为服务端读取的本地日程，忙碌事件期间 "me" 显示为忙碌
+	Calendar CalendarConfig `json:"Calendar,omitzero"`
+}
+
+// PrimaryConfig 定义了如何在用户的多个在线客户端中选出唯一的权威活动（合成的 "me" 条目）
+type PrimaryConfig struct {
+	// Strategy 为 "recent"（默认，选最近活跃的非空闲客户端）或 "priority"（按 Priority 顺序选择）
+	Strategy string `json:"strategy,omitempty"`
+	// Priority 为客户端 ID 或名称列表，越靠前优先级越高，未列出的客户端按 recent 策略兜底
+	Priority []string `json:"priority,omitempty"`
+	// IdleAfter 为客户端在多久没有切换焦点后被视为空闲（秒），0 表示使用默认值
+	IdleAfter int `json:"idle_after,omitempty"`
+}
+
+// HistoryConfig 定义了事件历史日志的容量，历史用于 SSE/RPC 订阅者断线重连后的续传
+type HistoryConfig struct {
+	// MaxEvents 为历史日志保留的事件条数，0 表示使用默认值
+	MaxEvents int `json:"max_events,omitempty"`
+	// MaxReplay 为一次续传最多重放的事件条数，断档超过该值时改为发送全量快照，0 表示使用默认值
+	MaxReplay int `json:"max_replay,omitempty"`
+}
+
+// ThemeConfig 定义了前端页面可定制的部分，未设置的项使用页面内置的默认值
+type ThemeConfig struct {
+	Colors  ThemeColors `json:"colors,omitzero"`
+	Tagline string      `json:"tagline,omitempty"` // 标题下方的标语
+	// Fields 为页面展示的附加字段，可选 "os"、"source"（当前设备）、"status"，nil 表示使用默认值
+	Fields []string `json:"fields,omitempty"`
+}
+
+// ThemeColors 为 CSS 颜色值，如 "#e0e7ff"
+type ThemeColors struct {
+	BackgroundFrom string `json:"background_from,omitempty"`
+	BackgroundTo   string `json:"background_to,omitempty"`
+	AccentFrom     string `json:"accent_from,omitempty"`
+	AccentTo       string `json:"accent_to,omitempty"`
+}
+
+// BadgeConfig 定义了 /badge 状态徽章的默认样式，均可通过同名查询参数覆盖
+type BadgeConfig struct {
+	Style        string `json:"style,omitempty"`         // "flat"（默认）或 "flat-square"
+	Label        string `json:"label,omitempty"`         // 徽章左侧的文字，默认为 "currently using"
+	LabelColor   string `json:"label_color,omitempty"`   // 左侧背景色
+	OnlineColor  string `json:"online_color,omitempty"`  // 在线时右侧背景色
+	IdleColor    string `json:"idle_color,omitempty"`    // 空闲时右侧背景色
+	OfflineColor string `json:"offline_color,omitempty"` // 离线时右侧背景色
+}
+
+// FeedConfig 定义了 Atom/RSS 订阅源的内容，订阅源由持久化的事件历史生成
+type FeedConfig struct {
+	Title      string `json:"title,omitempty"`       // 订阅源标题
+	MaxEntries int    `json:"max_entries,omitempty"` // 最多输出的条目数，0 表示使用默认值，可用 ?n= 覆盖
+	// Debounce 为最短停留时间（秒），停留不足该时间就切走的焦点变化不会出现在订阅源中，0 表示不过滤
+	Debounce int `json:"debounce,omitempty"`
+}
+
+// WebhooksConfig 定义了状态与焦点变化时触发的外部回调，投递失败时按指数退避重试
+type WebhooksConfig struct {
+	Endpoints []WebhookConfig `json:"endpoints,omitempty"`
+	// MaxQueue 为持久化的待投递队列容量，队列满时丢弃最旧的投递，0 表示使用默认值
+	MaxQueue int `json:"max_queue,omitempty"`
+	// MaxAttempts 为单次投递的最多尝试次数，0 表示使用默认值
+	MaxAttempts int `json:"max_attempts,omitempty"`
+}
+
+// WebhookConfig 定义了一个回调地址及其过滤条件，未设置的过滤条件表示不限制
+type WebhookConfig struct {
+	Name     string   `json:"name"`             // 唯一名称，用于投递日志
+	URL      string   `json:"url"`              // 接收 POST 请求的地址
+	Secret   string   `json:"secret,omitempty"` // 用于 X-Naniwosuruno-Signature 的 HMAC-SHA256 密钥
+	Events   []string `json:"events,omitempty"` // 事件类型，"presence" 或 "focus"
+	Clients  []string `json:"clients,omitempty"`
+	Statuses []string `json:"statuses,omitempty"` // "online"、"offline"、"idle"
+	App      string   `json:"app,omitempty"`      // 匹配窗口标题的正则表达式
+}
+
+// MQTTConfig 定义了 MQTT 输出，每个客户端的状态以保留消息发布到 <topic_prefix>/<client>/state
+type MQTTConfig struct {
+	Broker   string `json:"broker,omitempty"` // 如 "tcp://localhost:1883"，留空表示不启用
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	ClientID string `json:"client_id,omitempty"` // 默认为 "naniwosuruno"
+	// TopicPrefix 为主题前缀，默认为 "naniwosuruno"；<topic_prefix>/status 为服务端可用性，断线时由遗嘱消息置为 offline
+	TopicPrefix string `json:"topic_prefix,omitempty"`
+	// Discovery 为 true 时发送 Home Assistant MQTT 自动发现配置
+	Discovery bool `json:"discovery,omitempty"`
+	// DiscoveryPrefix 为 Home Assistant 的发现主题前缀，默认为 "homeassistant"
+	DiscoveryPrefix string `json:"discovery_prefix,omitempty"`
+}
+
+// PrivacyConfig 定义了客户端在窗口标题离开本机前的处理，对上报服务端与 Discord 同样生效
+type PrivacyConfig struct {
+	Hide        []string `json:"hide,omitempty"`        // 正则表达式，匹配的标题整体替换为 Placeholder
+	Redact      []string `json:"redact,omitempty"`      // 正则表达式，标题中匹配的部分替换为 "***"
+	Placeholder string   `json:"placeholder,omitempty"` // 被隐藏的标题显示的内容，默认为 "Private"
+}
+
+// ClientPolicyConfig 定义了由服务端统一下发的客户端行为，修改后无需逐台调整客户端配置；空闲阈值沿用 Primary.idle_after
+type ClientPolicyConfig struct {
+	PollInterval int `json:"poll_interval,omitempty"` // 检查窗口标题的间隔（秒），0 表示使用默认值
+	// HeartbeatInterval 为轮询模式下的心跳间隔（秒），0 表示使用默认值；服务端在 3 个心跳间隔内收不到心跳时判定客户端离线
+	HeartbeatInterval int `json:"heartbeat_interval,omitempty"`
+	// Privacy 中的规则追加在客户端本地规则之后，只能让过滤更严格；Placeholder 仅在客户端未配置时生效
+	Privacy PrivacyConfig `json:"privacy,omitzero"`
+	// Fields 为允许上报的字段，可选 "title"、"os"，nil 表示全部；不允许的标题以占位符代替
+	Fields []string `json:"fields,omitempty"`
+}
+
+// DiscordConfig 定义了客户端通过本机 Discord IPC 设置的 Rich Presence
+type DiscordConfig struct {
+	Enabled       bool   `json:"enabled,omitempty"`
+	ApplicationID string `json:"application_id,omitempty"` // Discord 开发者后台中创建的应用 ID
+	// Apps 为允许展示的应用（匹配窗口标题的正则表达式），不在列表中的窗口会清除 Presence；展示全部可设为 [".*"]
+	Apps       []string `json:"apps,omitempty"`
+	LargeImage string   `json:"large_image,omitempty"` // 应用资源中的图片名，留空表示不显示图片
+}
+
+// StatusSyncConfig 定义了如何将某个客户端的当前活动同步为聊天软件的状态文字与表情
+type StatusSyncConfig struct {
+	// Client 为跟随的客户端 ID 或名称，默认为 "me"
+	Client string `json:"client,omitempty"`
+	// Categories 将分类名称映射到匹配窗口标题的正则表达式，如 "gaming": ["(?i)steam", "(?i)minecraft"]，供规则的 Category 引用
+	Categories map[string][]string `json:"categories,omitempty"`
+	// Rules 按顺序匹配，第一条命中的规则决定状态，都不命中时清除状态
+	Rules []StatusRule `json:"rules,omitempty"`
+	// MinInterval 为同一后端两次更新之间的最短间隔（秒），期间的变化只保留最新的一次，0 表示使用默认值
+	MinInterval int          `json:"min_interval,omitempty"`
+	Slack       SlackConfig  `json:"slack,omitzero"`
+	Matrix      MatrixConfig `json:"matrix,omitzero"`
+}
+
+// StatusRule 将一类应用（或某个在线状态）映射为状态文字与表情
+type StatusRule struct {
+	Name string `json:"name,omitempty"` // 规则名称，仅用于日志，如 "gaming"
+	// Apps 为匹配窗口标题的正则表达式，任意一条命中即可；与 Category 都为空表示不限应用
+	Apps []string `json:"apps,omitempty"`
+	// Category 引用 Categories 中的分类，其中的正则与 Apps 合并匹配
+	Category string `json:"category,omitempty"`
+	// Status 为匹配的在线状态（"online"、"idle"、"offline"）；为空时匹配除 offline 以外的状态
+	Status string `json:"status,omitempty"`
+	Text   string `json:"text,omitempty"`
+	Emoji  string `json:"emoji,omitempty"` // Unicode 表情，如 "🎮"
+	// SlackEmoji 为 Slack 的表情代码，如 ":video_game:"；为空时 Emoji 会拼接在 Slack 状态文字之前
+	SlackEmoji string `json:"slack_emoji,omitempty"`
+}
+
+// SlackConfig 定义了通过 users.profile.set 更新 Slack 状态所需的参数，Token 为空表示不启用
+type SlackConfig struct {
+	Token   string `json:"token,omitempty"`    // 拥有 users.profile:write 权限的用户 Token
+	BaseURL string `json:"base_url,omitempty"` // 默认为 "https://slack.com/api"
+}
+
+// MatrixConfig 定义了通过 presence 接口更新 Matrix 状态所需的参数，AccessToken 为空表示不启用
+type MatrixConfig struct {
+	Homeserver  string `json:"homeserver,omitempty"` // 如 "https://matrix.org"
+	UserID      string `json:"user_id,omitempty"`    // 如 "@alice:matrix.org"
+	AccessToken string `json:"access_token,omitempty"`
+}
+
+// FederationConfig 定义了需要订阅的其他 Naniwosuruno 服务端
+type FederationConfig struct {
+	Peers []PeerConfig `json:"peers,omitempty"`
+}
+
+// PeerConfig 定义了一个联邦对端，对端的客户端在本地显示为 "<name>/<客户端>"，对端的 "me" 显示为 "<name>"
+type PeerConfig struct {
+	Name  string `json:"name"`            // 命名空间，不能包含 "/"
+	URL   string `json:"url"`             // 对端服务端地址，如 "https://alice.example.com"
+	Token string `json:"token,omitempty"` // 对端配置的 ViewerToken
+}
+
+// CalendarConfig 定义了服务端读取的日程，日程中的忙碌事件会覆盖 "me" 的可见状态，如 "In a meeting until 15:30"
+type CalendarConfig struct {
+	Calendars []CalendarSource `json:"calendars,omitempty"`
+	// Refresh 为重新读取日程的间隔（秒），0 表示使用默认值
+	Refresh int `json:"refresh,omitempty"`
+	// Timezone 为显示结束时间与解析不带时区的时间所用的时区（IANA 名称，如 "Asia/Shanghai"），为空表示服务端本地时区
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// CalendarSource 定义了一个日程来源，Path 与 URL 二选一
+type CalendarSource struct {
+	Name string `json:"name"`           // 仅用于日志
+	Path string `json:"path,omitempty"` // .ics 文件，或包含 .ics 文件的目录（如其他工具同步的导出目录）
+	URL  string `json:"url,omitempty"`  // 返回 .ics 内容的本地地址，如 CalDAV 服务的导出链接
+	// Privacy 为 "meeting"（默认，显示 "In a meeting until 15:30"）、"details"（显示事件标题）或 "busy"（只显示 "Busy"）
+	Privacy string `json:"privacy,omitempty"`
 }
 
 // ClientConfig 定义了服务端所知的客户端元数据，包括用于验签的公钥
 type ClientConfig struct {
 	ID        string `json:"id"`
 	Name      string `json:"name"`
-	PublicKey string `json:"public_key"` // Ed25519 公钥 (Base64)
+	PublicKey string `json:"public_key"`      // Ed25519 公钥 (Base64)
+	Relay     bool   `json:"relay,omitempty"` // 允许该客户端作为中继转发下游客户端的事件
 }
 
 // ConfigLoader 定义了加载和保存配置的底层行为，支持未来可能的多种格式（如 YAML/ETCD）
@@ -39,9 +243,15 @@ type JSONConfigLoader struct {
 	FileName string
 }
 
+// DefaultDataDir 是配置文件与运行时数据（如事件历史）的默认存放目录
+const DefaultDataDir = "./data"
+
+// PrimaryClientName 是服务端合成的 "me" 条目使用的客户端名称与 ID，配置中的客户端不能使用
+const PrimaryClientName = "me"
+
 func NewJSONConfigLoader() *JSONConfigLoader {
 	return &JSONConfigLoader{
-		DataDir:  "./data",
+		DataDir:  DefaultDataDir,
 		FileName: "config.json",
 	}
 }
@@ -68,6 +278,9 @@ func (l *JSONConfigLoader) Load() (*AppConfig, error) {
 	if err := json.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("解析 JSON 失败: %w", err)
 	}
+	if err := validateClients(cfg.Clients); err != nil {
+		return nil, err
+	}
 
 	// 兼容性检查：确保 Token 存在且格式基本正确
 	if len(cfg.Token) != 32 {
@@ -81,6 +294,16 @@ func (l *JSONConfigLoader) Load() (*AppConfig, error) {
 	return cfg, nil
 }
 
+// validateClients 拒绝与合成的 "me" 条目同名或同 ID 的客户端，否则 /now、徽章等按名称查找时无法区分两者
+func validateClients(clients []ClientConfig) error {
+	for _, c := range clients {
+		if strings.EqualFold(c.ID, PrimaryClientName) || strings.EqualFold(c.Name, PrimaryClientName) {
+			return fmt.Errorf("客户端 %q 的 ID 或名称不能为保留的 %q，请在 Clients 中改名", c.ID, PrimaryClientName)
+		}
+	}
+	return nil
+}
+
 func (l *JSONConfigLoader) initializeConfig(cfg *AppConfig, path string) (*AppConfig, error) {
 	cfg.Token = generateToken()
 	if err := l.Save(cfg); err != nil {
@@ -117,7 +340,11 @@ type ConfigManager struct {
 }
 
 func NewConfigManager() (*ConfigManager, error) {
-	loader := NewJSONConfigLoader()
+	return NewConfigManagerWithLoader(NewJSONConfigLoader())
+}
+
+// NewConfigManagerWithLoader 使用指定的加载器创建配置管理器，便于切换存储位置或格式
+func NewConfigManagerWithLoader(loader ConfigLoader) (*ConfigManager, error) {
 	cfg, err := loader.Load()
 	if err != nil {
 		return nil, err
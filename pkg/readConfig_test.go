@@ -0,0 +1,39 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadRejectsReservedClientName(t *testing.T) {
+	cases := map[string]string{
+		"name": `{"Token":"0123456789abcdef0123456789abcdef","Clients":[{"ID":"laptop-id","Name":"Me"}]}`,
+		"id":   `{"Token":"0123456789abcdef0123456789abcdef","Clients":[{"ID":"me","Name":"laptop"}]}`,
+	}
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			// 1. 写入包含保留名称客户端的配置
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			// 2. 加载应当失败，而不是让该客户端与合成的 "me" 条目冲突
+			loader := &JSONConfigLoader{DataDir: dir, FileName: "config.json"}
+			if _, err := loader.Load(); err == nil {
+				t.Fatal("expected reserved client name to be rejected")
+			}
+		})
+	}
+
+	// 3. 普通名称正常加载
+	dir := t.TempDir()
+	data := `{"Token":"0123456789abcdef0123456789abcdef","Clients":[{"ID":"laptop-id","Name":"laptop"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&JSONConfigLoader{DataDir: dir, FileName: "config.json"}).Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+}